@@ -300,3 +300,81 @@ func AddJacobianZeqZeq1(P1, P2 *ECPoint, Order *big.Int) *ECPoint {
 
 	return P3
 }
+
+// This function was designed for learning purposes and
+// is not advised for real life cryptographic use
+//
+// Same as `AddJacobian`, but for curves with arbitrary `a`.
+// `AddJacobian` falls back to `DoubleJacobian` when both
+// points are equal, which silently assumes a = -3. This one
+// uses `DoubleJacobianWithA` instead, so it is safe to use
+// on curves like secp256k1 (a = 0) or toy curves
+func AddJacobianWithA(P1, P2 *ECPoint, Order, A *big.Int) *ECPoint {
+	P3 := new(ECPoint)
+	if P1.Z.Sign() == 0 {
+		// P1 is a point at infinity
+		P3.SetCoords(P2.X, P2.Y, P2.Z)
+		return P3
+	} else if P2.Z.Sign() == 0 {
+		// P2 is a point at infinity
+		P3.SetCoords(P1.X, P1.Y, P1.Z)
+		return P3
+	}
+
+	ZZ1 := new(big.Int).Mul(P1.Z, P1.Z)
+	ZZ2 := new(big.Int).Mul(P2.Z, P2.Z)
+	U1 := new(big.Int).Mod(new(big.Int).Mul(ZZ2, P1.X), Order)
+	U2 := new(big.Int).Mod(new(big.Int).Mul(ZZ1, P2.X), Order)
+	S1 := new(big.Int).Mod(new(big.Int).Mul(new(big.Int).Mul(ZZ2, P2.Z), P1.Y), Order)
+	S2 := new(big.Int).Mod(new(big.Int).Mul(new(big.Int).Mul(ZZ1, P1.Z), P2.Y), Order)
+
+	if U1.Cmp(U2) == 0 {
+		if S1.Cmp(S2) != 0 {
+			// P1 == -P2, so the sum is the point at infinity
+			P3.SetCoords(big.NewInt(0), big.NewInt(0), big.NewInt(0))
+			return P3
+		}
+		return DoubleJacobianWithA(P1, Order, A)
+	}
+
+	H := new(big.Int).Sub(U2, U1)
+	R := new(big.Int).Sub(S2, S1)
+	HH := new(big.Int).Mul(H, H)
+	HHH := new(big.Int).Mul(HH, H)
+	U1HH := new(big.Int).Mul(U1, HH)
+
+	// X3 = R^(2) - H^(3) - 2*U1*H^(2)
+	P3.X = new(big.Int).Mod(
+		new(big.Int).Sub(
+			new(big.Int).Sub(
+				new(big.Int).Mul(R, R),
+				HHH,
+			),
+			new(big.Int).Lsh(U1HH, 1),
+		),
+		Order,
+	)
+
+	// Y3 = R*(U1*H^(2) - X3) - S1*H^(3)
+	P3.Y = new(big.Int).Mod(
+		new(big.Int).Sub(
+			new(big.Int).Mul(
+				R,
+				new(big.Int).Sub(U1HH, P3.X),
+			),
+			new(big.Int).Mul(S1, HHH),
+		),
+		Order,
+	)
+
+	// Z3 = Z1*Z2*H
+	P3.Z = new(big.Int).Mod(
+		new(big.Int).Mul(
+			new(big.Int).Mul(P1.Z, P2.Z),
+			H,
+		),
+		Order,
+	)
+
+	return P3
+}
@@ -0,0 +1,249 @@
+package ECwrap
+
+import (
+	"errors"
+	"math/big"
+)
+
+// Maps between curve models. Montgomery <-> short Weierstrass
+// maps are in montgomery.go (`Weierstrass`, `ToWeierstrass`,
+// `FromWeierstrass`), here are the twisted Edwards <-> Montgomery
+// ones and the way back from short Weierstrass to Montgomery.
+// Chaining them, a point can be moved between any two of the
+// forms, e.g. Ed25519 <-> Curve25519 <-> Wei25519
+// https://www.rfc-editor.org/rfc/rfc7748#section-4.1
+// https://datatracker.ietf.org/doc/html/draft-ietf-lwig-curve-representations
+
+// Birational map between twisted Edwards curve
+// a*x^(2) + y^(2) = 1 + d*x^(2)*y^(2) and Montgomery curve
+// B*v^(2) = u^(3) + A*u^(2) + u, where A = 2*(a + d)/(a - d),
+// B*S^(2) = 4/(a - d):
+//
+//	(u, v) = ((1 + y)/(1 - y), S*u/x)
+//	(x, y) = (S*u/v, (u - 1)/(u + 1))
+//
+// S rescales v, so that curves with the same A but different B
+// (when B/B' is a square) can be mapped as well. For Ed25519 and
+// Curve25519 it is sqrt(-486664), as in RFC 7748
+type EdMontMap struct {
+	Edwards    *EdwardsCurve
+	Montgomery *MontgomeryCurve
+	S          *big.Int
+}
+
+// Returns map between given curves, or error if they are
+// not birationally equivalent in the way described above.
+// Of the two possible S, the one which maps base point
+// to base point is picked, if there is such
+func NewEdMontMap(e *EdwardsCurve, m *MontgomeryCurve) (*EdMontMap, error) {
+	p := e.P
+	if p.Cmp(m.P) != 0 {
+		return nil, errors.New("curves are over different fields")
+	}
+	amd := new(big.Int).ModInverse(new(big.Int).Sub(e.A, e.D), p)
+	// A = 2*(a + d)/(a - d)
+	A := new(big.Int).Mul(new(big.Int).Lsh(new(big.Int).Add(e.A, e.D), 1), amd)
+	if A.Mod(A, p).Cmp(m.A) != 0 {
+		return nil, errors.New("Montgomery A does not match the Edwards curve")
+	}
+	// S^(2) = 4/((a - d)*B)
+	SS := new(big.Int).Mul(new(big.Int).Lsh(amd, 2), new(big.Int).ModInverse(m.B, p))
+	S := new(big.Int).ModSqrt(SS.Mod(SS, p), p)
+	if S == nil {
+		return nil, errors.New("Montgomery B does not match the Edwards curve")
+	}
+	mp := &EdMontMap{Edwards: e, Montgomery: m, S: smallerRoot(S, p)}
+
+	G, err := mp.ToMontgomery(e.Generator())
+	if err == nil && G.X.Cmp(m.Gu) == 0 && G.Y.Cmp(m.Gv) != 0 {
+		mp.S.Sub(p, mp.S)
+	}
+	return mp, nil
+}
+
+// Returns Montgomery form of the curve and the map to it.
+// B is made 1 when it is possible (4/(a - d) is a square),
+// and of the two possible S the one giving smaller v of the
+// base point is used, so for edwards25519 the result is
+// exactly curve25519
+func (c *EdwardsCurve) Montgomery() (*EdMontMap, error) {
+	p := c.P
+	amd := new(big.Int).ModInverse(new(big.Int).Sub(c.A, c.D), p)
+	A := new(big.Int).Mul(new(big.Int).Lsh(new(big.Int).Add(c.A, c.D), 1), amd)
+	A.Mod(A, p)
+	B := new(big.Int).Mod(new(big.Int).Lsh(amd, 2), p)
+	S := new(big.Int).ModSqrt(B, p)
+	if S != nil {
+		B.SetInt64(1)
+		S = smallerRoot(S, p)
+	} else {
+		S = big.NewInt(1)
+	}
+
+	m := &MontgomeryCurve{
+		Name: c.Name + " (Montgomery form)",
+		P:    new(big.Int).Set(p),
+		A:    A,
+		B:    B,
+		N:    new(big.Int).Set(c.N),
+		H:    new(big.Int).Set(c.H),
+	}
+	mp := &EdMontMap{Edwards: c, Montgomery: m, S: S}
+	G, err := mp.ToMontgomery(c.Generator())
+	if err != nil || G.IsInfinity() {
+		return nil, errors.New("base point has no image on the Montgomery curve")
+	}
+	// -S is as good as S, pick the one giving smaller v of G
+	if v := smallerRoot(G.Y, p); v.Cmp(G.Y) != 0 {
+		S.Sub(p, S)
+		G.Y = v
+	}
+	m.Gu, m.Gv = G.X, G.Y
+	return mp, nil
+}
+
+// Returns twisted Edwards form of the curve and the map to it,
+// a = (A + 2)/B, d = (A - 2)/B and S = 1. Different a can be
+// reached by rescaling x, see `NewEdMontMap` for such cases
+// (e.g. curve25519 gives a = 486664 rather than -1 of edwards25519)
+func (c *MontgomeryCurve) Edwards() (*EdMontMap, error) {
+	p := c.P
+	bInv := new(big.Int).ModInverse(c.B, p)
+	a := new(big.Int).Mul(new(big.Int).Add(c.A, big.NewInt(2)), bInv)
+	d := new(big.Int).Mul(new(big.Int).Sub(c.A, big.NewInt(2)), bInv)
+
+	mp := &EdMontMap{Montgomery: c, S: big.NewInt(1)}
+	mp.Edwards = &EdwardsCurve{P: p, A: a.Mod(a, p), D: d.Mod(d, p)}
+	G, err := mp.ToEdwards(c.Generator())
+	if err != nil {
+		return nil, err
+	}
+	gx, gy := mp.Edwards.Affine(G)
+	e, err := NewEdwardsCurve(c.Name+" (Edwards form)", p, a, d, gx, gy, c.N, c.H)
+	if err != nil {
+		return nil, err
+	}
+	mp.Edwards = e
+	return mp, nil
+}
+
+// Maps Edwards point to the Montgomery curve. Neutral element
+// (0, 1) goes to the point at infinity and (0, -1) to (0, 0),
+// the only exceptional points of this direction
+func (mp *EdMontMap) ToMontgomery(P *EdPoint) (*ECPoint, error) {
+	e := mp.Edwards
+	p := e.P
+	if P.Z.Sign() == 0 {
+		return nil, errors.New("Edwards point at infinity")
+	}
+	x, y := e.Affine(P)
+	if x.Sign() == 0 {
+		if y.Cmp(big.NewInt(1)) == 0 {
+			return PointAtInfinity(), nil
+		}
+		Q := new(ECPoint)
+		Q.SetCoords(big.NewInt(0), big.NewInt(0), big.NewInt(1))
+		return Q, nil
+	}
+
+	// u = (1 + y)/(1 - y)
+	u := new(big.Int).Mul(
+		new(big.Int).Add(big.NewInt(1), y),
+		new(big.Int).ModInverse(new(big.Int).Sub(big.NewInt(1), y), p),
+	)
+	u.Mod(u, p)
+	// v = S*u/x
+	v := new(big.Int).Mul(new(big.Int).Mul(mp.S, u), new(big.Int).ModInverse(x, p))
+	Q := new(ECPoint)
+	Q.SetCoords(u, v.Mod(v, p), big.NewInt(1))
+	return Q, nil
+}
+
+// Maps Montgomery point (u, v) to the Edwards curve. Point at
+// infinity goes to (0, 1) and (0, 0) to (0, -1). Points with
+// v = 0 or u = -1 would go to the points at infinity of the
+// Edwards curve, for them error is returned. Montgomery curves
+// of complete Edwards curves (such as curve25519) have no such
+// points, apart from (0, 0)
+func (mp *EdMontMap) ToEdwards(P *ECPoint) (*EdPoint, error) {
+	e := mp.Edwards
+	p := e.P
+	if P.IsInfinity() {
+		return e.Identity(), nil
+	}
+	u := new(big.Int).Mod(P.X, p)
+	v := new(big.Int).Mod(P.Y, p)
+	if u.Sign() == 0 && v.Sign() == 0 {
+		return e.NewPoint(big.NewInt(0), new(big.Int).Sub(p, big.NewInt(1))), nil
+	}
+	uPlus1 := new(big.Int).Add(u, big.NewInt(1))
+	if v.Sign() == 0 || uPlus1.Cmp(p) == 0 {
+		return nil, errors.New("point maps to the Edwards point at infinity")
+	}
+
+	// x = S*u/v
+	x := new(big.Int).Mul(new(big.Int).Mul(mp.S, u), new(big.Int).ModInverse(v, p))
+	// y = (u - 1)/(u + 1)
+	y := new(big.Int).Mul(
+		new(big.Int).Sub(u, big.NewInt(1)),
+		new(big.Int).ModInverse(uPlus1, p),
+	)
+	return e.NewPoint(x.Mod(x, p), y.Mod(y, p)), nil
+}
+
+// Returns Montgomery curve M such that `M.Weierstrass()` is
+// this curve, and `M.FromWeierstrass` / `M.ToWeierstrass`
+// map the points. It exists only if the curve has a point
+// (alpha, 0) of order 2 with 3*alpha^(2) + a being a square:
+//
+//	s = 1/sqrt(3*alpha^(2) + a), A = 3*alpha*s, B = s
+//
+// Smallest such alpha and the smaller of the square roots
+// are used, so for Wei25519 the result is curve25519
+func (c *Curve) Montgomery() (*MontgomeryCurve, error) {
+	A, B, err := c.montgomeryCoefficients()
+	if err != nil {
+		return nil, err
+	}
+	m := &MontgomeryCurve{
+		Name: c.Name + " (Montgomery form)",
+		P:    new(big.Int).Set(c.P),
+		A:    A,
+		B:    B,
+		N:    new(big.Int).Set(c.N),
+		H:    new(big.Int).Set(c.H),
+	}
+	G := m.FromWeierstrass(c.Generator())
+	m.Gu, m.Gv = G.X, G.Y
+	return m, nil
+}
+
+// Returns A and B of `Montgomery`, no base point needed
+func (c *Curve) montgomeryCoefficients() (*big.Int, *big.Int, error) {
+	p := c.P
+	for _, alpha := range cubicRoots(c.A, c.B, p) {
+		t := new(big.Int).Add(new(big.Int).Mul(big.NewInt(3), new(big.Int).Mul(alpha, alpha)), c.A)
+		r := new(big.Int).ModSqrt(t.Mod(t, p), p)
+		if r == nil || r.Sign() == 0 {
+			continue
+		}
+		s := smallerRoot(new(big.Int).ModInverse(r, p), p)
+		A := new(big.Int).Mul(new(big.Int).Mul(big.NewInt(3), alpha), s)
+		return A.Mod(A, p), s, nil
+	}
+	return nil, nil, errors.New("curve has no Montgomery form")
+}
+
+// Returns the smaller of r and p - r
+func smallerRoot(r, p *big.Int) *big.Int {
+	neg := new(big.Int).Sub(p, r)
+	if neg.Cmp(r) < 0 {
+		return neg
+	}
+	return new(big.Int).Set(r)
+}
+
+// Returns roots of x^(3) + a*x + b in F_p in ascending order
+func cubicRoots(a, b, p *big.Int) []*big.Int {
+	return polyRoots([]*big.Int{new(big.Int).Mod(b, p), new(big.Int).Mod(a, p), big.NewInt(0), big.NewInt(1)}, p)
+}
@@ -0,0 +1,150 @@
+package ECwrap
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+func TestEdwards25519Curve25519(t *testing.T) {
+	mp, err := Edwards25519().Montgomery()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, c := mp.Montgomery, Curve25519()
+	if m.A.Cmp(c.A) != 0 || m.B.Cmp(c.B) != 0 || m.Gu.Cmp(c.Gu) != 0 || m.Gv.Cmp(c.Gv) != 0 {
+		t.Fatalf(`Montgomery() = (A = %v, B = %v, G = (%v, %v)), expected curve25519`, m.A, m.B, m.Gu, m.Gv)
+	}
+	// S = sqrt(-486664)
+	SS := new(big.Int).Mod(new(big.Int).Mul(mp.S, mp.S), c.P)
+	if SS.Cmp(new(big.Int).Sub(c.P, big.NewInt(486664))) != 0 {
+		t.Fatalf(`S^(2) = %v, expected = -486664`, SS)
+	}
+
+	mp2, err := NewEdMontMap(Edwards25519(), Curve25519())
+	if err != nil || mp2.S.Cmp(mp.S) != 0 {
+		t.Fatalf(`NewEdMontMap() S = %v, expected = %v, err = %v`, mp2.S, mp.S, err)
+	}
+
+	// public keys of Ed25519 and X25519 for the same scalar
+	// have the same u = (1 + y)/(1 - y)
+	k, _ := rand.Int(rand.Reader, c.N)
+	M, _ := mp.ToMontgomery(Edwards25519().ScalarBaseMul(k))
+	if u := c.Ladder(k, c.Gu); u.Cmp(M.X) != 0 {
+		t.Fatalf(`u(k*G) = %v, expected = %v`, M.X, u)
+	}
+}
+
+func TestWei25519(t *testing.T) {
+	w, err := Curve25519().Weierstrass()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Wei25519 a from draft-ietf-lwig-curve-representations
+	a, _ := new(big.Int).SetString("2aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa984914a144", 16)
+	if w.A.Cmp(a) != 0 {
+		t.Fatalf(`a = %x, expected = %x`, w.A, a)
+	}
+	m, err := w.Montgomery()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := Curve25519()
+	if m.A.Cmp(c.A) != 0 || m.B.Cmp(c.B) != 0 || m.Gu.Cmp(c.Gu) != 0 || m.Gv.Cmp(c.Gv) != 0 {
+		t.Fatalf(`Montgomery() = (A = %v, B = %v, G = (%v, %v)), expected curve25519`, m.A, m.B, m.Gu, m.Gv)
+	}
+
+	if _, err := Secp256k1().Montgomery(); err == nil {
+		t.Fatalf(`secp256k1 has no points of order 2, Montgomery() did not fail`)
+	}
+}
+
+// Edwards -> Montgomery -> Weierstrass and back, checking
+// that addition commutes with the maps
+func TestBirationalCommute(t *testing.T) {
+	for _, e := range []*EdwardsCurve{Edwards25519(), Edwards448()} {
+		t.Run(e.Name, func(t *testing.T) {
+			mp, err := e.Montgomery()
+			if err != nil {
+				t.Fatal(err)
+			}
+			m := mp.Montgomery
+			w, err := m.Weierstrass()
+			if err != nil {
+				t.Fatal(err)
+			}
+			toW := func(P *EdPoint) *ECPoint {
+				M, err := mp.ToMontgomery(P)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !M.IsInfinity() && !m.IsOnCurve(M.X, M.Y) {
+					t.Fatalf(`ToMontgomery(P) is not on curve`)
+				}
+				return m.ToWeierstrass(M)
+			}
+
+			k1, _ := rand.Int(rand.Reader, e.N)
+			k2, _ := rand.Int(rand.Reader, e.N)
+			P, Q := e.ScalarBaseMul(k1), e.ScalarBaseMul(k2)
+			if !w.ECPEqual(toW(e.Add(P, Q)), w.ECPAdd(toW(P), toW(Q))) {
+				t.Fatalf(`map(P + Q) != map(P) + map(Q)`)
+			}
+			if !w.ECPEqual(toW(e.Double(P)), w.ECPDouble(toW(P))) {
+				t.Fatalf(`map(2*P) != 2*map(P)`)
+			}
+			if !w.ECPEqual(toW(e.ScalarBaseMul(k1)), w.ECPScalarMul(w.Generator(), k1)) {
+				t.Fatalf(`map(k*G) != k*map(G)`)
+			}
+
+			back, err := mp.ToEdwards(m.FromWeierstrass(toW(P)))
+			if err != nil || !e.Equal(back, P) {
+				t.Fatalf(`point did not survive the round trip, err = %v`, err)
+			}
+
+			// identity <-> infinity, (0, -1) <-> (0, 0)
+			if !toW(e.Identity()).IsInfinity() {
+				t.Fatalf(`identity is not mapped to infinity`)
+			}
+			T := e.NewPoint(big.NewInt(0), new(big.Int).Sub(e.P, big.NewInt(1)))
+			M, _ := mp.ToMontgomery(T)
+			if M.X.Sign() != 0 || M.Y.Sign() != 0 {
+				t.Fatalf(`(0, -1) is mapped to (%v, %v), expected = (0, 0)`, M.X, M.Y)
+			}
+			if back, err := mp.ToEdwards(M); err != nil || !e.Equal(back, T) {
+				t.Fatalf(`(0, 0) is not mapped back to (0, -1)`)
+			}
+		})
+	}
+}
+
+// curve448 is birational to an incomplete Edwards curve, its
+// points with u = -1 have no affine image there
+func TestBirationalExceptional(t *testing.T) {
+	c := Curve448()
+	mp, err := c.Edwards()
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := mp.Edwards
+
+	// B*v^(2) = A - 2 for u = -1
+	u := new(big.Int).Sub(c.P, big.NewInt(1))
+	v := new(big.Int).ModSqrt(new(big.Int).Sub(c.A, big.NewInt(2)), c.P)
+	if v == nil || !c.IsOnCurve(u, v) {
+		t.Fatalf(`curve448 has no point with u = -1`)
+	}
+	if _, err := mp.ToEdwards(&ECPoint{u, v, big.NewInt(1)}); err == nil {
+		t.Fatalf(`ToEdwards(u = -1) did not fail`)
+	}
+
+	k, _ := rand.Int(rand.Reader, c.N)
+	P := e.ScalarBaseMul(k)
+	M, err := mp.ToMontgomery(P)
+	if err != nil || !c.IsOnCurve(M.X, M.Y) {
+		t.Fatalf(`ToMontgomery(k*G) is not on curve448, err = %v`, err)
+	}
+	if M.X.Cmp(c.Ladder(k, c.Gu)) != 0 {
+		t.Fatalf(`u(k*G) does not match the ladder`)
+	}
+}
@@ -0,0 +1,325 @@
+package ECwrap
+
+import (
+	"crypto/elliptic"
+	"errors"
+	"math/big"
+	"sync"
+)
+
+// Short Weierstrass curve y^(2) = x^(3) + a*x + b over F_p
+//
+// Unlike `elliptic.CurveParams` it keeps `a`, so any curve
+// can be described, not only the a = -3 ones (secp256k1,
+// toy curves from the textbooks and so on)
+//
+// *Curve implements `elliptic.Curve`, so it can be passed
+// to the code expecting one. Keep in mind that the
+// `*elliptic.CurveParams` returned by `Params` has no `a`
+// in it, and its own (deprecated) methods assume a = -3.
+// Use the methods of the Curve itself for arithmetic
+type Curve struct {
+	Name string
+
+	// order of the underlying field
+	P *big.Int
+	// order of the base point
+	N *big.Int
+	// cofactor, #E(F_p) = N * H
+	H *big.Int
+
+	A *big.Int
+	B *big.Int
+
+	// base point
+	Gx *big.Int
+	Gy *big.Int
+
+	BitSize int
+}
+
+// Returns new Curve with provided parameters. Checks that
+// the curve is not singular (4*a^(3) + 27*b^(2) != 0) and
+// that the base point lies on it. Parameters are copied
+func NewCurve(name string, p, a, b, gx, gy, n, h *big.Int) (*Curve, error) {
+	if p == nil || a == nil || b == nil || gx == nil || gy == nil || n == nil || h == nil {
+		return nil, errors.New("curve parameter is nil")
+	}
+	if p.Cmp(big.NewInt(3)) <= 0 {
+		return nil, errors.New("field order is too small")
+	}
+	c := &Curve{
+		Name:    name,
+		P:       new(big.Int).Set(p),
+		N:       new(big.Int).Set(n),
+		H:       new(big.Int).Set(h),
+		A:       new(big.Int).Mod(a, p),
+		B:       new(big.Int).Mod(b, p),
+		Gx:      new(big.Int).Set(gx),
+		Gy:      new(big.Int).Set(gy),
+		BitSize: p.BitLen(),
+	}
+	if c.Discriminant().Sign() == 0 {
+		return nil, errors.New("curve is singular")
+	}
+	if !c.IsOnCurve(c.Gx, c.Gy) {
+		return nil, errors.New("base point is not on curve")
+	}
+	return c, nil
+}
+
+// Same as `NewCurve`, but takes string values in the given `base`
+func NewCurveStr(name, p, a, b, gx, gy, n, h string, base int) (*Curve, error) {
+	vals := make([]*big.Int, 7)
+	for i, s := range []string{p, a, b, gx, gy, n, h} {
+		v, ok := new(big.Int).SetString(s, base)
+		if !ok {
+			return nil, errors.New("cannot cast curve parameter string")
+		}
+		vals[i] = v
+	}
+	return NewCurve(name, vals[0], vals[1], vals[2], vals[3], vals[4], vals[5], vals[6])
+}
+
+func mustCurveHex(name, p, a, b, gx, gy, n, h string) *Curve {
+	c, err := NewCurveStr(name, p, a, b, gx, gy, n, h, 16)
+	if err != nil {
+		panic("ECwrap: bad parameters for " + name + ": " + err.Error())
+	}
+	return c
+}
+
+// Wraps `crypto/elliptic` curve (P-224, P-256, ...) into Curve.
+// Parameters of `elliptic` curves have no `a` in them, all of
+// the NIST curves have a = -3, so it is set to P - 3
+// Cofactor is set to 1, which is true for all of them.
+// A *Curve is returned as is, with its own a and h
+func FromElliptic(curve elliptic.Curve) *Curve {
+	if c, ok := curve.(*Curve); ok {
+		return c
+	}
+	params := curve.Params()
+	return &Curve{
+		Name:    params.Name,
+		P:       new(big.Int).Set(params.P),
+		N:       new(big.Int).Set(params.N),
+		H:       big.NewInt(1),
+		A:       new(big.Int).Sub(params.P, big.NewInt(3)),
+		B:       new(big.Int).Set(params.B),
+		Gx:      new(big.Int).Set(params.Gx),
+		Gy:      new(big.Int).Set(params.Gy),
+		BitSize: params.BitSize,
+	}
+}
+
+var initSecp256k1 sync.Once
+var secp256k1 *Curve
+
+// Returns secp256k1 curve (SEC 2, y^(2) = x^(3) + 7), the one
+// used by Bitcoin and Ethereum. It is not in `crypto/elliptic`
+// as a = 0 there
+func Secp256k1() *Curve {
+	initSecp256k1.Do(func() {
+		secp256k1 = mustCurveHex("secp256k1",
+			"FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEFFFFFC2F",
+			"0",
+			"7",
+			"79BE667EF9DCBBAC55A06295CE870B07029BFCDB2DCE28D959F2815B16F81798",
+			"483ADA7726A3C4655DA4FBFC0E1108A8FD17B448A68554199C47D08FFB10D4B8",
+			"FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364141",
+			"1",
+		)
+	})
+	return secp256k1
+}
+
+// Returns 4*a^(3) + 27*b^(2) mod p. Curve is singular
+// if it equals 0
+func (c *Curve) Discriminant() *big.Int {
+	aaa := new(big.Int).Exp(c.A, big.NewInt(3), c.P)
+	bb := new(big.Int).Exp(c.B, big.NewInt(2), c.P)
+	return new(big.Int).Mod(
+		new(big.Int).Add(
+			new(big.Int).Mul(big.NewInt(4), aaa),
+			new(big.Int).Mul(big.NewInt(27), bb),
+		),
+		c.P,
+	)
+}
+
+// Returns x^(3) + a*x + b mod p, the right side of
+// the curve equation
+func (c *Curve) Polynomial(x *big.Int) *big.Int {
+	xxx := new(big.Int).Mul(x, x)
+	xxx.Mul(xxx, x)
+	return new(big.Int).Mod(
+		new(big.Int).Add(
+			new(big.Int).Add(xxx, new(big.Int).Mul(c.A, x)),
+			c.B,
+		),
+		c.P,
+	)
+}
+
+// Returns `elliptic.CurveParams` of the curve. Note that
+// `a` is lost here, see the Curve description
+func (c *Curve) Params() *elliptic.CurveParams {
+	return &elliptic.CurveParams{
+		P:       c.P,
+		N:       c.N,
+		B:       c.B,
+		Gx:      c.Gx,
+		Gy:      c.Gy,
+		BitSize: c.BitSize,
+		Name:    c.Name,
+	}
+}
+
+// Reports whether affine (x, y) lies on the curve.
+// Coordinates out of [0, p) are rejected, as well as
+// (0, 0) which `elliptic` uses for the point at infinity
+func (c *Curve) IsOnCurve(x, y *big.Int) bool {
+	if x.Sign() < 0 || x.Cmp(c.P) >= 0 ||
+		y.Sign() < 0 || y.Cmp(c.P) >= 0 {
+		return false
+	}
+	yy := new(big.Int).Mod(new(big.Int).Mul(y, y), c.P)
+	return yy.Cmp(c.Polynomial(x)) == 0
+}
+
+// Converts `elliptic` style affine point into ECPoint,
+// (0, 0) becomes the point at infinity
+func (c *Curve) toECP(x, y *big.Int) *ECPoint {
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return PointAtInfinity()
+	}
+	P := new(ECPoint)
+	P.SetCoords(x, y, big.NewInt(1))
+	return P
+}
+
+// Converts ECPoint into `elliptic` style affine point
+func (c *Curve) fromECP(P *ECPoint) (*big.Int, *big.Int) {
+	if P.IsInfinity() {
+		return new(big.Int), new(big.Int)
+	}
+	A := c.ECPAffine(P)
+	return A.X, A.Y
+}
+
+func (c *Curve) Add(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	return c.fromECP(c.ECPAdd(c.toECP(x1, y1), c.toECP(x2, y2)))
+}
+
+func (c *Curve) Double(x1, y1 *big.Int) (*big.Int, *big.Int) {
+	return c.fromECP(c.ECPDouble(c.toECP(x1, y1)))
+}
+
+// `k` is big-endian, as in `elliptic`
+func (c *Curve) ScalarMult(x1, y1 *big.Int, k []byte) (*big.Int, *big.Int) {
+	return c.fromECP(c.ECPScalarMul(c.toECP(x1, y1), new(big.Int).SetBytes(k)))
+}
+
+func (c *Curve) ScalarBaseMult(k []byte) (*big.Int, *big.Int) {
+	return c.ScalarMult(c.Gx, c.Gy, k)
+}
+
+// Returns base point of the curve as ECPoint
+func (c *Curve) Generator() *ECPoint {
+	G := new(ECPoint)
+	G.SetCoords(c.Gx, c.Gy, big.NewInt(1))
+	return G
+}
+
+// Returns P1 + P2. Points can be Jacobian or affine,
+// equal or mutually inverse, or the point at infinity
+func (c *Curve) ECPAdd(P1, P2 *ECPoint) *ECPoint {
+	return AddJacobianWithA(P1, P2, c.P, c.A)
+}
+
+// Returns 2*P
+func (c *Curve) ECPDouble(P *ECPoint) *ECPoint {
+	return DoubleJacobianWithA(P, c.P, c.A)
+}
+
+// Returns -P
+func (c *Curve) ECPNeg(P *ECPoint) *ECPoint {
+	R := new(ECPoint)
+	R.SetCoords(P.X, new(big.Int).Mod(new(big.Int).Neg(P.Y), c.P), P.Z)
+	return R
+}
+
+// Returns P1 - P2
+func (c *Curve) ECPSub(P1, P2 *ECPoint) *ECPoint {
+	return c.ECPAdd(P1, c.ECPNeg(P2))
+}
+
+// Returns k*P. Scalar is not reduced by N, as P does
+// not have to lie in the subgroup generated by G.
+// Negative k multiplies -P by |k|
+func (c *Curve) ECPScalarMul(P *ECPoint, k *big.Int) *ECPoint {
+	if k.Sign() < 0 {
+		return ScalarMulWithA(c.ECPNeg(P), new(big.Int).Neg(k), c.P, c.A)
+	}
+	return ScalarMulWithA(P, k, c.P, c.A)
+}
+
+// Returns sum of scalars[i]*points[i], see `MultiScalarMulWithA`
+// Negative scalars are allowed
+func (c *Curve) ECPMultiScalarMul(points []*ECPoint, scalars []*big.Int) *ECPoint {
+	if len(points) != len(scalars) {
+		panic("ECwrap: number of points and scalars differ")
+	}
+	ps := make([]*ECPoint, len(points))
+	ks := make([]*big.Int, len(scalars))
+	for i := range points {
+		ps[i], ks[i] = points[i], scalars[i]
+		if ks[i].Sign() < 0 {
+			ps[i], ks[i] = c.ECPNeg(ps[i]), new(big.Int).Neg(ks[i])
+		}
+	}
+	return MultiScalarMulWithA(ps, ks, c.P, c.A)
+}
+
+// Returns k*G
+func (c *Curve) ECPScalarBaseMul(k *big.Int) *ECPoint {
+	return c.ECPScalarMul(c.Generator(), k)
+}
+
+// Returns affine copy of P (Z = 1). The point at
+// infinity is returned as is
+func (c *Curve) ECPAffine(P *ECPoint) *ECPoint {
+	A := P.Copy()
+	A.ECPNormalize(c.P)
+	return A
+}
+
+// Reports whether P1 and P2 are the same point, no
+// matter in which coordinates they are
+func (c *Curve) ECPEqual(P1, P2 *ECPoint) bool {
+	if P1.IsInfinity() || P2.IsInfinity() {
+		return P1.IsInfinity() && P2.IsInfinity()
+	}
+	// X1*Z2^(2) == X2*Z1^(2) and Y1*Z2^(3) == Y2*Z1^(3)
+	ZZ1 := new(big.Int).Mul(P1.Z, P1.Z)
+	ZZ2 := new(big.Int).Mul(P2.Z, P2.Z)
+	U1 := new(big.Int).Mod(new(big.Int).Mul(P1.X, ZZ2), c.P)
+	U2 := new(big.Int).Mod(new(big.Int).Mul(P2.X, ZZ1), c.P)
+	if U1.Cmp(U2) != 0 {
+		return false
+	}
+	S1 := new(big.Int).Mod(new(big.Int).Mul(P1.Y, new(big.Int).Mul(ZZ2, P2.Z)), c.P)
+	S2 := new(big.Int).Mod(new(big.Int).Mul(P2.Y, new(big.Int).Mul(ZZ1, P1.Z)), c.P)
+	return S1.Cmp(S2) == 0
+}
+
+// Reports whether P lies on the curve. Unlike
+// `ECPoint.IsOnCurve`, the point at infinity is
+// considered to be on curve here
+func (c *Curve) ECPIsOnCurve(P *ECPoint) bool {
+	if P.IsInfinity() {
+		return true
+	}
+	A := c.ECPAffine(P)
+	return c.IsOnCurve(A.X, A.Y)
+}
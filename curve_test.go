@@ -0,0 +1,243 @@
+package ECwrap
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+// toy curve y^2 = x^3 + 2x + 3 over F_97, #E = 100,
+// (3, 6) generates a subgroup of order 5
+func toyCurve(t *testing.T) *Curve {
+	c, err := NewCurve("toy97",
+		big.NewInt(97), big.NewInt(2), big.NewInt(3),
+		big.NewInt(3), big.NewInt(6), big.NewInt(5), big.NewInt(20),
+	)
+	if err != nil {
+		t.Fatalf(`NewCurve() error = %v`, err)
+	}
+	return c
+}
+
+// Tests run over all of the NIST curves wrapped with
+// `FromElliptic` and secp256k1, same as `crypto/elliptic`
+// runs its tests over its own curves
+func testAllCurves(t *testing.T, f func(*testing.T, *Curve)) {
+	tests := []struct {
+		name  string
+		curve *Curve
+	}{
+		{"P224", FromElliptic(elliptic.P224())},
+		{"P256", FromElliptic(elliptic.P256())},
+		{"P384", FromElliptic(elliptic.P384())},
+		{"P521", FromElliptic(elliptic.P521())},
+		{"secp256k1", Secp256k1()},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f(t, test.curve)
+		})
+	}
+}
+
+func isInfinity(x, y *big.Int) bool {
+	return x.Sign() == 0 && y.Sign() == 0
+}
+
+func TestCurveOnCurve(t *testing.T) {
+	testAllCurves(t, func(t *testing.T, c *Curve) {
+		if !c.IsOnCurve(c.Gx, c.Gy) {
+			t.Error("basepoint is not on the curve")
+		}
+		if c.IsOnCurve(big.NewInt(1), big.NewInt(1)) {
+			t.Error("point off curve is claimed to be on the curve")
+		}
+		// (x + p, y) must not be accepted
+		if c.IsOnCurve(new(big.Int).Add(c.Gx, c.P), c.Gy) {
+			t.Error("point with x >= p is claimed to be on the curve")
+		}
+	})
+}
+
+func TestCurveInfinity(t *testing.T) {
+	testAllCurves(t, func(t *testing.T, c *Curve) {
+		x0, y0 := new(big.Int), new(big.Int)
+		xG, yG := c.Gx, c.Gy
+
+		if !isInfinity(c.ScalarMult(xG, yG, c.N.Bytes())) {
+			t.Errorf("x^q != ∞")
+		}
+		if !isInfinity(c.ScalarMult(xG, yG, []byte{0})) {
+			t.Errorf("x^0 != ∞")
+		}
+		if !isInfinity(c.ScalarMult(x0, y0, []byte{1, 2, 3})) {
+			t.Errorf("∞^k != ∞")
+		}
+		if !isInfinity(c.ScalarBaseMult(c.N.Bytes())) {
+			t.Errorf("b^q != ∞")
+		}
+		if !isInfinity(c.Double(x0, y0)) {
+			t.Errorf("2∞ != ∞")
+		}
+
+		nMinusOne := new(big.Int).Sub(c.N, big.NewInt(1))
+		x, y := c.ScalarMult(xG, yG, nMinusOne.Bytes())
+		x, y = c.Add(x, y, xG, yG)
+		if !isInfinity(x, y) {
+			t.Errorf("x^(q-1) + x != ∞")
+		}
+		x, y = c.Add(xG, yG, x0, y0)
+		if x.Cmp(xG) != 0 || y.Cmp(yG) != 0 {
+			t.Errorf("x+∞ != x")
+		}
+		x, y = c.Add(x0, y0, xG, yG)
+		if x.Cmp(xG) != 0 || y.Cmp(yG) != 0 {
+			t.Errorf("∞+x != x")
+		}
+		if c.IsOnCurve(x0, y0) {
+			t.Errorf("IsOnCurve(∞) == true")
+		}
+		if xx, yy := elliptic.Unmarshal(c, elliptic.Marshal(c, x0, y0)); xx != nil || yy != nil {
+			t.Errorf("Unmarshal(Marshal(∞)) did not return an error")
+		}
+	})
+}
+
+func TestCurveMarshal(t *testing.T) {
+	testAllCurves(t, func(t *testing.T, c *Curve) {
+		_, x, y, err := elliptic.GenerateKey(c, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		xx, yy := elliptic.Unmarshal(c, elliptic.Marshal(c, x, y))
+		if xx == nil {
+			t.Fatal("failed to unmarshal")
+		}
+		if xx.Cmp(x) != 0 || yy.Cmp(y) != 0 {
+			t.Fatal("unmarshal returned different values")
+		}
+	})
+}
+
+// Compares wrapped NIST curves with the `crypto/elliptic` ones
+func TestCurveMatchesElliptic(t *testing.T) {
+	for _, std := range []elliptic.Curve{elliptic.P224(), elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		c := FromElliptic(std)
+		t.Run(c.Name, func(t *testing.T) {
+			k1, _ := rand.Int(rand.Reader, c.N)
+			k2, _ := rand.Int(rand.Reader, c.N)
+			x1, y1 := c.ScalarBaseMult(k1.Bytes())
+			x1n, y1n := std.ScalarBaseMult(k1.Bytes())
+			if x1.Cmp(x1n) != 0 || y1.Cmp(y1n) != 0 {
+				t.Fatalf(`ScalarBaseMult() = (%s, %s), expected = (%s, %s)`, x1, y1, x1n, y1n)
+			}
+			x2, y2 := c.ScalarMult(x1, y1, k2.Bytes())
+			x2n, y2n := std.ScalarMult(x1n, y1n, k2.Bytes())
+			if x2.Cmp(x2n) != 0 || y2.Cmp(y2n) != 0 {
+				t.Fatalf(`ScalarMult() = (%s, %s), expected = (%s, %s)`, x2, y2, x2n, y2n)
+			}
+			x3, y3 := c.Add(x1, y1, x2, y2)
+			x3n, y3n := std.Add(x1n, y1n, x2n, y2n)
+			if x3.Cmp(x3n) != 0 || y3.Cmp(y3n) != 0 {
+				t.Fatalf(`Add() = (%s, %s), expected = (%s, %s)`, x3, y3, x3n, y3n)
+			}
+			x4, y4 := c.Double(x1, y1)
+			x4n, y4n := std.Double(x1n, y1n)
+			if x4.Cmp(x4n) != 0 || y4.Cmp(y4n) != 0 {
+				t.Fatalf(`Double() = (%s, %s), expected = (%s, %s)`, x4, y4, x4n, y4n)
+			}
+		})
+	}
+}
+
+func TestSecp256k1Multiples(t *testing.T) {
+	c := Secp256k1()
+	want := []struct{ x, y string }{
+		{"79BE667EF9DCBBAC55A06295CE870B07029BFCDB2DCE28D959F2815B16F81798",
+			"483ADA7726A3C4655DA4FBFC0E1108A8FD17B448A68554199C47D08FFB10D4B8"},
+		{"C6047F9441ED7D6D3045406E95C07CD85C778E4B8CEF3CA7ABAC09B95C709EE5",
+			"1AE168FEA63DC339A3C58419466CEAEEF7F632653266D0E1236431A950CFE52A"},
+		{"F9308A019258C31049344F85F89D5229B531C845836F99B08601F113BCE036F9",
+			"388F7B0F632DE8140FE337E62A37F3566500A99934C2231B6CB9FD7584B8E672"},
+	}
+	for i, w := range want {
+		x, y := c.ScalarBaseMult([]byte{byte(i + 1)})
+		P, _ := SetPointStr(w.x, w.y, 16)
+		if x.Cmp(P.X) != 0 || y.Cmp(P.Y) != 0 {
+			t.Fatalf(`ScalarBaseMult(%d) = (%X, %X), expected = (%s, %s)`, i+1, x, y, w.x, w.y)
+		}
+	}
+	// 2G via Double and via Add must agree
+	xd, yd := c.Double(c.Gx, c.Gy)
+	xa, ya := c.Add(c.Gx, c.Gy, c.Gx, c.Gy)
+	if xd.Cmp(xa) != 0 || yd.Cmp(ya) != 0 {
+		t.Fatalf(`Double(G) != Add(G, G)`)
+	}
+}
+
+// Checks the whole subgroup of toy curve against
+// the naive affine formulas
+func TestCurveToy(t *testing.T) {
+	c := toyCurve(t)
+	G := c.Generator()
+	acc := PointAtInfinity()
+	for k := int64(0); k <= 10; k++ {
+		P := c.ECPScalarMul(G, big.NewInt(k))
+		if !c.ECPEqual(P, acc) {
+			t.Fatalf(`ECPScalarMul(G, %d) does not match repeated addition`, k)
+		}
+		if !c.ECPIsOnCurve(P) {
+			t.Fatalf(`ECPScalarMul(G, %d) is not on curve`, k)
+		}
+		acc = c.ECPAdd(acc, G)
+	}
+	if !c.ECPScalarMul(G, big.NewInt(5)).IsInfinity() {
+		t.Fatalf(`5*G != ∞ on toy curve`)
+	}
+	if !c.ECPEqual(c.ECPScalarMul(G, big.NewInt(-1)), c.ECPNeg(G)) {
+		t.Fatalf(`ECPScalarMul(G, -1) != -G`)
+	}
+	// (x, 0) points have order 2 and must double to infinity
+	for x := int64(0); x < 97; x++ {
+		if c.Polynomial(big.NewInt(x)).Sign() == 0 {
+			P := new(ECPoint)
+			P.SetCoords(big.NewInt(x), big.NewInt(0), big.NewInt(1))
+			if !c.ECPDouble(P).IsInfinity() {
+				t.Fatalf(`2*(%d, 0) != ∞`, x)
+			}
+		}
+	}
+}
+
+func TestNewCurveSingular(t *testing.T) {
+	// y^2 = x^3 has zero discriminant
+	_, err := NewCurve("singular",
+		big.NewInt(97), big.NewInt(0), big.NewInt(0),
+		big.NewInt(0), big.NewInt(0), big.NewInt(1), big.NewInt(1),
+	)
+	if err == nil {
+		t.Fatalf(`NewCurve(singular) error = %v, expected error`, err)
+	}
+}
+
+// A *Curve must keep its own a and h when it is passed
+// around as elliptic.Curve
+func TestFromEllipticCurve(t *testing.T) {
+	for _, c := range []*Curve{Secp256k1(), toyCurve(t)} {
+		if got := FromElliptic(c); got != c {
+			t.Fatalf(`FromElliptic(%s) = %v, expected = %v`, c.Name, got, c)
+		}
+		d := big.NewInt(3)
+		x, y := c.ScalarBaseMult(d.Bytes())
+		priv := &ecdsa.PrivateKey{PublicKey: ecdsa.PublicKey{Curve: c, X: x, Y: y}, D: d}
+		k, err := PrivateKeyFromECDSA(priv)
+		if err != nil {
+			t.Fatalf(`PrivateKeyFromECDSA() error = %v`, err)
+		}
+		if !c.ECPIsOnCurve(k.Q) || !c.ECPEqual(k.Q, c.ECPScalarBaseMul(d)) {
+			t.Fatalf(`PrivateKeyFromECDSA(%s).Q = %v, expected = 3*G`, c.Name, k.Q)
+		}
+	}
+}
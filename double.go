@@ -150,19 +150,20 @@ func DoubleWithA(P *ECPoint, Order, A *big.Int) *ECPoint {
 // This function was designed for learning purposes and
 // is not advised for real life cryptographic use
 //
-// Function is untested, should work as expected, though.
-// If ya have as braindead moment as I had while writing
-// this function you re free to try it.
 // Remember that `crypto/elliptic` package is now deprecated,
 // so any changes in it may not be written in its docs
 // Double check the value you set as `a`, cause curve
 // description may not match its parameters
+//
+// Used by `Curve` for all the doubling, so it is covered
+// by the curve tests now
 func DoubleJacobianWithA(P *ECPoint, Order, A *big.Int) *ECPoint {
 	D := new(ECPoint)
 
-	if P.Y.Sign() == 0 {
-		// Point at infinity
-		D.SetCoords(P.X, P.Y, P.Z)
+	if P.Z.Sign() == 0 || P.Y.Sign() == 0 {
+		// Point at infinity, or a point of order 2
+		// (which doubles to the point at infinity)
+		D.SetCoords(big.NewInt(0), big.NewInt(0), big.NewInt(0))
 		return D
 	}
 
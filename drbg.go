@@ -0,0 +1,115 @@
+package ECwrap
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"hash"
+)
+
+// HMAC_DRBG of NIST SP 800-90A Rev. 1 (section 10.1.2), without
+// prediction resistance. It is an io.Reader, so it can be passed
+// wherever the package wants randomness: seeded with a fixed
+// value it makes tests reproducible, seeded from a hardware
+// source it is a regular DRBG
+// https://csrc.nist.gov/pubs/sp/800/90/a/r1/final
+//
+// RFC 6979 nonces are HMAC_DRBG output as well, see `RFC6979`
+type HMACDRBG struct {
+	hash func() hash.Hash
+	k    []byte
+	v    []byte
+	// number of requests since the last (re)seed
+	reseedCounter uint64
+}
+
+// Most bytes per request and most requests between reseeds
+// allowed by SP 800-90A (table 2)
+const (
+	drbgMaxRequest  = 1 << 16
+	drbgMaxRequests = 1 << 48
+)
+
+// Instantiates HMAC_DRBG with hash `h`, `entropy`, `nonce`
+// and optional `personalization` string
+func NewHMACDRBG(h func() hash.Hash, entropy, nonce, personalization []byte) *HMACDRBG {
+	size := h().Size()
+	d := &HMACDRBG{hash: h, k: make([]byte, size), v: make([]byte, size)}
+	for i := range d.v {
+		d.v[i] = 0x01
+	}
+	d.update(entropy, nonce, personalization)
+	d.reseedCounter = 1
+	return d
+}
+
+// Returns HMAC_DRBG with SHA-256 seeded with `seed`. Same seed
+// gives the same stream, which is all tests need
+func NewTestReader(seed []byte) *HMACDRBG {
+	return NewHMACDRBG(sha256.New, seed, nil, []byte("ECwrap test reader"))
+}
+
+func (d *HMACDRBG) mac(key []byte, data ...[]byte) []byte {
+	m := hmac.New(d.hash, key)
+	for _, b := range data {
+		m.Write(b)
+	}
+	return m.Sum(nil)
+}
+
+// HMAC_DRBG_Update, `data` is concatenation of the parts
+func (d *HMACDRBG) update(data ...[]byte) {
+	// K = HMAC(K, V || 0x00 || data), V = HMAC(K, V)
+	d.k = d.mac(d.k, append([][]byte{d.v, {0x00}}, data...)...)
+	d.v = d.mac(d.k, d.v)
+	empty := true
+	for _, b := range data {
+		empty = empty && len(b) == 0
+	}
+	if empty {
+		return
+	}
+	// K = HMAC(K, V || 0x01 || data), V = HMAC(K, V)
+	d.k = d.mac(d.k, append([][]byte{d.v, {0x01}}, data...)...)
+	d.v = d.mac(d.k, d.v)
+}
+
+// Mixes fresh `entropy` and optional `additional` input
+// into the state
+func (d *HMACDRBG) Reseed(entropy, additional []byte) {
+	d.update(entropy, additional)
+	d.reseedCounter = 1
+}
+
+// Fills `out` with output of one generate request,
+// `additional` input is optional. At most 65536 bytes
+// can be requested at once
+func (d *HMACDRBG) Generate(out, additional []byte) error {
+	if len(out) > drbgMaxRequest {
+		return errors.New("too many bytes requested")
+	}
+	if d.reseedCounter > drbgMaxRequests {
+		return errors.New("reseed required")
+	}
+	if len(additional) > 0 {
+		d.update(additional)
+	}
+	for n := 0; n < len(out); {
+		d.v = d.mac(d.k, d.v)
+		n += copy(out[n:], d.v)
+	}
+	d.update(additional)
+	d.reseedCounter++
+	return nil
+}
+
+// Implements io.Reader, long reads are split into requests
+// of the maximum size
+func (d *HMACDRBG) Read(p []byte) (int, error) {
+	for n := 0; n < len(p); n += drbgMaxRequest {
+		if err := d.Generate(p[n:min(n+drbgMaxRequest, len(p))], nil); err != nil {
+			return n, err
+		}
+	}
+	return len(p), nil
+}
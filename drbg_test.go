@@ -0,0 +1,77 @@
+package ECwrap
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"testing"
+)
+
+// First HMAC_DRBG SHA-256 vector of NIST CAVP (no prediction
+// resistance, no reseed, no personalization or additional input)
+func TestHMACDRBG(t *testing.T) {
+	d := NewHMACDRBG(sha256.New,
+		decodeHex("ca851911349384bffe89de1cbdc46e6831e44d34a4fb935ee285dd14b71a7488"),
+		decodeHex("659ba96c601dc69fc902940805ec0ca8"),
+		nil,
+	)
+	out := make([]byte, 128)
+	// the vector is the second of two requests
+	for i := 0; i < 2; i++ {
+		if err := d.Generate(out, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := decodeHex("e528e9abf2dece54d47c7e75e5fe302149f817ea9fb4bee6f4199697d04d5b89" +
+		"d54fbb978a15b5c443c9ec21036d2460b6f73ebad0dc2aba6e624abf07745bc1" +
+		"07694bb7547bb0995f70de25d6b29e2d3011bb19d27676c07162c8b5ccde0668" +
+		"961df86803482cb37ed6d5c0bb8d50cf1f50d476aa0458bdaba806f48be9dcb8")
+	if !bytes.Equal(out, expected) {
+		t.Fatalf(`Generate() = %x, expected = %x`, out, expected)
+	}
+	if err := d.Generate(make([]byte, drbgMaxRequest+1), nil); err == nil {
+		t.Fatalf(`Generate() of more than %d bytes error = nil`, drbgMaxRequest)
+	}
+	// Read has no such limit
+	if n, err := d.Read(make([]byte, 3*drbgMaxRequest)); n != 3*drbgMaxRequest || err != nil {
+		t.Fatalf(`Read() = %d, err = %v`, n, err)
+	}
+}
+
+// Same seed, same keys
+func TestTestReader(t *testing.T) {
+	seed := []byte("ECwrap")
+	c := Secp256k1()
+	d1, Q1, _ := ECDHGenerateKey(c, NewTestReader(seed))
+	d2, Q2, _ := ECDHGenerateKey(c, NewTestReader(seed))
+	if !d1.Equal(d2) || !c.ECPEqual(Q1, Q2) {
+		t.Fatalf(`ECDHGenerateKey() differs for the same seed`)
+	}
+	d3, _, _ := ECDHGenerateKey(c, NewTestReader([]byte("ECwrap!")))
+	if d1.Equal(d3) {
+		t.Fatalf(`ECDHGenerateKey() is the same for different seeds`)
+	}
+
+	P1, _ := RandPointFrom(elliptic.P256(), NewTestReader(seed))
+	P2, _ := RandPointFrom(elliptic.P256(), NewTestReader(seed))
+	if !FromElliptic(elliptic.P256()).ECPEqual(P1, P2) {
+		t.Fatalf(`RandPointFrom() differs for the same seed`)
+	}
+	// a != -3 on both
+	for _, c := range []*Curve{Secp256k1(), toyCurve(t)} {
+		P, err := RandPointFrom(c, NewTestReader(seed))
+		if err != nil || P.IsInfinity() || !c.ECPIsOnCurve(P) {
+			t.Fatalf(`RandPointFrom(%s) = %v is not on curve, err = %v`, c.Name, P, err)
+		}
+	}
+
+	sk1, pub1, _ := Ed25519().GenerateKey(NewTestReader(seed))
+	sk2, pub2, err := Ed25519().GenerateKey(NewTestReader(seed))
+	if err != nil || !bytes.Equal(sk1, sk2) || !bytes.Equal(pub1, pub2) {
+		t.Fatalf(`Ed25519().GenerateKey() differs for the same seed, err = %v`, err)
+	}
+	k, pub, err := X25519().GenerateKey(NewTestReader(seed))
+	if expected, _ := X25519().ScalarBaseMult(k); err != nil || !bytes.Equal(pub, expected) {
+		t.Fatalf(`X25519().GenerateKey() = %x, expected = %x, err = %v`, pub, expected, err)
+	}
+}
@@ -0,0 +1,83 @@
+package ECwrap
+
+import (
+	"errors"
+	"io"
+	"math/big"
+)
+
+// Elliptic curve Diffie-Hellman over Curve, as in
+// NIST SP 800-56A Rev. 3 (sections 5.6.2.3 and 5.7.1.2)
+// https://csrc.nist.gov/pubs/sp/800/56/a/r3/final
+//
+// Shared secret Z is the x coordinate of the shared point,
+// padded to the field byte length, which is the same as
+// `crypto/ecdh` returns for the NIST curves. Z itself should
+// not be used as a key, pass it through one of the KDFs
+// (`X963KDF`, `ConcatKDF`, `HKDF`)
+
+// Options of ECDH
+type ECDHOptions struct {
+	// Multiply by cofactor as well, Z = x(h*d*Q) (the "ECC CDH"
+	// primitive of SP 800-56A). For h = 1 it changes nothing,
+	// otherwise it kills any small order component of Q
+	Cofactor bool
+}
+
+// Generates ECDH key pair, private key d is in [1, n - 1]
+func ECDHGenerateKey(c *Curve, rand io.Reader) (*Scalar, *ECPoint, error) {
+	d, err := c.RandomScalar(rand)
+	if err != nil {
+		return nil, nil, err
+	}
+	return d, c.ECPAffine(c.ECPBaseMul(d)), nil
+}
+
+// Full public key validation (SP 800-56A, 5.6.2.3.3):
+// Q is not the point at infinity, its coordinates are in
+// [0, p - 1], it lies on the curve and n*Q is the point
+// at infinity
+func ECDHValidatePublicKey(c *Curve, Q *ECPoint) error {
+	if Q == nil || Q.IsInfinity() {
+		return errors.New("public key is the point at infinity")
+	}
+	// affine point is checked as it is, Jacobian
+	// one can not have coordinates out of range
+	A := Q
+	if Q.Z.Cmp(big.NewInt(1)) != 0 {
+		A = c.ECPAffine(Q)
+	}
+	if A.X.Sign() < 0 || A.X.Cmp(c.P) >= 0 || A.Y.Sign() < 0 || A.Y.Cmp(c.P) >= 0 {
+		return errors.New("public key coordinates are out of range [0, p - 1]")
+	}
+	if !c.IsOnCurve(A.X, A.Y) {
+		return errors.New("public key is not on curve")
+	}
+	if !c.ECPScalarMul(A, c.N).IsInfinity() {
+		return errors.New("public key is not in the subgroup of order n")
+	}
+	return nil
+}
+
+// Computes shared secret Z of private key `d` and the other
+// party's public key `Q`. Q is fully validated first
+func ECDH(c *Curve, d *Scalar, Q *ECPoint, opts *ECDHOptions) ([]byte, error) {
+	x, err := c.privateKey(d)
+	if err != nil {
+		return nil, err
+	}
+	if err := ECDHValidatePublicKey(c, Q); err != nil {
+		return nil, err
+	}
+	// cofactor is applied to the integer, h*d mod n would
+	// not clear a small order component
+	k := x
+	if opts != nil && opts.Cofactor {
+		k = new(big.Int).Mul(x, c.H)
+	}
+	S := c.ECPScalarMul(Q, k)
+	if S.IsInfinity() {
+		return nil, errors.New("shared point is the point at infinity")
+	}
+	return c.ECPAffine(S).X.FillBytes(make([]byte, c.byteLen())), nil
+}
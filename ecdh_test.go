@@ -0,0 +1,149 @@
+package ECwrap
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"math/big"
+	"testing"
+)
+
+func TestECDHMatchesStd(t *testing.T) {
+	tests := []struct {
+		name  string
+		curve ecdh.Curve
+		c     *Curve
+	}{
+		{"P256", ecdh.P256(), FromElliptic(elliptic.P256())},
+		{"P384", ecdh.P384(), FromElliptic(elliptic.P384())},
+		{"P521", ecdh.P521(), FromElliptic(elliptic.P521())},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a, _ := test.curve.GenerateKey(rand.Reader)
+			b, _ := test.curve.GenerateKey(rand.Reader)
+			c := test.c
+
+			d, _ := c.ScalarFromBytes(a.Bytes())
+			if pub := c.ECPMarshal(c.ECPBaseMul(d)); !bytes.Equal(pub, a.PublicKey().Bytes()) {
+				t.Fatalf(`public key = %x, expected = %x`, pub, a.PublicKey().Bytes())
+			}
+			Q, err := c.ECPUnmarshal(b.PublicKey().Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected, _ := a.ECDH(b.PublicKey())
+			for _, opts := range []*ECDHOptions{nil, {Cofactor: true}} {
+				got, err := ECDH(c, d, Q, opts)
+				if err != nil || !bytes.Equal(got, expected) {
+					t.Fatalf(`ECDH(%+v) = %x, expected = %x, err = %v`, opts, got, expected, err)
+				}
+			}
+		})
+	}
+}
+
+func TestECDHCofactor(t *testing.T) {
+	// Wei25519, h = 8
+	c, _ := Curve25519().Weierstrass()
+	da, Qa, _ := ECDHGenerateKey(c, rand.Reader)
+	db, Qb, _ := ECDHGenerateKey(c, rand.Reader)
+
+	opts := &ECDHOptions{Cofactor: true}
+	za, err := ECDH(c, da, Qb, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zb, _ := ECDH(c, db, Qa, opts)
+	if !bytes.Equal(za, zb) {
+		t.Fatalf(`parties do not agree: %x != %x`, za, zb)
+	}
+	// same as plain ECDH with 8*d
+	plain, _ := ECDH(c, c.NewScalar(new(big.Int).Lsh(da.BigInt(), 3)), Qb, nil)
+	if !bytes.Equal(za, plain) {
+		t.Fatalf(`cofactor ECDH = %x, expected = %x`, za, plain)
+	}
+
+	// Qb + T, T of order 2, is on curve but not in the subgroup
+	T := Curve25519().ToWeierstrass(&ECPoint{big.NewInt(0), big.NewInt(0), big.NewInt(1)})
+	if _, err := ECDH(c, da, c.ECPAdd(Qb, T), opts); err == nil {
+		t.Fatalf(`ECDH() accepted point out of the subgroup`)
+	}
+}
+
+func TestECDHValidatePublicKey(t *testing.T) {
+	c := FromElliptic(elliptic.P256())
+	_, Q, _ := ECDHGenerateKey(c, rand.Reader)
+
+	if err := ECDHValidatePublicKey(c, Q); err != nil {
+		t.Fatalf(`valid key rejected: %v`, err)
+	}
+	bad := map[string]*ECPoint{
+		"infinity":    PointAtInfinity(),
+		"off curve":   {Q.X, new(big.Int).Add(Q.Y, big.NewInt(1)), big.NewInt(1)},
+		"x + p":       {new(big.Int).Add(Q.X, c.P), Q.Y, big.NewInt(1)},
+		"negative y":  {Q.X, new(big.Int).Sub(Q.Y, c.P), big.NewInt(1)},
+		"not a point": {big.NewInt(0), big.NewInt(0), big.NewInt(1)},
+	}
+	for name, P := range bad {
+		if err := ECDHValidatePublicKey(c, P); err == nil {
+			t.Errorf(`%s: ECDHValidatePublicKey() = nil, expected error`, name)
+		}
+	}
+	if _, err := ECDH(c, c.NewScalar(big.NewInt(0)), Q, nil); err == nil {
+		t.Errorf(`ECDH() accepted zero private key`)
+	}
+}
+
+func TestKDF(t *testing.T) {
+	// NIST CAVS ansx963_2001, SHA-256
+	got, _ := X963KDF(sha256.New, decodeHex("96c05619d56c328ab95fe84b18264b08725b85e33fd34f08"), nil, 16)
+	if expected := decodeHex("443024c3dae66b95e6f5670601558f71"); !bytes.Equal(got, expected) {
+		t.Errorf(`X963KDF() = %x, expected = %x`, got, expected)
+	}
+	got, _ = X963KDF(sha256.New,
+		decodeHex("22518b10e70f2a3f243810ae3254139efbee04aa57c7af7d"),
+		decodeHex("75eef81aa3041e33b80971203d2c0c52"),
+		128,
+	)
+	expected := decodeHex("c498af77161cc59f2962b9a713e2b215152d139766ce34a776df11866a69bf2e" +
+		"52a13d9c7c6fc878c50c5ea0bc7b00e0da2447cfd874f6cf92f30d0097111485" +
+		"500c90c3af8b487872d04685d14c8d1dc8d7fa08beb0ce0ababc11f0bd496269" +
+		"142d43525a78e5bc79a17f59676a5706dc54d54d4d1f0bd7e386128ec26afc21")
+	if !bytes.Equal(got, expected) {
+		t.Errorf(`X963KDF() = %x, expected = %x`, got, expected)
+	}
+
+	// RFC 7518, appendix C (ECDH-ES, A128GCM, Alice and Bob)
+	got, _ = ConcatKDF(sha256.New,
+		decodeHex("9e56d91d817135d372834283bf84269cfb316ea3da806a48f6daa7798cfe90c4"),
+		decodeHex("000000074131323847434d00000005416c69636500000003426f6200000080"),
+		16,
+	)
+	if enc := base64.RawURLEncoding.EncodeToString(got); enc != "VqqN6vgjbSBcIijNcacQGg" {
+		t.Errorf(`ConcatKDF() = %s, expected = VqqN6vgjbSBcIijNcacQGg`, enc)
+	}
+
+	// RFC 5869, test case 1
+	got, _ = HKDF(sha256.New,
+		bytes.Repeat([]byte{0x0b}, 22),
+		decodeHex("000102030405060708090a0b0c"),
+		decodeHex("f0f1f2f3f4f5f6f7f8f9"),
+		42,
+	)
+	expected = decodeHex("3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865")
+	if !bytes.Equal(got, expected) {
+		t.Errorf(`HKDF() = %x, expected = %x`, got, expected)
+	}
+
+	// lengths which are not multiples of the hash size
+	full, _ := X963KDF(sha512.New, []byte("z"), nil, 100)
+	part, _ := X963KDF(sha512.New, []byte("z"), nil, 65)
+	if len(full) != 100 || !bytes.Equal(full[:65], part) {
+		t.Errorf(`X963KDF() output is not a prefix of the longer one`)
+	}
+}
@@ -0,0 +1,392 @@
+package ECwrap
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/big"
+)
+
+// Discrete logarithm solvers: given P and Q = k*P, find k.
+// They are generic, only adding points, so they work on any
+// curve and need about sqrt(n) group operations. That is
+// hopeless for real curves and seconds for toy ones up to
+// 2^40 or so, which is the point: students can watch how
+// the work grows with the size of the group
+//
+// All of them stop when the context is done and report
+// progress through `ECDLPOptions`
+
+// Options of the ECDLP solvers, may be nil
+type ECDLPOptions struct {
+	// Called every ProgressInterval group operations
+	// with the number of operations done so far
+	Progress func(steps uint64)
+	// 1 << 16 if zero
+	ProgressInterval uint64
+}
+
+// Counter of group operations, shared by a solver
+// and the solvers it calls
+type dlpSteps struct {
+	ctx  context.Context
+	opts *ECDLPOptions
+	n    uint64
+}
+
+func newDLPSteps(ctx context.Context, opts *ECDLPOptions) *dlpSteps {
+	return &dlpSteps{ctx: ctx, opts: opts}
+}
+
+// Counts one group operation, the context is checked
+// every 256 of them
+func (s *dlpSteps) step() error {
+	s.n++
+	if s.opts != nil && s.opts.Progress != nil {
+		interval := s.opts.ProgressInterval
+		if interval == 0 {
+			interval = 1 << 16
+		}
+		if s.n%interval == 0 {
+			s.opts.Progress(s.n)
+		}
+	}
+	if s.n%256 == 0 {
+		return s.ctx.Err()
+	}
+	return nil
+}
+
+// Baby-step giant-step: returns k in [0, n - 1] with k*P = Q,
+// n is the order of P (or any bound on k). Takes at most
+// 2*sqrt(n) additions and keeps sqrt(n) points in memory
+func (c *Curve) BabyStepGiantStep(ctx context.Context, P, Q *ECPoint, n *big.Int, opts *ECDLPOptions) (*big.Int, error) {
+	return c.bsgs(newDLPSteps(ctx, opts), P, Q, n)
+}
+
+func (c *Curve) bsgs(s *dlpSteps, P, Q *ECPoint, n *big.Int) (*big.Int, error) {
+	if n.Sign() <= 0 {
+		return nil, errors.New("group order must be positive")
+	}
+	// m = ceil(sqrt(n))
+	m := new(big.Int).Add(new(big.Int).Sqrt(new(big.Int).Sub(n, big.NewInt(1))), big.NewInt(1))
+	if m.BitLen() > 32 {
+		return nil, errors.New("group order is too big for baby-step giant-step")
+	}
+	size := m.Int64()
+
+	// baby steps j*P, j in [0, m - 1]
+	baby := make(map[string]int64, size)
+	R := PointAtInfinity()
+	for j := int64(0); j < size; j++ {
+		key := string(c.ECPMarshal(R))
+		if _, ok := baby[key]; !ok {
+			baby[key] = j
+		}
+		R = c.ECPAdd(R, P)
+		if err := s.step(); err != nil {
+			return nil, err
+		}
+	}
+
+	// giant steps Q - i*m*P, i in [0, m]
+	mP := c.ECPNeg(c.ECPScalarMul(P, m))
+	R = Q
+	for i := int64(0); i <= size; i++ {
+		if j, ok := baby[string(c.ECPMarshal(R))]; ok {
+			k := new(big.Int).Add(new(big.Int).Mul(big.NewInt(i), m), big.NewInt(j))
+			return k.Mod(k, n), nil
+		}
+		R = c.ECPAdd(R, mP)
+		if err := s.step(); err != nil {
+			return nil, err
+		}
+	}
+	return nil, errors.New("logarithm not found, Q is not a multiple of P")
+}
+
+// Reports whether the lowest `bits` bits of x are zero. The
+// walks of rho and kangaroo stop or leave a mark at such points
+func distinguished(X *ECPoint, bits int) bool {
+	return X.IsInfinity() || X.X.Sign() == 0 || X.X.TrailingZeroBits() >= uint(bits)
+}
+
+// Returns x mod m, which selects the next jump of a walk
+func walkIndex(X *ECPoint, m int) int {
+	if X.IsInfinity() {
+		return 0
+	}
+	return int(new(big.Int).Mod(X.X, big.NewInt(int64(m))).Int64())
+}
+
+// Number of partitions of the rho walk, 20 makes it behave
+// like a random one (Teske, 2001)
+const rhoPartitions = 20
+
+// Pollard's rho with r-adding walk and distinguished points.
+// Each walk starts at X = a*P + b*Q for random a, b and goes
+// on with X += R_j (R_j = a_j*P + b_j*Q fixed, j picked by x)
+// until it reaches a distinguished point. Two walks meeting
+// there give a + b*k = a' + b'*k mod n
+//
+// n is the order of P and must be prime, for composite orders
+// use `PohligHellman`. Takes about sqrt(pi*n/2) additions
+// and little memory
+func (c *Curve) PollardRho(ctx context.Context, P, Q *ECPoint, n *big.Int, rand io.Reader, opts *ECDLPOptions) (*big.Int, error) {
+	return c.rho(newDLPSteps(ctx, opts), P, Q, n, rand)
+}
+
+func (c *Curve) rho(s *dlpSteps, P, Q *ECPoint, n *big.Int, rand io.Reader) (*big.Int, error) {
+	if !n.ProbablyPrime(20) {
+		return nil, errors.New("group order must be prime")
+	}
+	if !c.ECPScalarMul(Q, n).IsInfinity() {
+		return nil, errors.New("Q is not in the subgroup of order n")
+	}
+	// too few points for the walk to be random,
+	// nothing to gain over exhaustive search
+	if n.BitLen() <= 16 {
+		return c.bsgs(s, P, Q, n)
+	}
+
+	type walkStep struct {
+		R    *ECPoint
+		a, b *big.Int
+	}
+	var steps [rhoPartitions]walkStep
+	for j := range steps {
+		a, err := randInt(rand, n)
+		if err != nil {
+			return nil, err
+		}
+		b, err := randInt(rand, n)
+		if err != nil {
+			return nil, err
+		}
+		steps[j] = walkStep{c.ECPAffine(c.ECPMultiScalarMul([]*ECPoint{P, Q}, []*big.Int{a, b})), a, b}
+	}
+
+	// about 32 distinguished points per sqrt(n) steps,
+	// walks longer than 20 times the mean are stuck in a cycle
+	bits := max(0, n.BitLen()/2-5)
+	maxWalk := int64(math.MaxInt64)
+	if bits < 58 {
+		maxWalk = 20 << bits
+	}
+	seen := map[string][2]*big.Int{}
+	for {
+		if err := s.ctx.Err(); err != nil {
+			return nil, err
+		}
+		a, err := randInt(rand, n)
+		if err != nil {
+			return nil, err
+		}
+		b, err := randInt(rand, n)
+		if err != nil {
+			return nil, err
+		}
+		X := c.ECPAffine(c.ECPMultiScalarMul([]*ECPoint{P, Q}, []*big.Int{a, b}))
+		for w := int64(0); w < maxWalk && !X.IsInfinity(); w++ {
+			if distinguished(X, bits) {
+				key := string(c.ECPMarshal(X))
+				prev, ok := seen[key]
+				if !ok {
+					seen[key] = [2]*big.Int{a, b}
+					break
+				}
+				// a + b*k = a' + b'*k, k = (a - a') / (b' - b)
+				db := new(big.Int).Sub(prev[1], b)
+				if db.Mod(db, n).Sign() == 0 {
+					// same walk from another start, useless
+					break
+				}
+				k := new(big.Int).Mul(new(big.Int).Sub(a, prev[0]), db.ModInverse(db, n))
+				return k.Mod(k, n), nil
+			}
+			j := walkIndex(X, rhoPartitions)
+			X = c.ECPAffine(c.ECPAdd(X, steps[j].R))
+			a.Add(a, steps[j].a).Mod(a, n)
+			b.Add(b, steps[j].b).Mod(b, n)
+			if err := s.step(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// Pollard's kangaroo (lambda) method: returns k in [a, b] with
+// k*P = Q in about 2*sqrt(b - a) additions, however big the group
+// is. Tame kangaroo starts in the middle of the interval at a known
+// multiple of P, wild one at Q, both jump by powers of two of P
+// (picked by x) and leave marks at distinguished points. Once one
+// lands on the trail of the other, their distances give k
+//
+// Unlucky runs are restarted from random offsets, after 16 of them
+// it is assumed that k is not in the interval
+func (c *Curve) PollardKangaroo(ctx context.Context, P, Q *ECPoint, a, b *big.Int, rand io.Reader, opts *ECDLPOptions) (*big.Int, error) {
+	s := newDLPSteps(ctx, opts)
+	w := new(big.Int).Sub(b, a)
+	if w.Sign() < 0 {
+		return nil, errors.New("interval is empty")
+	}
+	sw := new(big.Int).Add(new(big.Int).Sqrt(w), big.NewInt(1))
+
+	// jumps 2^i*P, i in [0, m - 1], the mean (2^m - 1)/m is
+	// about sqrt(w)/2
+	m := 1
+	for new(big.Int).Lsh(big.NewInt(1), uint(m+1)).Cmp(new(big.Int).Mul(big.NewInt(int64(m)), sw)) < 0 {
+		m++
+	}
+	jumps := make([]*ECPoint, m)
+	jumps[0] = c.ECPAffine(P)
+	for i := 1; i < m; i++ {
+		jumps[i] = c.ECPAffine(c.ECPAdd(jumps[i-1], jumps[i-1]))
+	}
+
+	// about 16 marks per sqrt(w) jumps, a run is over when
+	// both kangaroos made 4*sqrt(w) jumps and some
+	bits := max(0, sw.BitLen()-5)
+	limit := int64(math.MaxInt64)
+	if sw.BitLen() < 60 {
+		limit = 4*sw.Int64() + 4<<bits + 16
+	}
+
+	type kangaroo struct {
+		X    *ECPoint
+		d    *big.Int
+		tame bool
+	}
+	type mark struct {
+		d    *big.Int
+		tame bool
+		run  int
+	}
+	trail := map[string]mark{}
+	for run := 0; run < 16; run++ {
+		// tame at (a + w/2 + t)*P, wild at Q + u*P,
+		// offsets t, u in [0, w/8] after the first run
+		t, u := new(big.Int), new(big.Int)
+		if run > 0 {
+			bound := new(big.Int).Add(new(big.Int).Rsh(w, 3), big.NewInt(1))
+			var err error
+			if t, err = randInt(rand, bound); err != nil {
+				return nil, err
+			}
+			if u, err = randInt(rand, bound); err != nil {
+				return nil, err
+			}
+		}
+		td := new(big.Int).Add(new(big.Int).Add(a, new(big.Int).Rsh(w, 1)), t)
+		tame := &kangaroo{c.ECPAffine(c.ECPScalarMul(P, td)), td, true}
+		wild := &kangaroo{c.ECPAffine(c.ECPAdd(Q, c.ECPScalarMul(P, u))), u, false}
+
+	walk:
+		for i := int64(0); i < limit; i++ {
+			for _, kg := range []*kangaroo{tame, wild} {
+				if distinguished(kg.X, bits) {
+					key := string(c.ECPMarshal(kg.X))
+					prev, ok := trail[key]
+					switch {
+					case !ok:
+						trail[key] = mark{new(big.Int).Set(kg.d), kg.tame, run}
+					case prev.tame != kg.tame:
+						// tame distance = k + wild distance
+						k := new(big.Int).Sub(prev.d, kg.d)
+						if kg.tame {
+							k.Neg(k)
+						}
+						return k, nil
+					case prev.run != run:
+						// on the trail of the same kind from an
+						// earlier run, it leads nowhere new
+						break walk
+					}
+				}
+				j := walkIndex(kg.X, m)
+				kg.X = c.ECPAffine(c.ECPAdd(kg.X, jumps[j]))
+				kg.d.Add(kg.d, new(big.Int).Lsh(big.NewInt(1), uint(j)))
+				if err := s.step(); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return nil, errors.New("logarithm not found in the interval")
+}
+
+// Pohlig-Hellman: reduces the logarithm modulo n = p1^e1 * ...
+// to e_i logarithms in subgroups of prime order p_i each, solves
+// them with baby-step giant-step (Pollard's rho for p_i above
+// 2^32) and glues the results with CRT. Cost is about
+// sum e_i*sqrt(p_i), so a group of smooth order is weak
+// no matter how big it is
+//
+// n is the order of P or a multiple of it
+func (c *Curve) PohligHellman(ctx context.Context, P, Q *ECPoint, n *big.Int, rand io.Reader, opts *ECDLPOptions) (*big.Int, error) {
+	s := newDLPSteps(ctx, opts)
+	if n.Sign() <= 0 {
+		return nil, errors.New("group order must be positive")
+	}
+	if !c.ECPScalarMul(P, n).IsInfinity() {
+		return nil, errors.New("n is not a multiple of the order of P")
+	}
+
+	// drop the prime factors n has beyond the order of P
+	order := new(big.Int).Set(n)
+	factors := factorize(n)
+	for i := range factors {
+		pp := &factors[i]
+		for pp.e > 0 {
+			q := new(big.Int).Quo(order, pp.p)
+			if !c.ECPScalarMul(P, q).IsInfinity() {
+				break
+			}
+			order = q
+			pp.e--
+		}
+	}
+
+	k, mod := new(big.Int), big.NewInt(1)
+	for _, pp := range factors {
+		if pp.e == 0 {
+			continue
+		}
+		pe := new(big.Int).Exp(pp.p, big.NewInt(int64(pp.e)), nil)
+		h := new(big.Int).Quo(order, pe)
+		// P0 of order p, Pi of order p^e, Qi = ki*Pi
+		P0 := c.ECPScalarMul(P, new(big.Int).Quo(order, pp.p))
+		Pi, Qi := c.ECPScalarMul(P, h), c.ECPScalarMul(Q, h)
+
+		// ki = d_0 + d_1*p + ..., d_j is the logarithm of
+		// p^(e - 1 - j)*(Qi - (d_0 + ... + d_(j-1)*p^(j-1))*Pi)
+		ki := new(big.Int)
+		pj := big.NewInt(1)
+		for j := 0; j < pp.e; j++ {
+			e := new(big.Int).Exp(pp.p, big.NewInt(int64(pp.e-1-j)), nil)
+			R := c.ECPScalarMul(c.ECPSub(Qi, c.ECPScalarMul(Pi, ki)), e)
+			var d *big.Int
+			var err error
+			if pp.p.BitLen() <= 32 {
+				d, err = c.bsgs(s, P0, R, pp.p)
+			} else {
+				d, err = c.rho(s, P0, R, pp.p, rand)
+			}
+			if err != nil {
+				return nil, err
+			}
+			ki.Add(ki, new(big.Int).Mul(d, pj))
+			pj.Mul(pj, pp.p)
+		}
+
+		// k = k + mod*((ki - k)/mod mod p^e), k = ki mod p^e
+		t := new(big.Int).Mul(new(big.Int).Sub(ki, k), new(big.Int).ModInverse(mod, pe))
+		k.Add(k, new(big.Int).Mul(mod, t.Mod(t, pe)))
+		mod.Mul(mod, pe)
+	}
+	if !c.ECPEqual(c.ECPScalarMul(P, k), Q) {
+		return nil, errors.New("logarithm not found, Q is not a multiple of P")
+	}
+	return k, nil
+}
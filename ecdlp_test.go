@@ -0,0 +1,145 @@
+package ECwrap
+
+import (
+	"context"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// Curves over p = 2^26 - 5 for the solvers: one of prime
+// order, one of order 2^7 * 3^2 * 7 * 53 * 157
+func dlpCurves(t *testing.T) (*Curve, *Curve) {
+	p := big.NewInt(67108859)
+	prime, err := NewCurve("toy26", p,
+		big.NewInt(19192021), big.NewInt(56523184),
+		big.NewInt(2), big.NewInt(66206756), big.NewInt(67104913), big.NewInt(1),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	smooth, err := NewCurve("toy26smooth", p,
+		big.NewInt(48242567), big.NewInt(41969053),
+		big.NewInt(23), big.NewInt(52560852), big.NewInt(67100544), big.NewInt(1),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return prime, smooth
+}
+
+func TestBabyStepGiantStep(t *testing.T) {
+	prime, smooth := dlpCurves(t)
+	for _, c := range []*Curve{prime, smooth, toyCurve(t)} {
+		k, _ := rand.Int(rand.Reader, c.N)
+		got, err := c.BabyStepGiantStep(context.Background(), c.Generator(), c.ECPScalarBaseMul(k), c.N, nil)
+		if err != nil || got.Cmp(k) != 0 {
+			t.Fatalf(`%s: BabyStepGiantStep() = %v, expected = %v, err = %v`, c.Name, got, k, err)
+		}
+	}
+}
+
+func TestPollardRho(t *testing.T) {
+	c, smooth := dlpCurves(t)
+	rnd := NewTestReader([]byte("rho"))
+	for i := 0; i < 4; i++ {
+		k, _ := randInt(rnd, c.N)
+		got, err := c.PollardRho(context.Background(), c.Generator(), c.ECPScalarBaseMul(k), c.N, rnd, nil)
+		if err != nil || got.Cmp(k) != 0 {
+			t.Fatalf(`PollardRho() = %v, expected = %v, err = %v`, got, k, err)
+		}
+	}
+	if _, err := smooth.PollardRho(context.Background(), smooth.Generator(), smooth.Generator(), smooth.N, rnd, nil); err == nil {
+		t.Fatalf(`PollardRho() accepted composite order`)
+	}
+}
+
+// 2^24 wide interval on P-256, the size of the group
+// does not matter
+func TestPollardKangaroo(t *testing.T) {
+	c := FromElliptic(elliptic.P256())
+	rnd := NewTestReader([]byte("kangaroo"))
+	a, _ := randInt(rnd, c.N)
+	b := new(big.Int).Add(a, big.NewInt(1<<24))
+	for i := 0; i < 2; i++ {
+		off, _ := randInt(rnd, big.NewInt(1<<24+1))
+		k := new(big.Int).Add(a, off)
+		got, err := c.PollardKangaroo(context.Background(), c.Generator(), c.ECPScalarBaseMul(k), a, b, rnd, nil)
+		if err != nil || got.Cmp(k) != 0 {
+			t.Fatalf(`PollardKangaroo() = %v, expected = %v, err = %v`, got, k, err)
+		}
+	}
+	// tiny interval, every point is distinguished
+	_, smooth := dlpCurves(t)
+	got, err := smooth.PollardKangaroo(context.Background(), smooth.Generator(), smooth.ECPScalarBaseMul(big.NewInt(7)), big.NewInt(5), big.NewInt(9), rnd, nil)
+	if err != nil || got.Int64() != 7 {
+		t.Fatalf(`PollardKangaroo() = %v, expected = 7, err = %v`, got, err)
+	}
+}
+
+func TestPohligHellman(t *testing.T) {
+	_, c := dlpCurves(t)
+	steps := uint64(0)
+	opts := &ECDLPOptions{Progress: func(n uint64) { steps = n }, ProgressInterval: 16}
+	k, _ := rand.Int(rand.Reader, c.N)
+	got, err := c.PohligHellman(context.Background(), c.Generator(), c.ECPScalarBaseMul(k), c.N, rand.Reader, opts)
+	if err != nil || got.Cmp(k) != 0 {
+		t.Fatalf(`PohligHellman() = %v, expected = %v, err = %v`, got, k, err)
+	}
+	// about sum e_i*sqrt(p_i), far less than sqrt(n)
+	if steps == 0 || steps > 1000 {
+		t.Fatalf(`PohligHellman() took %d steps`, steps)
+	}
+
+	// P of order 2^7 * 53, n is a multiple of it
+	P := c.ECPScalarMul(c.Generator(), big.NewInt(9*7*157))
+	Q := c.ECPScalarMul(P, big.NewInt(1000))
+	got, err = c.PohligHellman(context.Background(), P, Q, c.N, rand.Reader, nil)
+	if err != nil || got.Int64() != 1000 {
+		t.Fatalf(`PohligHellman() = %v, expected = 1000, err = %v`, got, err)
+	}
+}
+
+func TestECDLPCancel(t *testing.T) {
+	c, _ := dlpCurves(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	Q := c.ECPScalarBaseMul(big.NewInt(12345678))
+	if _, err := c.BabyStepGiantStep(ctx, c.Generator(), Q, c.N, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf(`BabyStepGiantStep() error = %v, expected = %v`, err, context.Canceled)
+	}
+	if _, err := c.PollardRho(ctx, c.Generator(), Q, c.N, rand.Reader, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf(`PollardRho() error = %v, expected = %v`, err, context.Canceled)
+	}
+
+	// walks on P-256 never end, the deadline must stop them
+	p256 := FromElliptic(elliptic.P256())
+	Q = p256.ECPScalarBaseMul(big.NewInt(12345678))
+	for name, solve := range map[string]func(context.Context) (*big.Int, error){
+		"PollardRho": func(ctx context.Context) (*big.Int, error) {
+			return p256.PollardRho(ctx, p256.Generator(), Q, p256.N, rand.Reader, nil)
+		},
+		"PohligHellman": func(ctx context.Context) (*big.Int, error) {
+			return p256.PohligHellman(ctx, p256.Generator(), Q, p256.N, rand.Reader, nil)
+		},
+	} {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		done := make(chan error, 1)
+		go func() {
+			_, err := solve(ctx)
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf(`%s() error = %v, expected = %v`, name, err, context.DeadlineExceeded)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf(`%s() ignored the deadline`, name)
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,198 @@
+package ECwrap
+
+import (
+	"encoding/asn1"
+	"errors"
+	"hash"
+	"math/big"
+)
+
+// ECDSA signature (r, s)
+type ECDSASignature struct {
+	R *big.Int
+	S *big.Int
+}
+
+// Signs already hashed message `hash` with private key `d`
+// using explicitly given nonce `k`
+//
+// Reusing `k` for two different messages reveals the private
+// key, that is exactly why RFC 6979 exists. This function is
+// here for learning (and for the attacks on nonce reuse),
+// use `ECDSASign` otherwise
+func ECDSASignWithNonce(c *Curve, d *Scalar, hash []byte, k *Scalar) (*ECDSASignature, error) {
+	sig, _, err := ecdsaSign(c, d, hash, k)
+	return sig, err
+}
+
+// Does the actual signing. Besides the signature returns
+// recovery id of it: bit 0 is parity of R.y, the rest is
+// R.x / n (0 almost always, see `ECDSARecoverPublicKey`)
+func ecdsaSign(c *Curve, d *Scalar, hash []byte, k *Scalar) (*ECDSASignature, byte, error) {
+	x, err := c.privateKey(d)
+	if err != nil {
+		return nil, 0, err
+	}
+	if k == nil || k.n.Cmp(c.N) != 0 {
+		return nil, 0, errors.New("nonce is not a scalar of the curve")
+	}
+	if k.IsZero() {
+		return nil, 0, errors.New("nonce is zero")
+	}
+
+	// R = k*G, r = R.x mod n
+	R := c.ECPAffine(c.ECPBaseMul(k))
+	j, r := new(big.Int).DivMod(R.X, c.N, new(big.Int))
+	if r.Sign() == 0 {
+		return nil, 0, errors.New("r is zero, choose another nonce")
+	}
+	recid := byte(j.Uint64()<<1) | byte(R.Y.Bit(0))
+
+	// s = k^(-1) * (e + r*d) mod n
+	e := bits2int(hash, c.N)
+	s := new(big.Int).Mul(
+		k.Inverse().v,
+		new(big.Int).Add(e, new(big.Int).Mul(r, x)),
+	)
+	s.Mod(s, c.N)
+	if s.Sign() == 0 {
+		return nil, 0, errors.New("s is zero, choose another nonce")
+	}
+
+	return &ECDSASignature{R: r, S: s}, recid, nil
+}
+
+// Signs already hashed message `hash` with private key `d`.
+// Nonce is derived deterministically from the key and the
+// hash as described in RFC 6979, `h` is used for HMAC there
+// (normally the same function the message was hashed with)
+//
+// Signature is not normalized to low-S, call
+// `NormalizeLowS` if you need it
+func ECDSASign(c *Curve, d *Scalar, hash []byte, h func() hash.Hash) (*ECDSASignature, error) {
+	sig, _, err := ecdsaSignDeterministic(c, d, hash, h)
+	return sig, err
+}
+
+func ecdsaSignDeterministic(c *Curve, d *Scalar, hash []byte, h func() hash.Hash) (*ECDSASignature, byte, error) {
+	x, err := c.privateKey(d)
+	if err != nil {
+		return nil, 0, err
+	}
+	nonces := NewRFC6979(c.N, x, hash, h)
+	for {
+		sig, recid, err := ecdsaSign(c, d, hash, c.NewScalar(nonces.Next()))
+		if err == nil {
+			return sig, recid, nil
+		}
+		// r or s happened to be zero, take the next nonce
+	}
+}
+
+// Verifies signature of already hashed message `hash` against
+// public key `Q`. Both high and low S are accepted, check
+// `IsLowS` if you need to reject malleable signatures
+func ECDSAVerify(c *Curve, Q *ECPoint, hash []byte, sig *ECDSASignature) bool {
+	if sig == nil || sig.R == nil || sig.S == nil {
+		return false
+	}
+	if sig.R.Sign() <= 0 || sig.R.Cmp(c.N) >= 0 ||
+		sig.S.Sign() <= 0 || sig.S.Cmp(c.N) >= 0 {
+		return false
+	}
+	if Q.IsInfinity() || !c.ECPIsOnCurve(Q) {
+		return false
+	}
+
+	e := bits2int(hash, c.N)
+	w := new(big.Int).ModInverse(sig.S, c.N)
+	u1 := new(big.Int).Mod(new(big.Int).Mul(e, w), c.N)
+	u2 := new(big.Int).Mod(new(big.Int).Mul(sig.R, w), c.N)
+
+	// X = u1*G + u2*Q
+	X := c.ECPMultiScalarMul(
+		[]*ECPoint{c.Generator(), Q},
+		[]*big.Int{u1, u2},
+	)
+	if X.IsInfinity() {
+		return false
+	}
+	X = c.ECPAffine(X)
+	v := new(big.Int).Mod(X.X, c.N)
+	return v.Cmp(sig.R) == 0
+}
+
+// Reports whether s <= n/2. Bitcoin (BIP 62/146) and
+// Ethereum (EIP-2) only accept such signatures, since
+// (r, n - s) is valid as well, and would be a different
+// encoding of the same signature
+func (sig *ECDSASignature) IsLowS(c *Curve) bool {
+	halfN := new(big.Int).Rsh(c.N, 1)
+	return sig.S.Cmp(halfN) <= 0
+}
+
+// Replaces s with n - s if s > n/2. Returns true
+// if signature was changed
+func (sig *ECDSASignature) NormalizeLowS(c *Curve) bool {
+	if sig.IsLowS(c) {
+		return false
+	}
+	sig.S = new(big.Int).Sub(c.N, sig.S)
+	return true
+}
+
+// Returns signature encoded as ASN.1 DER
+// SEQUENCE { r INTEGER, s INTEGER }, the format used
+// by X.509, TLS and `crypto/ecdsa`
+func (sig *ECDSASignature) MarshalDER() ([]byte, error) {
+	if sig.R == nil || sig.S == nil || sig.R.Sign() <= 0 || sig.S.Sign() <= 0 {
+		return nil, errors.New("invalid signature values")
+	}
+	return asn1.Marshal(*sig)
+}
+
+// Parses ASN.1 DER encoded signature. Trailing data,
+// and non-positive values are rejected
+func ParseECDSASignatureDER(der []byte) (*ECDSASignature, error) {
+	sig := new(ECDSASignature)
+	rest, err := asn1.Unmarshal(der, sig)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("trailing data after signature")
+	}
+	if sig.R.Sign() <= 0 || sig.S.Sign() <= 0 {
+		return nil, errors.New("invalid signature values")
+	}
+	return sig, nil
+}
+
+// Returns fixed width r || s encoding, each value padded to
+// the byte length of the group order (64 bytes for P-256
+// and secp256k1). This format is used by JWS, WebCrypto
+// and most of the hardware tokens
+func (sig *ECDSASignature) Bytes(c *Curve) []byte {
+	size := (c.N.BitLen() + 7) / 8
+	out := make([]byte, 2*size)
+	sig.R.FillBytes(out[:size])
+	sig.S.FillBytes(out[size:])
+	return out
+}
+
+// Parses fixed width r || s signature, see `Bytes`
+func ParseECDSASignature(c *Curve, b []byte) (*ECDSASignature, error) {
+	size := (c.N.BitLen() + 7) / 8
+	if len(b) != 2*size {
+		return nil, errors.New("invalid signature length")
+	}
+	sig := &ECDSASignature{
+		R: new(big.Int).SetBytes(b[:size]),
+		S: new(big.Int).SetBytes(b[size:]),
+	}
+	if sig.R.Sign() == 0 || sig.R.Cmp(c.N) >= 0 ||
+		sig.S.Sign() == 0 || sig.S.Cmp(c.N) >= 0 {
+		return nil, errors.New("invalid signature values")
+	}
+	return sig, nil
+}
@@ -0,0 +1,154 @@
+package ECwrap
+
+import (
+	"errors"
+	"hash"
+	"math/big"
+)
+
+// Public key recovered from ECDSA signature together
+// with the recovery id that leads to it
+type ECDSARecovered struct {
+	RecID byte
+	Q     *ECPoint
+}
+
+// Same as `ECDSASign`, but also returns the recovery id,
+// which allows to get public key back from the signature
+// (see `ECDSARecoverPublicKey`)
+//
+// Signature is normalized to low-S, as Bitcoin and Ethereum
+// require, recovery id is fixed accordingly (negating s
+// is the same as negating R, which flips parity of R.y)
+func ECDSASignRecoverable(c *Curve, d *Scalar, hash []byte, h func() hash.Hash) (*ECDSASignature, byte, error) {
+	sig, recid, err := ecdsaSignDeterministic(c, d, hash, h)
+	if err != nil {
+		return nil, 0, err
+	}
+	if sig.NormalizeLowS(c) {
+		recid ^= 1
+	}
+	return sig, recid, nil
+}
+
+// Recovers public key from signature of already hashed message
+//
+// R is restored from r: its x is r + j*n where j = recid >> 1
+// (for curves where p > n it is almost always 0), and parity
+// of its y is recid & 1. Then Q = r^(-1) * (s*R - e*G), which
+// is computed with a single multi scalar multiplication
+func ECDSARecoverPublicKey(c *Curve, hash []byte, sig *ECDSASignature, recid byte) (*ECPoint, error) {
+	if sig == nil || sig.R == nil || sig.S == nil {
+		return nil, errors.New("signature is nil")
+	}
+	if sig.R.Sign() <= 0 || sig.R.Cmp(c.N) >= 0 {
+		return nil, errors.New("signature r is out of range [1, n - 1]")
+	}
+	if sig.S.Sign() <= 0 || sig.S.Cmp(c.N) >= 0 {
+		return nil, errors.New("signature s is out of range [1, n - 1]")
+	}
+
+	// x = r + j*n
+	x := new(big.Int).Add(
+		sig.R,
+		new(big.Int).Mul(big.NewInt(int64(recid>>1)), c.N),
+	)
+	if x.Cmp(c.P) >= 0 {
+		return nil, errors.New("recovery id gives x >= p")
+	}
+	R, err := c.ECPFromX(x, recid&1 == 1)
+	if err != nil {
+		return nil, errors.New("signature is not for a valid curve point")
+	}
+	if c.H.Cmp(big.NewInt(1)) != 0 && !c.ECPScalarMul(R, c.N).IsInfinity() {
+		return nil, errors.New("R is not in the subgroup of order n")
+	}
+
+	e := bits2int(hash, c.N)
+	rInv := new(big.Int).ModInverse(sig.R, c.N)
+	// u1 = -e * r^(-1), u2 = s * r^(-1)
+	u1 := new(big.Int).Mod(new(big.Int).Mul(new(big.Int).Neg(e), rInv), c.N)
+	u2 := new(big.Int).Mod(new(big.Int).Mul(sig.S, rInv), c.N)
+
+	Q := c.ECPMultiScalarMul(
+		[]*ECPoint{c.Generator(), R},
+		[]*big.Int{u1, u2},
+	)
+	if Q.IsInfinity() {
+		return nil, errors.New("recovered public key is the point at infinity")
+	}
+	return c.ECPAffine(Q), nil
+}
+
+// Returns all public keys the signature can be recovered to,
+// for the case the recovery id is unknown. Every one of them
+// verifies the signature, so the right one has to be picked
+// by some other means (address, fingerprint, etc).
+// Nil or malformed signature gives no candidates
+func ECDSARecoverCandidates(c *Curve, hash []byte, sig *ECDSASignature) []ECDSARecovered {
+	if sig == nil || sig.R == nil || sig.S == nil {
+		return nil
+	}
+	var out []ECDSARecovered
+	// recid is a byte, so j can not be larger than 127
+	for j := int64(0); j < 128; j++ {
+		x := new(big.Int).Add(sig.R, new(big.Int).Mul(big.NewInt(j), c.N))
+		if x.Cmp(c.P) >= 0 {
+			break
+		}
+		for parity := byte(0); parity < 2; parity++ {
+			recid := byte(j<<1) | parity
+			Q, err := ECDSARecoverPublicKey(c, hash, sig, recid)
+			if err == nil {
+				out = append(out, ECDSARecovered{RecID: recid, Q: Q})
+			}
+		}
+	}
+	return out
+}
+
+// Signs already hashed message and returns 65 byte compact
+// signature header || r || s as used by Bitcoin message
+// signing. Header is 27 + recid, plus 4 if the signer's key
+// is meant to be compressed
+func ECDSASignCompact(c *Curve, d *Scalar, hash []byte, h func() hash.Hash, compressed bool) ([]byte, error) {
+	sig, recid, err := ECDSASignRecoverable(c, d, hash, h)
+	if err != nil {
+		return nil, err
+	}
+	if recid > 3 {
+		return nil, errors.New("recovery id does not fit compact signature")
+	}
+	header := 27 + recid
+	if compressed {
+		header += 4
+	}
+	return append([]byte{header}, sig.Bytes(c)...), nil
+}
+
+// Recovers public key from compact signature, see
+// `ECDSASignCompact`. Also reports whether the key was
+// flagged as compressed
+func ECDSARecoverCompact(c *Curve, compact, hash []byte) (*ECPoint, bool, error) {
+	size := (c.N.BitLen() + 7) / 8
+	if len(compact) != 1+2*size {
+		return nil, false, errors.New("invalid compact signature length")
+	}
+	header := compact[0]
+	if header < 27 || header > 34 {
+		return nil, false, errors.New("invalid compact signature recovery code")
+	}
+	recid := header - 27
+	compressed := recid >= 4
+	recid &= 3
+
+	sig := &ECDSASignature{
+		R: new(big.Int).SetBytes(compact[1 : 1+size]),
+		S: new(big.Int).SetBytes(compact[1+size:]),
+	}
+	Q, err := ECDSARecoverPublicKey(c, hash, sig, recid)
+	if err != nil {
+		return nil, false, err
+	}
+	return Q, compressed, nil
+}
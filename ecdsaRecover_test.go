@@ -0,0 +1,169 @@
+package ECwrap
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+func decodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic("bad hex: " + s)
+	}
+	return b
+}
+
+// Cases from btcec (originally github.com/fjl/btcec-issue),
+// signatures are recid || r || s
+func TestECDSARecoverCompact(t *testing.T) {
+	c := Secp256k1()
+	tests := []struct {
+		msg string
+		sig string
+		pub string
+	}{
+		{
+			// valid curve point recovered
+			msg: "ce0677bb30baa8cf067c88db9811f4333d131bf8bcf12fe7065d211dce971008",
+			sig: "0190f27b8b488db00b00606796d2987f6a5f59ae62ea05effe84fef5b8b0e549984a691139ad57a3f0b906637673aa2f63d1f55cb1a69199d4009eea23ceaddc93",
+			pub: "04E32DF42865E97135ACFB65F3BAE71BDC86F4D49150AD6A440B6F15878109880A0A2B2667F7E725CEEA70C673093BF67663E0312623C8E091B13CF2C0F11EF652",
+		},
+		{
+			// invalid curve point recovered
+			msg: "00c547e4f7b0f325ad1e56f57e26c745b09a3e503d86e00e5255ff7f715d3d1c",
+			sig: "0100b1693892219d736caba55bdb67216e485557ea6b6af75f37096c9aa6a5a75f00b940b1d03b21e36b0e47e79769f095fe2ab855bd91e3a38756b7d75a9c4549",
+		},
+		{
+			// point at infinity recovered
+			msg: "6b8d2c81b11b2d699528dde488dbdf2f94293d0d33c32e347f255fa4a6c1f0a9",
+			sig: "0079be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f817986b8d2c81b11b2d699528dde488dbdf2f94293d0d33c32e347f255fa4a6c1f0a9",
+		},
+		{
+			// low r and s values
+			msg: "ba09edc1275a285fb27bfe82c4eea240a907a0dbaf9e55764b8f318c37d5974f",
+			sig: "00000000000000000000000000000000000000000000000000000000000000002c0000000000000000000000000000000000000000000000000000000000000004",
+			pub: "04A7640409AA2083FDAD38B2D8DE1263B2251799591D840653FB02DBBA503D7745FCB83D80E08A1E02896BE691EA6AFFB8A35939A646F1FC79052A744B1C82EDC3",
+		},
+		{
+			// r = 0
+			msg: "2bcebac60d8a78e520ae81c2ad586792df495ed429bd730dcd897b301932d054",
+			sig: "000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000007c",
+		},
+		{
+			// s = n
+			msg: "ce0677bb30baa8cf067c88db9811f4333d131bf8bcf12fe7065d211dce971008",
+			sig: "0190f27b8b488db00b00606796d2987f6a5f59ae62ea05effe84fef5b8b0e54998fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141",
+		},
+	}
+	for i, test := range tests {
+		sig := decodeHex(test.sig)
+		sig[0] += 27
+		Q, _, err := ECDSARecoverCompact(c, sig, decodeHex(test.msg))
+		if test.pub == "" {
+			if err == nil {
+				t.Errorf(`#%d ECDSARecoverCompact() error = nil, expected error`, i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf(`#%d ECDSARecoverCompact() error = %v`, i, err)
+			continue
+		}
+		if got := c.ECPMarshal(Q); !bytes.Equal(got, decodeHex(test.pub)) {
+			t.Errorf(`#%d ECDSARecoverCompact() = %X, expected = %s`, i, got, test.pub)
+		}
+	}
+}
+
+// RFC 6979 secp256k1 vectors matching Trezor and CoreBitcoin,
+// signatures there are low-S normalized
+func TestECDSASignCompact(t *testing.T) {
+	c := Secp256k1()
+	tests := []struct {
+		key string
+		msg string
+		der string
+	}{
+		{
+			"cca9fbcc1b41e5a95d369eaa6ddcff73b61a4efaa279cfc6567e8daa39cbaf50",
+			"sample",
+			"3045022100af340daf02cc15c8d5d08d7735dfe6b98a474ed373bdb5fbecf7571be52b384202205009fb27f37034a9b24b707b7c6b79ca23ddef9e25f7282e8a797efe53a8f124",
+		},
+		{
+			// s is higher than n/2 before normalization
+			"0000000000000000000000000000000000000000000000000000000000000001",
+			"Satoshi Nakamoto",
+			"3045022100934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d802202442ce9d2b916064108014783e923ec36b49743e2ffa1c4496f01a512aafd9e5",
+		},
+		{
+			"fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364140",
+			"Satoshi Nakamoto",
+			"3045022100fd567d121db66e382991534ada77a6bd3106f0a1098c231e47993447cd6af2d002206b39cd0eb1bc8603e159ef5c20a5c8ad685a45b06ce9bebed3f153d10d93bed5",
+		},
+		{
+			"f8b8af8ce3c7cca5e300d33939540c10d45ce001b8f252bfbc57ba0342904181",
+			"Alan Turing",
+			"304402207063ae83e7f62bbb171798131b4a0564b956930092b33b07b395615d9ec7e15c022058dfcc1e00a35e1572f366ffe34ba0fc47db1e7189759b9fb233c5b05ab388ea",
+		},
+	}
+	for i, test := range tests {
+		d, _ := c.ScalarFromBytes(decodeHex(test.key))
+		Q := c.ECPBaseMul(d)
+		digest := sha256.Sum256([]byte(test.msg))
+
+		compact, err := ECDSASignCompact(c, d, digest[:], sha256.New, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(compact) != 65 {
+			t.Fatalf(`#%d len(ECDSASignCompact()) = %d, expected = 65`, i, len(compact))
+		}
+		sig, _ := ParseECDSASignature(c, compact[1:])
+		der, _ := sig.MarshalDER()
+		if !bytes.Equal(der, decodeHex(test.der)) {
+			t.Errorf(`#%d ECDSASignCompact() = %X, expected = %s`, i, der, test.der)
+		}
+
+		R, compressed, err := ECDSARecoverCompact(c, compact, digest[:])
+		if err != nil || !compressed || !c.ECPEqual(R, Q) {
+			t.Errorf(`#%d ECDSARecoverCompact() did not return the signer key, err = %v`, i, err)
+		}
+
+		found := false
+		for _, cand := range ECDSARecoverCandidates(c, digest[:], sig) {
+			if !ECDSAVerify(c, cand.Q, digest[:], sig) {
+				t.Errorf(`#%d candidate with recid %d does not verify`, i, cand.RecID)
+			}
+			found = found || c.ECPEqual(cand.Q, Q)
+		}
+		if !found {
+			t.Errorf(`#%d ECDSARecoverCandidates() does not contain the signer key`, i)
+		}
+	}
+}
+
+// Toy curve has p > n, so x = r + j*n for j > 0 is possible
+func TestECDSARecoverToy(t *testing.T) {
+	c := toyCurve(t)
+	for d := int64(1); d < 5; d++ {
+		Q := c.ECPScalarBaseMul(big.NewInt(d))
+		for k := int64(1); k < 5; k++ {
+			sig, recid, err := ecdsaSign(c, c.NewScalar(big.NewInt(d)), []byte{3}, c.NewScalar(big.NewInt(k)))
+			if err != nil {
+				continue
+			}
+			R, err := ECDSARecoverPublicKey(c, []byte{3}, sig, recid)
+			if err != nil || !c.ECPEqual(R, Q) {
+				t.Fatalf(`ECDSARecoverPublicKey(d = %d, k = %d) did not return the signer key, err = %v`, d, k, err)
+			}
+		}
+	}
+	for _, sig := range []*ECDSASignature{nil, {R: big.NewInt(1)}} {
+		if got := ECDSARecoverCandidates(c, []byte{3}, sig); got != nil {
+			t.Fatalf(`ECDSARecoverCandidates(%v) = %v, expected = nil`, sig, got)
+		}
+	}
+}
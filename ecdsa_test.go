@@ -0,0 +1,204 @@
+package ECwrap
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"math/big"
+	"testing"
+)
+
+func fromHex(s string) *big.Int {
+	v, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("bad hex: " + s)
+	}
+	return v
+}
+
+func testRFC6979(t *testing.T, c *Curve, h func() hash.Hash, D, X, Y, msg, r, s string) {
+	Q := c.ECPScalarBaseMul(fromHex(D))
+	Q = c.ECPAffine(Q)
+	if Q.X.Cmp(fromHex(X)) != 0 || Q.Y.Cmp(fromHex(Y)) != 0 {
+		t.Fatalf(`public key mismatch`)
+	}
+	hh := h()
+	hh.Write([]byte(msg))
+	digest := hh.Sum(nil)
+
+	sig, err := ECDSASign(c, c.NewScalar(fromHex(D)), digest, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig.R.Cmp(fromHex(r)) != 0 || sig.S.Cmp(fromHex(s)) != 0 {
+		t.Errorf("signature mismatch:\n got: (%X, %X)\nwant: (%s, %s)", sig.R, sig.S, r, s)
+	}
+	if !ECDSAVerify(c, Q, digest, sig) {
+		t.Errorf("ECDSAVerify() rejected RFC 6979 signature")
+	}
+}
+
+// Vectors from RFC 6979, appendix A.2, and the looping vector
+// from `crypto/ecdsa` tests
+func TestRFC6979(t *testing.T) {
+	t.Run("P-224", func(t *testing.T) {
+		c := FromElliptic(elliptic.P224())
+		testRFC6979(t, c, sha256.New,
+			"F220266E1105BFE3083E03EC7A3A654651F45E37167E88600BF257C1",
+			"00CF08DA5AD719E42707FA431292DEA11244D64FC51610D94B130D6C",
+			"EEAB6F3DEBE455E3DBF85416F7030CBD94F34F2D6F232C69F3C1385A",
+			"sample",
+			"61AA3DA010E8E8406C656BC477A7A7189895E7E840CDFE8FF42307BA",
+			"BC814050DAB5D23770879494F9E0A680DC1AF7161991BDE692B10101")
+		testRFC6979(t, c, sha256.New,
+			"F220266E1105BFE3083E03EC7A3A654651F45E37167E88600BF257C1",
+			"00CF08DA5AD719E42707FA431292DEA11244D64FC51610D94B130D6C",
+			"EEAB6F3DEBE455E3DBF85416F7030CBD94F34F2D6F232C69F3C1385A",
+			"test",
+			"AD04DDE87B84747A243A631EA47A1BA6D1FAA059149AD2440DE6FBA6",
+			"178D49B1AE90E3D8B629BE3DB5683915F4E8C99FDF6E666CF37ADCFD")
+	})
+	t.Run("P-256", func(t *testing.T) {
+		c := FromElliptic(elliptic.P256())
+		const D = "C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721"
+		const X = "60FED4BA255A9D31C961EB74C6356D68C049B8923B61FA6CE669622E60F29FB6"
+		const Y = "7903FE1008B8BC99A41AE9E95628BC64F2F1B20C2D7E9F5177A3C294D4462299"
+		// first candidate k is >= q for this message
+		testRFC6979(t, c, sha256.New, D, X, Y,
+			"wv[vnX",
+			"EFD9073B652E76DA1B5A019C0E4A2E3FA529B035A6ABB91EF67F0ED7A1F21234",
+			"3DB4706C9D9F4A4FE13BB5E08EF0FAB53A57DBAB2061C83A35FA411C68D2BA33")
+		testRFC6979(t, c, sha256.New, D, X, Y,
+			"sample",
+			"EFD48B2AACB6A8FD1140DD9CD45E81D69D2C877B56AAF991C34D0EA84EAF3716",
+			"F7CB1C942D657C41D436C7A1B6E29F65F3E900DBB9AFF4064DC4AB2F843ACDA8")
+		testRFC6979(t, c, sha256.New, D, X, Y,
+			"test",
+			"F1ABB023518351CD71D881567B1EA663ED3EFCF6C5132B354F28D3B0B7D38367",
+			"019F4113742A2B14BD25926B49C649155F267E60D3814B4C0CC84250E46F0083")
+		testRFC6979(t, c, sha1.New, D, X, Y,
+			"sample",
+			"61340C88C3AAEBEB4F6D667F672CA9759A6CCAA9FA8811313039EE4A35471D32",
+			"6D7F147DAC089441BB2E2FE8F7A3FA264B9C475098FDCF6E00D7C996E1B8B7EB")
+		testRFC6979(t, c, sha512.New, D, X, Y,
+			"sample",
+			"8496A60B5E9B47C825488827E0495B0E3FA109EC4568FD3F8D1097678EB97F00",
+			"2362AB1ADBE2B8ADF9CB9EDAB740EA6049C028114F2460F96554F61FAE3302FE")
+	})
+	t.Run("P-384", func(t *testing.T) {
+		testRFC6979(t, FromElliptic(elliptic.P384()), sha256.New,
+			"6B9D3DAD2E1B8C1C05B19875B6659F4DE23C3B667BF297BA9AA47740787137D896D5724E4C70A825F872C9EA60D2EDF5",
+			"EC3A4E415B4E19A4568618029F427FA5DA9A8BC4AE92E02E06AAE5286B300C64DEF8F0EA9055866064A254515480BC13",
+			"8015D9B72D7D57244EA8EF9AC0C621896708A59367F9DFB9F54CA84B3F1C9DB1288B231C3AE0D4FE7344FD2533264720",
+			"sample",
+			"21B13D1E013C7FA1392D03C5F99AF8B30C570C6F98D4EA8E354B63A21D3DAA33BDE1E888E63355D92FA2B3C36D8FB2CD",
+			"F3AA443FB107745BF4BD77CB3891674632068A10CA67E3D45DB2266FA7D1FEEBEFDC63ECCD1AC42EC0CB8668A4FA0AB0")
+	})
+	t.Run("P-521", func(t *testing.T) {
+		testRFC6979(t, FromElliptic(elliptic.P521()), sha256.New,
+			"0FAD06DAA62BA3B25D2FB40133DA757205DE67F5BB0018FEE8C86E1B68C7E75CAA896EB32F1F47C70855836A6D16FCC1466F6D8FBEC67DB89EC0C08B0E996B83538",
+			"1894550D0785932E00EAA23B694F213F8C3121F86DC97A04E5A7167DB4E5BCD371123D46E45DB6B5D5370A7F20FB633155D38FFA16D2BD761DCAC474B9A2F5023A4",
+			"0493101C962CD4D2FDDF782285E64584139C2F91B47F87FF82354D6630F746A28A0DB25741B5B34A828008B22ACC23F924FAAFBD4D33F81EA66956DFEAA2BFDFCF5",
+			"sample",
+			"1511BB4D675114FE266FC4372B87682BAECC01D3CC62CF2303C92B3526012659D16876E25C7C1E57648F23B73564D67F61C6F14D527D54972810421E7D87589E1A7",
+			"04A171143A83163D6DF460AAF61522695F207A58B95C0644D87E52AA1A347916E4F7A72930B1BC06DBE22CE3F58264AFD23704CBB63B29B931F7DE6C9D949A7ECFC")
+	})
+}
+
+// Signatures must be accepted by `crypto/ecdsa` and the
+// other way around
+func TestECDSAInterop(t *testing.T) {
+	c := FromElliptic(elliptic.P256())
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Q := new(ECPoint)
+	Q.SetCoords(priv.X, priv.Y, big.NewInt(1))
+	digest := sha256.Sum256([]byte("ECwrap"))
+
+	sig, err := ECDSASign(c, c.NewScalar(priv.D), digest[:], sha256.New)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := sig.MarshalDER()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ecdsa.VerifyASN1(&priv.PublicKey, digest[:], der) {
+		t.Fatalf(`ecdsa.VerifyASN1() rejected ECDSASign() signature`)
+	}
+
+	der, err = ecdsa.SignASN1(rand.Reader, priv, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err = ParseECDSASignatureDER(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ECDSAVerify(c, Q, digest[:], sig) {
+		t.Fatalf(`ECDSAVerify() rejected ecdsa.SignASN1() signature`)
+	}
+}
+
+func TestECDSASecp256k1(t *testing.T) {
+	c := Secp256k1()
+	d, _ := c.RandomScalar(rand.Reader)
+	Q := c.ECPBaseMul(d)
+	digest := sha256.Sum256([]byte("ECwrap"))
+
+	sig, err := ECDSASign(c, d, digest[:], sha256.New)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ECDSAVerify(c, Q, digest[:], sig) {
+		t.Fatalf(`ECDSAVerify() = false, expected = true`)
+	}
+	// (r, n - s) is valid as well
+	sig.NormalizeLowS(c)
+	if !sig.IsLowS(c) || !ECDSAVerify(c, Q, digest[:], sig) {
+		t.Fatalf(`low-S signature is not valid`)
+	}
+
+	bad := sha256.Sum256([]byte("ECwrap!"))
+	if ECDSAVerify(c, Q, bad[:], sig) {
+		t.Fatalf(`ECDSAVerify(wrong message) = true, expected = false`)
+	}
+	if ECDSAVerify(c, c.Generator(), digest[:], sig) {
+		t.Fatalf(`ECDSAVerify(wrong key) = true, expected = false`)
+	}
+}
+
+func TestECDSAEncoding(t *testing.T) {
+	c := Secp256k1()
+	sig := &ECDSASignature{R: big.NewInt(1), S: new(big.Int).Sub(c.N, big.NewInt(1))}
+
+	b := sig.Bytes(c)
+	if len(b) != 64 {
+		t.Fatalf(`len(Bytes()) = %d, expected = %d`, len(b), 64)
+	}
+	parsed, err := ParseECDSASignature(c, b)
+	if err != nil || parsed.R.Cmp(sig.R) != 0 || parsed.S.Cmp(sig.S) != 0 {
+		t.Fatalf(`ParseECDSASignature(Bytes()) did not round trip, err = %v`, err)
+	}
+
+	der, err := sig.MarshalDER()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err = ParseECDSASignatureDER(der)
+	if err != nil || parsed.R.Cmp(sig.R) != 0 || parsed.S.Cmp(sig.S) != 0 {
+		t.Fatalf(`ParseECDSASignatureDER(MarshalDER()) did not round trip, err = %v`, err)
+	}
+	if _, err := ParseECDSASignatureDER(append(der, 0)); err == nil {
+		t.Fatalf(`ParseECDSASignatureDER(trailing data) error = nil`)
+	}
+	if _, err := ParseECDSASignature(c, make([]byte, 64)); err == nil {
+		t.Fatalf(`ParseECDSASignature(zeros) error = nil`)
+	}
+}
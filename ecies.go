@@ -0,0 +1,276 @@
+package ECwrap
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"hash"
+	"io"
+)
+
+// Elliptic Curve Integrated Encryption Scheme, SEC 1 v2 (section 5.1)
+// https://www.secg.org/sec1-v2.pdf
+//
+// Sender generates ephemeral key pair (r, R = r*G), computes
+// shared secret Z = x(r*Q) with recipient's public key Q and
+// derives symmetric keys from Z with the KDF. Ciphertext is
+//
+//	R || encrypted message || tag
+//
+// Each message gets its own keys, so by default the IV (nonce)
+// of the symmetric cipher is all zeroes, as SEC 1 does
+
+// Data encapsulation mode of ECIES
+type ECIESMode int
+
+const (
+	// AES-GCM, SharedInfo2 is authenticated as additional data
+	ECIESAESGCM ECIESMode = iota
+	// AES-CTR and HMAC over ciphertext || SharedInfo2
+	ECIESAESCTRHMAC
+)
+
+// KDF of ECIES, `X963KDF` and `ConcatKDF` fit it as they are
+type ECIESKDF func(h func() hash.Hash, z, info []byte, length int) ([]byte, error)
+
+// Parameters of ECIES, both parties must use the same.
+// Zero value is SEC 1 ECIES with X9.63 KDF over SHA-256,
+// AES-128-GCM and uncompressed ephemeral key
+type ECIESParams struct {
+	// Hash function of the KDF and the HMAC, SHA-256 if nil
+	Hash func() hash.Hash
+	// X963KDF if nil
+	KDF ECIESKDF
+	// Derive keys from R || Z instead of Z alone, as
+	// ISO 18033-2 and Tink do. It binds the keys to the
+	// ephemeral key and removes ciphertext malleability
+	// caused by R and -R giving the same Z
+	EphemeralInKDF bool
+	// Use cofactor ECDH, see `ECDHOptions`
+	Cofactor bool
+
+	Mode ECIESMode
+	// AES key length in bytes, 16 if zero
+	KeyLen int
+	// HMAC key and tag lengths for ECIESAESCTRHMAC,
+	// hash size if zero
+	MACKeyLen int
+	TagLen    int
+	// Put random IV in front of the encrypted message
+	// instead of using the all zero one (Tink does so)
+	RandomIV bool
+
+	// Encode ephemeral key compressed
+	Compressed bool
+
+	// Passed to the KDF
+	SharedInfo1 []byte
+	// Authenticated along with the encrypted message
+	SharedInfo2 []byte
+}
+
+func (params *ECIESParams) hash() func() hash.Hash {
+	if params.Hash == nil {
+		return sha256.New
+	}
+	return params.Hash
+}
+
+func (params *ECIESParams) keyLen() int {
+	if params.KeyLen == 0 {
+		return 16
+	}
+	return params.KeyLen
+}
+
+func (params *ECIESParams) macKeyLen() int {
+	if params.MACKeyLen == 0 {
+		return params.hash()().Size()
+	}
+	return params.MACKeyLen
+}
+
+func (params *ECIESParams) tagLen() int {
+	if params.Mode == ECIESAESGCM {
+		return 16
+	}
+	if params.TagLen == 0 {
+		return params.hash()().Size()
+	}
+	return params.TagLen
+}
+
+func (params *ECIESParams) ivLen() int {
+	if params.Mode == ECIESAESGCM {
+		return 12
+	}
+	return aes.BlockSize
+}
+
+func (params *ECIESParams) check() error {
+	if params.Mode != ECIESAESGCM && params.Mode != ECIESAESCTRHMAC {
+		return errors.New("unknown ECIES mode")
+	}
+	if params.TagLen < 0 || params.TagLen > params.hash()().Size() {
+		return errors.New("tag length is out of range [0, hash size]")
+	}
+	return nil
+}
+
+// Derives encryption and MAC keys from shared secret
+// z and the encoded ephemeral key
+func (params *ECIESParams) deriveKeys(z, R []byte) ([]byte, []byte, error) {
+	kdf := params.KDF
+	if kdf == nil {
+		kdf = X963KDF
+	}
+	if params.EphemeralInKDF {
+		z = append(append([]byte(nil), R...), z...)
+	}
+	macKeyLen := 0
+	if params.Mode == ECIESAESCTRHMAC {
+		macKeyLen = params.macKeyLen()
+	}
+	k, err := kdf(params.hash(), z, params.SharedInfo1, params.keyLen()+macKeyLen)
+	if err != nil {
+		return nil, nil, err
+	}
+	return k[:params.keyLen()], k[params.keyLen():], nil
+}
+
+// Encrypts `msg` to public key `Q`. `params` may be nil
+// for the defaults, ephemeral key is generated from `rand`
+func ECIESEncrypt(c *Curve, Q *ECPoint, msg []byte, params *ECIESParams, rand io.Reader) ([]byte, error) {
+	if params == nil {
+		params = &ECIESParams{}
+	}
+	if err := params.check(); err != nil {
+		return nil, err
+	}
+	if err := ECDHValidatePublicKey(c, Q); err != nil {
+		return nil, err
+	}
+	r, R, err := ECDHGenerateKey(c, rand)
+	if err != nil {
+		return nil, err
+	}
+	var out []byte
+	if params.Compressed {
+		out = c.ECPMarshalCompressed(R)
+	} else {
+		out = c.ECPMarshal(R)
+	}
+	z, err := ECDH(c, r, Q, &ECDHOptions{Cofactor: params.Cofactor})
+	if err != nil {
+		return nil, err
+	}
+	ek, mk, err := params.deriveKeys(z, out)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(ek)
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, params.ivLen())
+	macStart := len(out)
+	if params.RandomIV {
+		if _, err := io.ReadFull(rand, iv); err != nil {
+			return nil, errors.New("could not generate rand value")
+		}
+		out = append(out, iv...)
+	}
+
+	switch params.Mode {
+	case ECIESAESGCM:
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		return aead.Seal(out, iv, msg, params.SharedInfo2), nil
+	case ECIESAESCTRHMAC:
+		start := len(out)
+		out = append(out, make([]byte, len(msg))...)
+		cipher.NewCTR(block, iv).XORKeyStream(out[start:], msg)
+		// IV is authenticated too when it is random
+		return append(out, eciesTag(params, mk, out[macStart:])...), nil
+	}
+	return nil, errors.New("unknown ECIES mode")
+}
+
+// Decrypts ciphertext produced by `ECIESEncrypt` with
+// private key `d`, `params` must be the same
+func ECIESDecrypt(c *Curve, d *Scalar, ct []byte, params *ECIESParams) ([]byte, error) {
+	if params == nil {
+		params = &ECIESParams{}
+	}
+	if err := params.check(); err != nil {
+		return nil, err
+	}
+	size := c.byteLen()
+	if len(ct) == 0 {
+		return nil, errors.New("ciphertext is too short")
+	}
+	rLen := 1 + size
+	if ct[0] == 0x04 {
+		rLen = 1 + 2*size
+	}
+	ivLen := 0
+	if params.RandomIV {
+		ivLen = params.ivLen()
+	}
+	if len(ct) < rLen+ivLen+params.tagLen() {
+		return nil, errors.New("ciphertext is too short")
+	}
+	R, err := c.ECPUnmarshal(ct[:rLen])
+	if err != nil {
+		return nil, err
+	}
+	z, err := ECDH(c, d, R, &ECDHOptions{Cofactor: params.Cofactor})
+	if err != nil {
+		return nil, err
+	}
+	ek, mk, err := params.deriveKeys(z, ct[:rLen])
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(ek)
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, params.ivLen())
+	copy(iv, ct[rLen:rLen+ivLen])
+	body := ct[rLen+ivLen:]
+
+	switch params.Mode {
+	case ECIESAESGCM:
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		return aead.Open(nil, iv, body, params.SharedInfo2)
+	case ECIESAESCTRHMAC:
+		em := body[:len(body)-params.tagLen()]
+		tag := eciesTag(params, mk, ct[rLen:len(ct)-params.tagLen()])
+		if subtle.ConstantTimeCompare(tag, body[len(em):]) != 1 {
+			return nil, errors.New("message authentication failed")
+		}
+		out := make([]byte, len(em))
+		cipher.NewCTR(block, iv).XORKeyStream(out, em)
+		return out, nil
+	}
+	return nil, errors.New("unknown ECIES mode")
+}
+
+// HMAC(mk, data || SharedInfo2), truncated to the tag length
+func eciesTag(params *ECIESParams, mk, data []byte) []byte {
+	mac := hmac.New(params.hash(), mk)
+	mac.Write(data)
+	mac.Write(params.SharedInfo2)
+	return mac.Sum(nil)[:params.tagLen()]
+}
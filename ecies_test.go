@@ -0,0 +1,122 @@
+package ECwrap
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Tink's ECIES-AEAD-HKDF: keys are HKDF(R || Z) with context
+// info, AES-GCM with random IV in front of the ciphertext
+func TestECIESTink(t *testing.T) {
+	f, err := os.Open("testdata/tink-ecies-vectors.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	curves := map[string]*Curve{
+		"P256": FromElliptic(elliptic.P256()),
+		"P384": FromElliptic(elliptic.P384()),
+		"P521": FromElliptic(elliptic.P521()),
+	}
+	hashes := map[string]func() hash.Hash{
+		"SHA256": sha256.New,
+		"SHA384": sha512.New384,
+		"SHA512": sha512.New,
+	}
+	hkdfNoSalt := func(h func() hash.Hash, z, info []byte, length int) ([]byte, error) {
+		return HKDF(h, z, nil, info, length)
+	}
+
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		if strings.HasPrefix(s.Text(), "#") {
+			continue
+		}
+		v := strings.Split(s.Text(), "|")
+		c := curves[v[1]]
+		keyLen, _ := strconv.Atoi(v[3])
+		params := &ECIESParams{
+			Hash:           hashes[v[2]],
+			KDF:            hkdfNoSalt,
+			EphemeralInKDF: true,
+			KeyLen:         keyLen,
+			RandomIV:       true,
+			Compressed:     v[4] == "compressed",
+			SharedInfo1:    decodeHex(v[7]),
+		}
+		d, _ := c.ScalarFromBytes(decodeHex(v[5]))
+		msg := decodeHex(v[6])
+
+		got, err := ECIESDecrypt(c, d, decodeHex(v[8]), params)
+		if err != nil || !bytes.Equal(got, msg) {
+			t.Errorf(`%s: ECIESDecrypt() = %x, expected = %x, err = %v`, v[0], got, msg, err)
+			continue
+		}
+
+		ct, err := ECIESEncrypt(c, c.ECPBaseMul(d), msg, params, rand.Reader)
+		if err != nil {
+			t.Fatalf(`%s: ECIESEncrypt() error = %v`, v[0], err)
+		}
+		if len(ct) != len(decodeHex(v[8])) {
+			t.Errorf(`%s: ciphertext length = %d, expected = %d`, v[0], len(ct), len(decodeHex(v[8])))
+		}
+		if got, err := ECIESDecrypt(c, d, ct, params); err != nil || !bytes.Equal(got, msg) {
+			t.Errorf(`%s: round trip failed, err = %v`, v[0], err)
+		}
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestECIES(t *testing.T) {
+	msg := []byte("small payload")
+	params := []*ECIESParams{
+		nil,
+		{Compressed: true, SharedInfo1: []byte("s1"), SharedInfo2: []byte("s2")},
+		{Mode: ECIESAESCTRHMAC},
+		{Mode: ECIESAESCTRHMAC, KDF: ConcatKDF, Hash: sha512.New, KeyLen: 32, TagLen: 16, Compressed: true},
+		{Mode: ECIESAESCTRHMAC, RandomIV: true, EphemeralInKDF: true, SharedInfo2: []byte("s2")},
+	}
+	testAllCurves(t, func(t *testing.T, c *Curve) {
+		d, Q, _ := ECDHGenerateKey(c, rand.Reader)
+		for i, p := range params {
+			ct, err := ECIESEncrypt(c, Q, msg, p, rand.Reader)
+			if err != nil {
+				t.Fatalf(`params %d: ECIESEncrypt() error = %v`, i, err)
+			}
+			got, err := ECIESDecrypt(c, d, ct, p)
+			if err != nil || !bytes.Equal(got, msg) {
+				t.Fatalf(`params %d: ECIESDecrypt() = %q, expected = %q, err = %v`, i, got, msg, err)
+			}
+
+			// any flipped bit must be caught, in R as well
+			for _, pos := range []int{1, len(ct) / 2, len(ct) - 1} {
+				bad := append([]byte(nil), ct...)
+				bad[pos] ^= 1
+				if _, err := ECIESDecrypt(c, d, bad, p); err == nil {
+					t.Fatalf(`params %d: tampered byte %d is not detected`, i, pos)
+				}
+			}
+			other := &ECIESParams{}
+			if p != nil {
+				*other = *p
+			}
+			other.SharedInfo2 = []byte("other")
+			if _, err := ECIESDecrypt(c, d, ct, other); err == nil {
+				t.Fatalf(`params %d: wrong SharedInfo2 is not detected`, i)
+			}
+		}
+	})
+}
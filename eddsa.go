@@ -0,0 +1,261 @@
+package ECwrap
+
+import (
+	"crypto/sha3"
+	"crypto/sha512"
+	"errors"
+	"io"
+	"math/big"
+	"sync"
+)
+
+// EdDSA instance as defined in RFC 8032: curve, hash function,
+// domain separation and the bits of the secret scalar
+// https://www.rfc-editor.org/rfc/rfc8032
+//
+// Ed25519 and Ed448 are available, options select the
+// variant (context, pre-hashing) and verification mode
+type EdDSA struct {
+	Name  string
+	Curve *EdwardsCurve
+
+	// length of keys and of each half of the signature, b/8
+	size int
+	// log2 of the cofactor, c in RFC 8032
+	c uint
+	// the highest set bit of the secret scalar, n in RFC 8032
+	n int
+
+	// H, hashes all the parts into 2*size bytes
+	hash func(parts ...[]byte) []byte
+	// PH for the pre-hashed variant
+	prehash func(msg []byte) []byte
+	// dom2 / dom4 prefix
+	dom func(phflag byte, ctx []byte) []byte
+	// plain Ed25519 has no prefix at all, unlike Ed448
+	bareDom bool
+}
+
+// Options of EdDSA signing and verification
+type EdDSAOptions struct {
+	// Context string, at most 255 bytes. For Ed25519 non empty
+	// context selects Ed25519ctx, Ed448 always has one
+	Context []byte
+	// Use pre-hashed variant (Ed25519ph / Ed448ph), message
+	// is hashed with PH inside, pass it as is
+	Prehash bool
+	// Verify with [S]B == R + [k]A instead of the cofactored
+	// [2^c][S]B == [2^c]R + [2^c][k]A. Both are allowed by
+	// RFC 8032, but they disagree on signatures with small
+	// order components, so implementations must pick one
+	Cofactorless bool
+}
+
+var initEd25519, initEd448 sync.Once
+var ed25519, ed448 *EdDSA
+
+// Returns Ed25519 (RFC 8032, 5.1), SHA-512 over edwards25519
+func Ed25519() *EdDSA {
+	initEd25519.Do(func() {
+		ed25519 = &EdDSA{
+			Name:    "Ed25519",
+			Curve:   Edwards25519(),
+			size:    32,
+			c:       3,
+			n:       254,
+			bareDom: true,
+			hash: func(parts ...[]byte) []byte {
+				h := sha512.New()
+				for _, p := range parts {
+					h.Write(p)
+				}
+				return h.Sum(nil)
+			},
+			prehash: func(msg []byte) []byte {
+				h := sha512.Sum512(msg)
+				return h[:]
+			},
+			dom: func(phflag byte, ctx []byte) []byte {
+				return append(
+					append([]byte("SigEd25519 no Ed25519 collisions"), phflag, byte(len(ctx))),
+					ctx...,
+				)
+			},
+		}
+	})
+	return ed25519
+}
+
+// Returns Ed448 (RFC 8032, 5.2), SHAKE256 over edwards448
+func Ed448() *EdDSA {
+	initEd448.Do(func() {
+		ed448 = &EdDSA{
+			Name:  "Ed448",
+			Curve: Edwards448(),
+			size:  57,
+			c:     2,
+			n:     447,
+			hash: func(parts ...[]byte) []byte {
+				h := sha3.NewSHAKE256()
+				for _, p := range parts {
+					h.Write(p)
+				}
+				out := make([]byte, 114)
+				h.Read(out)
+				return out
+			},
+			prehash: func(msg []byte) []byte {
+				return sha3.SumSHAKE256(msg, 64)
+			},
+			dom: func(phflag byte, ctx []byte) []byte {
+				return append(
+					append([]byte("SigEd448"), phflag, byte(len(ctx))),
+					ctx...,
+				)
+			},
+		}
+	})
+	return ed448
+}
+
+// Returns domain separation prefix and the message to sign
+// (hashed with PH for pre-hashed variants)
+func (e *EdDSA) prepare(msg []byte, opts *EdDSAOptions) ([]byte, []byte, error) {
+	if opts == nil {
+		opts = &EdDSAOptions{}
+	}
+	if len(opts.Context) > 255 {
+		return nil, nil, errors.New("context is longer than 255 bytes")
+	}
+	var phflag byte
+	if opts.Prehash {
+		phflag = 1
+		msg = e.prehash(msg)
+	}
+	if e.bareDom && !opts.Prehash && len(opts.Context) == 0 {
+		return nil, msg, nil
+	}
+	return e.dom(phflag, opts.Context), msg, nil
+}
+
+// Returns clamped secret scalar s and the prefix
+// used for nonce derivation (RFC 8032, 5.1.5)
+func (e *EdDSA) expand(sk []byte) (*big.Int, []byte, error) {
+	if len(sk) != e.size {
+		return nil, nil, errors.New("invalid private key length")
+	}
+	h := e.hash(sk)
+	// clear the lowest c bits, clear all the bits above n,
+	// and set bit n
+	s := leInt(h[:e.size])
+	for i := uint(0); i < e.c; i++ {
+		s.SetBit(s, int(i), 0)
+	}
+	for i := e.n + 1; i < 8*e.size; i++ {
+		s.SetBit(s, i, 0)
+	}
+	s.SetBit(s, e.n, 1)
+	return s, h[e.size:], nil
+}
+
+// Returns public key for private key `sk` (32 bytes for
+// Ed25519, 57 for Ed448)
+func (e *EdDSA) PublicKey(sk []byte) ([]byte, error) {
+	s, _, err := e.expand(sk)
+	if err != nil {
+		return nil, err
+	}
+	return e.Curve.Encode(e.Curve.ScalarBaseMul(s)), nil
+}
+
+// Generates key pair, private key is `size` bytes of `rand`
+func (e *EdDSA) GenerateKey(rand io.Reader) ([]byte, []byte, error) {
+	sk := make([]byte, e.size)
+	if _, err := io.ReadFull(rand, sk); err != nil {
+		return nil, nil, errors.New("could not generate rand value")
+	}
+	pub, err := e.PublicKey(sk)
+	return sk, pub, err
+}
+
+// Signs message `msg` with private key `sk`, options
+// may be nil for plain Ed25519 / Ed448
+func (e *EdDSA) Sign(sk, msg []byte, opts *EdDSAOptions) ([]byte, error) {
+	s, prefix, err := e.expand(sk)
+	if err != nil {
+		return nil, err
+	}
+	dom, msg, err := e.prepare(msg, opts)
+	if err != nil {
+		return nil, err
+	}
+	c := e.Curve
+	A := c.Encode(c.ScalarBaseMul(s))
+
+	// r = H(dom || prefix || M) mod L, R = [r]B
+	r := leInt(e.hash(dom, prefix, msg))
+	r.Mod(r, c.N)
+	R := c.Encode(c.ScalarBaseMul(r))
+
+	// k = H(dom || R || A || M) mod L
+	k := leInt(e.hash(dom, R, A, msg))
+	k.Mod(k, c.N)
+
+	// S = r + k*s mod L
+	S := new(big.Int).Add(r, new(big.Int).Mul(k, s))
+	S.Mod(S, c.N)
+
+	return append(R, leBytes(S, e.size)...), nil
+}
+
+// Verifies signature `sig` of message `msg` against public key `pub`
+func (e *EdDSA) Verify(pub, msg, sig []byte, opts *EdDSAOptions) bool {
+	c := e.Curve
+	if len(sig) != 2*e.size {
+		return false
+	}
+	dom, msg, err := e.prepare(msg, opts)
+	if err != nil {
+		return false
+	}
+	A, err := c.Decode(pub)
+	if err != nil {
+		return false
+	}
+	R, err := c.Decode(sig[:e.size])
+	if err != nil {
+		return false
+	}
+	S := leInt(sig[e.size:])
+	if S.Cmp(c.N) >= 0 {
+		return false
+	}
+	k := leInt(e.hash(dom, sig[:e.size], pub, msg))
+	k.Mod(k, c.N)
+
+	// [S]B - [k]A - R
+	D := c.Add(
+		c.ScalarBaseMul(S),
+		c.Neg(c.Add(c.ScalarMul(A, k), R)),
+	)
+	if opts == nil || !opts.Cofactorless {
+		for i := uint(0); i < e.c; i++ {
+			D = c.Double(D)
+		}
+	}
+	return c.IsIdentity(D)
+}
+
+// Little-endian bytes to integer
+func leInt(b []byte) *big.Int {
+	be := append([]byte(nil), b...)
+	reverse(be)
+	return new(big.Int).SetBytes(be)
+}
+
+// Integer to `size` little-endian bytes
+func leBytes(v *big.Int, size int) []byte {
+	out := v.FillBytes(make([]byte, size))
+	reverse(out)
+	return out
+}
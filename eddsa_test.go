@@ -0,0 +1,143 @@
+package ECwrap
+
+import (
+	"bufio"
+	"bytes"
+	std25519 "crypto/ed25519"
+	"crypto/rand"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestEdwardsGroup(t *testing.T) {
+	for _, c := range []*EdwardsCurve{Edwards25519(), Edwards448()} {
+		t.Run(c.Name, func(t *testing.T) {
+			G := c.Generator()
+			if !c.IsIdentity(c.ScalarMul(G, c.N)) {
+				t.Fatalf(`N*G != identity`)
+			}
+			k, _ := rand.Int(rand.Reader, c.N)
+			P := c.ScalarMul(G, k)
+			if !c.PointIsOnCurve(P) {
+				t.Fatalf(`k*G is not on curve`)
+			}
+			if !c.Equal(c.Double(P), c.Add(P, P)) {
+				t.Fatalf(`Double(P) != Add(P, P)`)
+			}
+			if !c.IsIdentity(c.Add(P, c.Neg(P))) {
+				t.Fatalf(`P + (-P) != identity`)
+			}
+			Q, err := c.Decode(c.Encode(P))
+			if err != nil || !c.Equal(P, Q) {
+				t.Fatalf(`Decode(Encode(P)) did not round trip, err = %v`, err)
+			}
+		})
+	}
+}
+
+func TestEd25519MatchesStd(t *testing.T) {
+	for i := 0; i < 4; i++ {
+		pub, priv, _ := std25519.GenerateKey(rand.Reader)
+		seed := priv.Seed()
+		msg := []byte("ECwrap")
+
+		got, err := Ed25519().PublicKey(seed)
+		if err != nil || !bytes.Equal(got, pub) {
+			t.Fatalf(`PublicKey() = %x, expected = %x, err = %v`, got, pub, err)
+		}
+		sig, _ := Ed25519().Sign(seed, msg, nil)
+		if !bytes.Equal(sig, std25519.Sign(priv, msg)) {
+			t.Fatalf(`Sign() does not match crypto/ed25519`)
+		}
+		if !Ed25519().Verify(pub, msg, sig, nil) {
+			t.Fatalf(`Verify() = false, expected = true`)
+		}
+	}
+}
+
+func TestRFC8032(t *testing.T) {
+	f, err := os.Open("testdata/rfc8032-vectors.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1<<20)
+	for n := 1; s.Scan(); n++ {
+		if strings.HasPrefix(s.Text(), "#") {
+			continue
+		}
+		v := strings.Split(s.Text(), "|")
+		var e *EdDSA
+		opts := &EdDSAOptions{
+			Context: decodeHex(v[4]),
+			Prehash: strings.HasSuffix(v[0], "ph"),
+		}
+		if strings.HasPrefix(v[0], "Ed25519") {
+			e = Ed25519()
+		} else {
+			e = Ed448()
+		}
+		sk, pk, msg, sig := decodeHex(v[1]), decodeHex(v[2]), decodeHex(v[3]), decodeHex(v[5])
+
+		got, err := e.PublicKey(sk)
+		if err != nil || !bytes.Equal(got, pk) {
+			t.Errorf(`line %d %s PublicKey() = %x, expected = %x, err = %v`, n, v[0], got, pk, err)
+		}
+		got, err = e.Sign(sk, msg, opts)
+		if err != nil || !bytes.Equal(got, sig) {
+			t.Errorf(`line %d %s Sign() = %x, expected = %x, err = %v`, n, v[0], got, sig, err)
+		}
+		for _, cofactorless := range []bool{false, true} {
+			opts.Cofactorless = cofactorless
+			if !e.Verify(pk, msg, sig, opts) {
+				t.Errorf(`line %d %s Verify(cofactorless = %t) = false`, n, v[0], cofactorless)
+			}
+		}
+		if e.Verify(pk, append(msg, 0), sig, opts) {
+			t.Errorf(`line %d %s Verify(wrong message) = true`, n, v[0])
+		}
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Public key with a small order component: cofactored
+// verification accepts such signatures, cofactorless does not
+func TestEd25519Cofactor(t *testing.T) {
+	e := Ed25519()
+	c := e.Curve
+	sk := make([]byte, 32)
+	s, prefix, _ := e.expand(sk)
+
+	// (0, -1) has order 2
+	T := c.NewPoint(big.NewInt(0), new(big.Int).Sub(c.P, big.NewInt(1)))
+	A := c.Encode(c.Add(c.ScalarBaseMul(s), T))
+
+	for i := byte(0); ; i++ {
+		msg := []byte{i}
+		r := leInt(e.hash(prefix, msg))
+		r.Mod(r, c.N)
+		R := c.Encode(c.ScalarBaseMul(r))
+		k := leInt(e.hash(R, A, msg))
+		k.Mod(k, c.N)
+		if k.Bit(0) == 0 {
+			// even k kills the order 2 component anyway
+			continue
+		}
+		S := new(big.Int).Mod(new(big.Int).Add(r, new(big.Int).Mul(k, s)), c.N)
+		sig := append(R, leBytes(S, 32)...)
+
+		if !e.Verify(A, msg, sig, nil) {
+			t.Fatalf(`cofactored Verify() = false, expected = true`)
+		}
+		if e.Verify(A, msg, sig, &EdDSAOptions{Cofactorless: true}) {
+			t.Fatalf(`cofactorless Verify() = true, expected = false`)
+		}
+		return
+	}
+}
@@ -0,0 +1,358 @@
+package ECwrap
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+)
+
+// Twisted Edwards curve a*x^(2) + y^(2) = 1 + d*x^(2)*y^(2) over F_p
+//
+// Unlike short Weierstrass curves, addition law here is the same
+// for P + Q and P + P, and if a is a square and d is not, it has
+// no exceptions at all (it is complete), so there is no need to
+// track the point at infinity. Neutral element is (0, 1)
+type EdwardsCurve struct {
+	Name string
+
+	// order of the underlying field
+	P *big.Int
+	A *big.Int
+	D *big.Int
+	// order of the base point
+	N *big.Int
+	// cofactor, #E(F_p) = N * H
+	H *big.Int
+
+	// base point
+	Gx *big.Int
+	Gy *big.Int
+}
+
+// Point on twisted Edwards curve in extended coordinates
+// (X : Y : Z : T), where x = X/Z, y = Y/Z and x*y = T/Z
+// https://eprint.iacr.org/2008/522
+type EdPoint struct {
+	X *big.Int
+	Y *big.Int
+	Z *big.Int
+	T *big.Int
+}
+
+// Returns new EdwardsCurve with provided parameters. Checks that
+// a != d, both are non-zero and the base point lies on the curve
+func NewEdwardsCurve(name string, p, a, d, gx, gy, n, h *big.Int) (*EdwardsCurve, error) {
+	if p == nil || a == nil || d == nil || gx == nil || gy == nil || n == nil || h == nil {
+		return nil, errors.New("curve parameter is nil")
+	}
+	c := &EdwardsCurve{
+		Name: name,
+		P:    new(big.Int).Set(p),
+		A:    new(big.Int).Mod(a, p),
+		D:    new(big.Int).Mod(d, p),
+		N:    new(big.Int).Set(n),
+		H:    new(big.Int).Set(h),
+		Gx:   new(big.Int).Set(gx),
+		Gy:   new(big.Int).Set(gy),
+	}
+	if c.A.Sign() == 0 || c.D.Sign() == 0 || c.A.Cmp(c.D) == 0 {
+		return nil, errors.New("curve is singular")
+	}
+	if !c.IsOnCurve(c.Gx, c.Gy) {
+		return nil, errors.New("base point is not on curve")
+	}
+	return c, nil
+}
+
+func mustEdwardsCurve(name string, p, a, d, gx, gy, n, h *big.Int) *EdwardsCurve {
+	c, err := NewEdwardsCurve(name, p, a, d, gx, gy, n, h)
+	if err != nil {
+		panic("ECwrap: bad parameters for " + name + ": " + err.Error())
+	}
+	return c
+}
+
+func mustDec(s string) *big.Int {
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		panic("ECwrap: bad constant " + s)
+	}
+	return v
+}
+
+var initEdwards25519, initEdwards448 sync.Once
+var edwards25519, edwards448 *EdwardsCurve
+
+// Returns edwards25519, -x^(2) + y^(2) = 1 - (121665/121666)*x^(2)*y^(2)
+// over 2^(255) - 19, the curve of Ed25519 (RFC 8032, 5.1)
+func Edwards25519() *EdwardsCurve {
+	initEdwards25519.Do(func() {
+		p := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+		// d = -121665 / 121666
+		d := new(big.Int).Mul(
+			big.NewInt(-121665),
+			new(big.Int).ModInverse(big.NewInt(121666), p),
+		)
+		n := new(big.Int).Add(
+			new(big.Int).Lsh(big.NewInt(1), 252),
+			mustDec("27742317777372353535851937790883648493"),
+		)
+		edwards25519 = mustEdwardsCurve("edwards25519",
+			p, big.NewInt(-1), d,
+			mustDec("15112221349535400772501151409588531511454012693041857206046113283949847762202"),
+			mustDec("46316835694926478169428394003475163141307993866256225615783033603165251855960"),
+			n, big.NewInt(8),
+		)
+	})
+	return edwards25519
+}
+
+// Returns edwards448, x^(2) + y^(2) = 1 - 39081*x^(2)*y^(2)
+// over 2^(448) - 2^(224) - 1, the curve of Ed448 (RFC 8032, 5.2)
+func Edwards448() *EdwardsCurve {
+	initEdwards448.Do(func() {
+		p := new(big.Int).Lsh(big.NewInt(1), 448)
+		p.Sub(p, new(big.Int).Lsh(big.NewInt(1), 224))
+		p.Sub(p, big.NewInt(1))
+		n := new(big.Int).Sub(
+			new(big.Int).Lsh(big.NewInt(1), 446),
+			mustDec("13818066809895115352007386748515426880336692474882178609894547503885"),
+		)
+		edwards448 = mustEdwardsCurve("edwards448",
+			p, big.NewInt(1), big.NewInt(-39081),
+			mustDec("224580040295924300187604334099896036246789641632564134246125461686950415467406032909029192869357953282578032075146446173674602635247710"),
+			mustDec("298819210078481492676017930443930673437544040154080242095928241372331506189835876003536878655418784733982303233503462500531545062832660"),
+			n, big.NewInt(4),
+		)
+	})
+	return edwards448
+}
+
+// Reports whether affine (x, y) lies on the curve
+func (c *EdwardsCurve) IsOnCurve(x, y *big.Int) bool {
+	if x.Sign() < 0 || x.Cmp(c.P) >= 0 ||
+		y.Sign() < 0 || y.Cmp(c.P) >= 0 {
+		return false
+	}
+	xx := new(big.Int).Mul(x, x)
+	yy := new(big.Int).Mul(y, y)
+	// a*x^(2) + y^(2)
+	left := new(big.Int).Add(new(big.Int).Mul(c.A, xx), yy)
+	// 1 + d*x^(2)*y^(2)
+	right := new(big.Int).Add(
+		big.NewInt(1),
+		new(big.Int).Mul(c.D, new(big.Int).Mul(xx, yy)),
+	)
+	diff := new(big.Int).Sub(left, right)
+	return diff.Mod(diff, c.P).Sign() == 0
+}
+
+// Returns EdPoint for affine (x, y), it is not checked
+// to be on curve
+func (c *EdwardsCurve) NewPoint(x, y *big.Int) *EdPoint {
+	return &EdPoint{
+		X: new(big.Int).Set(x),
+		Y: new(big.Int).Set(y),
+		Z: big.NewInt(1),
+		T: new(big.Int).Mod(new(big.Int).Mul(x, y), c.P),
+	}
+}
+
+// Returns neutral element (0, 1)
+func (c *EdwardsCurve) Identity() *EdPoint {
+	return c.NewPoint(big.NewInt(0), big.NewInt(1))
+}
+
+// Returns base point of the curve
+func (c *EdwardsCurve) Generator() *EdPoint {
+	return c.NewPoint(c.Gx, c.Gy)
+}
+
+// Returns P1 + P2 with the unified "add-2008-hwcd" formula
+// https://hyperelliptic.org/EFD/g1p/auto-twisted-extended.html#addition-add-2008-hwcd
+// Works for P1 == P2 and the neutral element as well
+func (c *EdwardsCurve) Add(P1, P2 *EdPoint) *EdPoint {
+	A := new(big.Int).Mul(P1.X, P2.X)
+	B := new(big.Int).Mul(P1.Y, P2.Y)
+	C := new(big.Int).Mod(
+		new(big.Int).Mul(new(big.Int).Mul(P1.T, c.D), P2.T),
+		c.P,
+	)
+	D := new(big.Int).Mul(P1.Z, P2.Z)
+	// E = (X1+Y1)*(X2+Y2) - A - B
+	E := new(big.Int).Sub(
+		new(big.Int).Sub(
+			new(big.Int).Mul(
+				new(big.Int).Add(P1.X, P1.Y),
+				new(big.Int).Add(P2.X, P2.Y),
+			),
+			A,
+		),
+		B,
+	)
+	F := new(big.Int).Sub(D, C)
+	G := new(big.Int).Add(D, C)
+	// H = B - a*A
+	H := new(big.Int).Sub(B, new(big.Int).Mul(c.A, A))
+
+	return &EdPoint{
+		X: new(big.Int).Mod(new(big.Int).Mul(E, F), c.P),
+		Y: new(big.Int).Mod(new(big.Int).Mul(G, H), c.P),
+		T: new(big.Int).Mod(new(big.Int).Mul(E, H), c.P),
+		Z: new(big.Int).Mod(new(big.Int).Mul(F, G), c.P),
+	}
+}
+
+// Returns 2*P with "dbl-2008-hwcd" formula, which is
+// cheaper than `Add(P, P)`
+// https://hyperelliptic.org/EFD/g1p/auto-twisted-extended.html#doubling-dbl-2008-hwcd
+func (c *EdwardsCurve) Double(P *EdPoint) *EdPoint {
+	A := new(big.Int).Mul(P.X, P.X)
+	B := new(big.Int).Mul(P.Y, P.Y)
+	C := new(big.Int).Lsh(new(big.Int).Mul(P.Z, P.Z), 1)
+	D := new(big.Int).Mul(c.A, A)
+	XY := new(big.Int).Add(P.X, P.Y)
+	// E = (X1+Y1)^(2) - A - B
+	E := new(big.Int).Sub(new(big.Int).Sub(new(big.Int).Mul(XY, XY), A), B)
+	G := new(big.Int).Add(D, B)
+	F := new(big.Int).Sub(G, C)
+	H := new(big.Int).Sub(D, B)
+
+	return &EdPoint{
+		X: new(big.Int).Mod(new(big.Int).Mul(E, F), c.P),
+		Y: new(big.Int).Mod(new(big.Int).Mul(G, H), c.P),
+		T: new(big.Int).Mod(new(big.Int).Mul(E, H), c.P),
+		Z: new(big.Int).Mod(new(big.Int).Mul(F, G), c.P),
+	}
+}
+
+// Returns -P, which is (-x, y)
+func (c *EdwardsCurve) Neg(P *EdPoint) *EdPoint {
+	return &EdPoint{
+		X: new(big.Int).Mod(new(big.Int).Neg(P.X), c.P),
+		Y: new(big.Int).Set(P.Y),
+		Z: new(big.Int).Set(P.Z),
+		T: new(big.Int).Mod(new(big.Int).Neg(P.T), c.P),
+	}
+}
+
+// Returns k*P, negative k multiplies -P by |k|
+func (c *EdwardsCurve) ScalarMul(P *EdPoint, k *big.Int) *EdPoint {
+	if k.Sign() < 0 {
+		return c.ScalarMul(c.Neg(P), new(big.Int).Neg(k))
+	}
+	R := c.Identity()
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		R = c.Double(R)
+		if k.Bit(i) == 1 {
+			R = c.Add(R, P)
+		}
+	}
+	return R
+}
+
+// Returns k*G
+func (c *EdwardsCurve) ScalarBaseMul(k *big.Int) *EdPoint {
+	return c.ScalarMul(c.Generator(), k)
+}
+
+// Returns affine coordinates of P
+func (c *EdwardsCurve) Affine(P *EdPoint) (*big.Int, *big.Int) {
+	Zinv := new(big.Int).ModInverse(P.Z, c.P)
+	x := new(big.Int).Mod(new(big.Int).Mul(P.X, Zinv), c.P)
+	y := new(big.Int).Mod(new(big.Int).Mul(P.Y, Zinv), c.P)
+	return x, y
+}
+
+// Reports whether P1 and P2 are the same point
+func (c *EdwardsCurve) Equal(P1, P2 *EdPoint) bool {
+	// X1*Z2 == X2*Z1 and Y1*Z2 == Y2*Z1
+	l := new(big.Int).Mod(new(big.Int).Mul(P1.X, P2.Z), c.P)
+	r := new(big.Int).Mod(new(big.Int).Mul(P2.X, P1.Z), c.P)
+	if l.Cmp(r) != 0 {
+		return false
+	}
+	l.Mod(l.Mul(P1.Y, P2.Z), c.P)
+	r.Mod(r.Mul(P2.Y, P1.Z), c.P)
+	return l.Cmp(r) == 0
+}
+
+// Reports whether P is the neutral element (0, 1)
+func (c *EdwardsCurve) IsIdentity(P *EdPoint) bool {
+	return c.Equal(P, c.Identity())
+}
+
+// Reports whether P lies on the curve, checking the
+// extended coordinate T as well
+func (c *EdwardsCurve) PointIsOnCurve(P *EdPoint) bool {
+	if P.Z.Sign() == 0 {
+		return false
+	}
+	// T*Z == X*Y
+	tz := new(big.Int).Mod(new(big.Int).Mul(P.T, P.Z), c.P)
+	xy := new(big.Int).Mod(new(big.Int).Mul(P.X, P.Y), c.P)
+	if tz.Cmp(xy) != 0 {
+		return false
+	}
+	return c.IsOnCurve(c.Affine(P))
+}
+
+// Length of encoded point: y and one more bit for the sign
+// of x (32 bytes for edwards25519, 57 for edwards448)
+func (c *EdwardsCurve) EncodedLen() int {
+	return (c.P.BitLen() + 1 + 7) / 8
+}
+
+// Encodes P as in RFC 8032 (5.1.2): little-endian y,
+// with the least significant bit of x in the top bit
+// of the last byte
+func (c *EdwardsCurve) Encode(P *EdPoint) []byte {
+	x, y := c.Affine(P)
+	out := make([]byte, c.EncodedLen())
+	y.FillBytes(out)
+	reverse(out)
+	out[len(out)-1] |= byte(x.Bit(0)) << 7
+	return out
+}
+
+// Decodes point encoded with `Encode` (RFC 8032, 5.1.3),
+// non-canonical y (y >= p) is rejected
+func (c *EdwardsCurve) Decode(b []byte) (*EdPoint, error) {
+	if len(b) != c.EncodedLen() {
+		return nil, errors.New("invalid point length")
+	}
+	buf := append([]byte(nil), b...)
+	sign := uint(buf[len(buf)-1] >> 7)
+	buf[len(buf)-1] &= 0x7f
+	reverse(buf)
+	y := new(big.Int).SetBytes(buf)
+	if y.Cmp(c.P) >= 0 {
+		return nil, errors.New("y is not less than p")
+	}
+
+	// x^(2) = (y^(2) - 1) / (d*y^(2) - a)
+	yy := new(big.Int).Mul(y, y)
+	u := new(big.Int).Mod(new(big.Int).Sub(yy, big.NewInt(1)), c.P)
+	v := new(big.Int).Mod(new(big.Int).Sub(new(big.Int).Mul(c.D, yy), c.A), c.P)
+	if v.Sign() == 0 {
+		return nil, errors.New("no point with such y on curve")
+	}
+	xx := new(big.Int).Mod(new(big.Int).Mul(u, new(big.Int).ModInverse(v, c.P)), c.P)
+	x := new(big.Int).ModSqrt(xx, c.P)
+	if x == nil {
+		return nil, errors.New("no point with such y on curve")
+	}
+	if x.Sign() == 0 && sign == 1 {
+		return nil, errors.New("x is zero, but sign bit is set")
+	}
+	if x.Bit(0) != sign {
+		x.Sub(c.P, x)
+	}
+	return c.NewPoint(x, y), nil
+}
+
+func reverse(b []byte) {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+}
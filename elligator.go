@@ -0,0 +1,449 @@
+package ECwrap
+
+import (
+	crand "crypto/rand"
+	"errors"
+	"io"
+	"math/big"
+)
+
+// Encodings of points as strings indistinguishable from random
+//
+// Elligator 2 (Bernstein, Hamburg, Krasnova, Lange) inverts the
+// map of RFC 9380: about half of the points of a Montgomery curve
+// have representatives u, and u is uniform when the point is.
+// Elligator Squared (Tibouchi) works for every point of every
+// curve with a map to it: point P is written as f(u) + f(v),
+// with u random and v one of the preimages of P - f(u)
+// https://elligator.cr.yp.to/elligator-20130828.pdf
+// https://eprint.iacr.org/2014/043
+
+// Returns all u with `MapToCurveElligator2(u, z)` = P
+// (none, or u and -u)
+func (c *MontgomeryCurve) Elligator2Inverse(P *ECPoint, z *big.Int) []*big.Int {
+	if P.IsInfinity() {
+		return nil
+	}
+	p := c.P
+	kInv := new(big.Int).ModInverse(c.B, p)
+	jk := new(big.Int).Mod(new(big.Int).Mul(c.A, kInv), p)
+	zInv := new(big.Int).ModInverse(z, p)
+	x := new(big.Int).Mod(new(big.Int).Mul(P.X, kInv), p)
+
+	// x1 = -(J/K)/(1 + Z*u^(2)), so u^(2) = (-(J/K)/x1 - 1)/Z,
+	// where x1 = x or, for x = x2, x1 = -x - J/K. -1/Z is the
+	// exceptional case of 1 + Z*u^(2) = 0
+	var t []*big.Int
+	for _, x1 := range []*big.Int{x, new(big.Int).Neg(new(big.Int).Add(x, jk))} {
+		if x1.Mod(x1, p).Sign() == 0 {
+			continue
+		}
+		tt := new(big.Int).Mul(new(big.Int).Neg(jk), new(big.Int).ModInverse(x1, p))
+		tt.Mul(tt.Sub(tt, big.NewInt(1)), zInv)
+		t = append(t, tt)
+	}
+	t = append(t, new(big.Int).Neg(zInv))
+
+	return preimages(sqrtCandidates(t, p), func(u *big.Int) bool {
+		Q, err := c.MapToCurveElligator2(u, z)
+		return err == nil && Q.X.Cmp(P.X) == 0 && Q.Y.Cmp(P.Y) == 0
+	})
+}
+
+// Encodes Montgomery point (u, v, 1) as its Elligator 2
+// representative, a string of `Elligator2Len` bytes that looks
+// random if the point is random. Error is returned for the
+// points that have no representative (about a half of them),
+// so keys to be encoded are generated until one has it
+//
+// The point has to be random among all the points of the curve,
+// not only the ones of order n. Public keys of `XDH.GenerateKey`
+// are k*G, and their representatives are easy to tell from random
+// strings: they always decode to points of order n, while only
+// 1/h of random strings do. Use `XDH.GenerateElligator2Key` for
+// keys that are meant to be hidden
+func (c *MontgomeryCurve) Elligator2Encode(P *ECPoint, rand io.Reader) ([]byte, error) {
+	u := c.Elligator2Inverse(P, c.elligator2Z())
+	if len(u) == 0 {
+		return nil, errors.New("point has no Elligator 2 representative")
+	}
+	i, err := randIndex(rand, len(u))
+	if err != nil {
+		return nil, err
+	}
+	// little-endian, same as the keys of X25519 and X448
+	b, err := encodeUniform(u[i], c.P, rand)
+	if err != nil {
+		return nil, err
+	}
+	reverse(b)
+	return b, nil
+}
+
+// Decodes Elligator 2 representative back to the point.
+// Any string of `Elligator2Len` bytes is a valid one
+func (c *MontgomeryCurve) Elligator2Decode(b []byte) (*ECPoint, error) {
+	if len(b) != c.Elligator2Len() {
+		return nil, errors.New("invalid representative length")
+	}
+	u := leInt(b)
+	return c.MapToCurveElligator2(u.Mod(u, c.P), c.elligator2Z())
+}
+
+// Length of Elligator 2 representatives
+func (c *MontgomeryCurve) Elligator2Len() int {
+	return uniformLen(c.P)
+}
+
+// Generates key pair whose public key has an Elligator 2
+// representative and returns it too. The public key is
+// k*G + T for random T of order dividing h (the "dirty" keys of
+// Elligator), so it is random among all the points of the curve
+// and so is the representative, see `Elligator2Encode`
+//
+// Clamped scalars are multiples of h, so T does not change the
+// shared secrets, `ScalarMult` with the key works as usual
+func (x *XDH) GenerateElligator2Key(rand io.Reader) ([]byte, []byte, []byte, error) {
+	c := x.Curve
+	W, err := c.Weierstrass()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for {
+		k := make([]byte, x.size)
+		if _, err := io.ReadFull(rand, k); err != nil {
+			return nil, nil, nil, errors.New("could not generate rand value")
+		}
+		// n*R is uniform among the points of order dividing h
+		// when R is uniform among all of them, so is the sign of y
+		R, err := W.randAffinePoint(rand)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		neg, err := randIndex(rand, 2)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if neg == 1 {
+			R = W.ECPNeg(R)
+		}
+		P := W.ECPAdd(W.ECPScalarBaseMul(x.clamp(k)), W.ECPScalarMul(R, W.N))
+		if P.IsInfinity() {
+			continue
+		}
+		M := c.FromWeierstrass(P)
+		b, err := c.Elligator2Encode(M, rand)
+		if err != nil {
+			// no representative, next key
+			continue
+		}
+		return k, leBytes(M.X, x.size), b, nil
+	}
+}
+
+// Returns all u with `MapToCurveSSWU(u, z)` = P
+func (c *Curve) SSWUInverse(P *ECPoint, z *big.Int) []*big.Int {
+	if P.IsInfinity() {
+		return nil
+	}
+	p := c.P
+	A := c.ECPAffine(P)
+	x := A.X
+	zInv := new(big.Int).ModInverse(z, p)
+	// B + A*x
+	bax := new(big.Int).Mod(new(big.Int).Add(c.B, new(big.Int).Mul(c.A, x)), p)
+
+	// With w = Z*u^(2), x1 = (-B/A)*(1 + 1/(w^(2) + w)) and
+	// x2 = w*x1. x = x1 gives w^(2) + w + B/(B + A*x) = 0, x = x2
+	// gives B*w^(2) + (B + A*x)*w + (B + A*x) = 0. 0 and -1/Z are
+	// the exceptional u^(2) with x1 = B/(Z*A)
+	var w []*big.Int
+	if bax.Sign() != 0 {
+		w = append(w, quadRoots(big.NewInt(1), big.NewInt(1),
+			new(big.Int).Mul(c.B, new(big.Int).ModInverse(bax, p)), p)...)
+	}
+	w = append(w, quadRoots(c.B, bax, bax, p)...)
+	t := []*big.Int{big.NewInt(0), new(big.Int).Neg(zInv)}
+	for _, wi := range w {
+		t = append(t, new(big.Int).Mul(wi, zInv))
+	}
+
+	return preimages(sqrtCandidates(t, p), func(u *big.Int) bool {
+		Q, err := c.MapToCurveSSWU(u, z)
+		return err == nil && c.ECPEqual(Q, A)
+	})
+}
+
+// Returns all u with `MapToCurveSVDW(u, z)` = P
+func (c *Curve) SVDWInverse(P *ECPoint, z *big.Int) []*big.Int {
+	if P.IsInfinity() {
+		return nil
+	}
+	p := c.P
+	A := c.ECPAffine(P)
+	x := A.X
+	c1, c2, c3, c4, err := c.svdwConstants(z)
+	if err != nil {
+		return nil
+	}
+
+	// x1 and x2 are c2 -+ c3*u/(1 + c1*u^(2)), so with d = c2 - x
+	// or x - c2, c1*d*u^(2) - c3*u + d = 0
+	var u []*big.Int
+	for _, d := range []*big.Int{new(big.Int).Sub(c2, x), new(big.Int).Sub(x, c2)} {
+		if d.Mod(d, p).Sign() == 0 {
+			u = append(u, big.NewInt(0))
+			continue
+		}
+		u = append(u, quadRoots(new(big.Int).Mul(c1, d), new(big.Int).Neg(c3), d, p)...)
+	}
+	// x3 = Z + c4*r^(2), r = (1 + c1*u^(2))/(1 - c1*u^(2)), so
+	// u^(2) = (r - 1)/(c1*(r + 1)). +-1/c1 are the exceptional
+	// u^(2) making tv3 zero
+	c1Inv := new(big.Int).ModInverse(c1, p)
+	t := []*big.Int{c1Inv, new(big.Int).Neg(c1Inv)}
+	rr := new(big.Int).Mul(new(big.Int).Sub(x, z), new(big.Int).ModInverse(c4, p))
+	if r := new(big.Int).ModSqrt(rr.Mod(rr, p), p); r != nil {
+		for _, r := range []*big.Int{r, new(big.Int).Neg(r)} {
+			rp1 := new(big.Int).Add(r, big.NewInt(1))
+			if rp1.Mod(rp1, p).Sign() == 0 {
+				continue
+			}
+			tt := new(big.Int).Mul(new(big.Int).Sub(r, big.NewInt(1)), c1Inv)
+			t = append(t, tt.Mul(tt, new(big.Int).ModInverse(rp1, p)))
+		}
+	}
+	u = append(u, sqrtCandidates(t, p)...)
+
+	return preimages(u, func(u *big.Int) bool {
+		Q, err := c.MapToCurveSVDW(u, z)
+		return err == nil && c.ECPEqual(Q, A)
+	})
+}
+
+// Returns all u with `MapToCurve(u)` = P, P on `Curve`
+func (s *HashToCurveSuite) Preimages(P *ECPoint) []*big.Int {
+	if P.IsInfinity() {
+		return nil
+	}
+	switch {
+	case s.Montgomery != nil:
+		return s.Montgomery.Elligator2Inverse(s.Montgomery.FromWeierstrass(P), s.Z)
+	case s.iso != nil:
+		var u []*big.Int
+		for _, R := range s.isoInv(s.Curve.ECPAffine(P)) {
+			u = append(u, s.iso.SSWUInverse(R, s.Z)...)
+		}
+		return u
+	case s.svdw:
+		return s.Curve.SVDWInverse(P, s.Z)
+	}
+	return s.Curve.SSWUInverse(P, s.Z)
+}
+
+// Upper bound on the number of preimages of a point
+func (s *HashToCurveSuite) maxPreimages() int {
+	switch {
+	case s.Montgomery != nil:
+		return 2
+	case s.iso != nil:
+		// 3-isogeny, up to 3 points on E'
+		return 12
+	case s.svdw:
+		return 6
+	}
+	return 4
+}
+
+// Encodes point P of `Curve` with Elligator Squared as a
+// string of 2*`ElligatorSquaredLen` bytes, which is close to
+// uniformly random if P is uniformly random. Unlike Elligator 2,
+// every point can be encoded
+func (s *HashToCurveSuite) ElligatorSquaredEncode(P *ECPoint, rand io.Reader) ([]byte, error) {
+	p := s.Curve.P
+	d := s.maxPreimages()
+	for {
+		u, err := randInt(rand, p)
+		if err != nil {
+			return nil, err
+		}
+		Fu, err := s.MapToCurve(u)
+		if err != nil {
+			return nil, err
+		}
+		v := s.Preimages(s.Curve.ECPSub(P, Fu))
+		// v[j] is taken with probability 1/d, so that points
+		// with many preimages are not preferred
+		j, err := randIndex(rand, d)
+		if err != nil {
+			return nil, err
+		}
+		if j >= len(v) {
+			continue
+		}
+		a, err := encodeUniform(u, p, rand)
+		if err != nil {
+			return nil, err
+		}
+		b, err := encodeUniform(v[j], p, rand)
+		if err != nil {
+			return nil, err
+		}
+		return append(a, b...), nil
+	}
+}
+
+// Decodes the output of `ElligatorSquaredEncode`, f(u) + f(v).
+// Any string of the right length is valid
+func (s *HashToCurveSuite) ElligatorSquaredDecode(b []byte) (*ECPoint, error) {
+	p := s.Curve.P
+	size := s.ElligatorSquaredLen()
+	if len(b) != 2*size {
+		return nil, errors.New("invalid encoding length")
+	}
+	u := new(big.Int).SetBytes(b[:size])
+	v := new(big.Int).SetBytes(b[size:])
+	Fu, err := s.MapToCurve(u.Mod(u, p))
+	if err != nil {
+		return nil, err
+	}
+	Fv, err := s.MapToCurve(v.Mod(v, p))
+	if err != nil {
+		return nil, err
+	}
+	return s.Curve.ECPAffine(s.Curve.ECPAdd(Fu, Fv)), nil
+}
+
+// Length of each of the two halves of Elligator Squared encoding
+func (s *HashToCurveSuite) ElligatorSquaredLen() int {
+	return uniformLen(s.Curve.P)
+}
+
+// Points (x', y') of E' with `secp256k1IsoMap` = P: x' are the
+// roots of x_num(x') - x*x_den(x'), a cubic
+func secp256k1IsoPreimages(P *ECPoint) []*ECPoint {
+	p := secp256k1Iso().P
+	k := secp256k1IsoK
+	f := make([]*big.Int, 4)
+	for i := range f {
+		f[i] = new(big.Int).Set(k[0][i])
+		if i < len(k[1]) {
+			f[i].Sub(f[i], new(big.Int).Mul(P.X, k[1][i]))
+		}
+	}
+	var out []*ECPoint
+	for _, x := range polyRoots(f, p) {
+		yNum := polyEval(k[2], x, p)
+		if yNum.Sign() == 0 {
+			continue
+		}
+		y := new(big.Int).Mul(new(big.Int).Mul(P.Y, polyEval(k[3], x, p)), new(big.Int).ModInverse(yNum, p))
+		R := new(ECPoint)
+		R.SetCoords(x, y.Mod(y, p), big.NewInt(1))
+		out = append(out, R)
+	}
+	return out
+}
+
+// Returns roots of a*x^(2) + b*x + c in F_p, a != 0
+func quadRoots(a, b, c, p *big.Int) []*big.Int {
+	if new(big.Int).Mod(a, p).Sign() == 0 {
+		return nil
+	}
+	// (-b +- sqrt(b^(2) - 4*a*c)) / (2*a)
+	disc := new(big.Int).Sub(new(big.Int).Mul(b, b), new(big.Int).Lsh(new(big.Int).Mul(a, c), 2))
+	s := new(big.Int).ModSqrt(disc.Mod(disc, p), p)
+	if s == nil {
+		return nil
+	}
+	inv := new(big.Int).ModInverse(new(big.Int).Lsh(a, 1), p)
+	r1 := new(big.Int).Mul(new(big.Int).Sub(s, b), inv)
+	r2 := new(big.Int).Mul(new(big.Int).Neg(new(big.Int).Add(s, b)), inv)
+	return []*big.Int{r1.Mod(r1, p), r2.Mod(r2, p)}
+}
+
+// Returns square roots (both signs) of those of t that are squares
+func sqrtCandidates(t []*big.Int, p *big.Int) []*big.Int {
+	var out []*big.Int
+	for _, tt := range t {
+		r := new(big.Int).ModSqrt(new(big.Int).Mod(tt, p), p)
+		if r != nil {
+			out = append(out, r, new(big.Int).Mod(new(big.Int).Neg(r), p))
+		}
+	}
+	return out
+}
+
+// Returns distinct candidates u for which `ok(u)` holds. Inverse
+// maps produce candidates from the equations of the map and
+// check them with the map itself, which handles all the sign
+// choices and exceptional cases
+func preimages(candidates []*big.Int, ok func(*big.Int) bool) []*big.Int {
+	var out []*big.Int
+next:
+	for _, u := range candidates {
+		for _, v := range out {
+			if u.Cmp(v) == 0 {
+				continue next
+			}
+		}
+		if ok(u) {
+			out = append(out, u)
+		}
+	}
+	return out
+}
+
+// Length of uniform encoding of elements of F_p: the smallest
+// number of bytes such that `encodeUniform` output is within
+// statistical distance 2^(-128) from uniform. It is the byte
+// length of p when p is close to a power of 2 (curve25519,
+// curve448, secp256k1), and 16 bytes longer in general (P-256)
+func uniformLen(p *big.Int) int {
+	for size := (p.BitLen() + 7) / 8; ; size++ {
+		bound := new(big.Int).Lsh(big.NewInt(1), uint(8*size))
+		r := new(big.Int).Mod(bound, p)
+		if d := new(big.Int).Sub(p, r); d.Cmp(r) < 0 {
+			r = d
+		}
+		// distance is below 2*r/bound
+		if r.Lsh(r, 129).Cmp(bound) <= 0 {
+			return size
+		}
+	}
+}
+
+// Encodes u in [0, p) as big-endian u + k*p of `uniformLen(p)`
+// bytes, with random k such that the result fits. If u is
+// uniform, the result is (close to) uniform among all strings
+func encodeUniform(u, p *big.Int, rand io.Reader) ([]byte, error) {
+	size := uniformLen(p)
+	bound := new(big.Int).Lsh(big.NewInt(1), uint(8*size))
+	// count of k with u + k*p < bound
+	count := new(big.Int).Sub(bound, big.NewInt(1))
+	count.Sub(count, u).Div(count, p).Add(count, big.NewInt(1))
+	k, err := randInt(rand, count)
+	if err != nil {
+		return nil, err
+	}
+	w := new(big.Int).Add(u, k.Mul(k, p))
+	return w.FillBytes(make([]byte, size)), nil
+}
+
+// Uniform integer in [0, max)
+func randInt(rand io.Reader, max *big.Int) (*big.Int, error) {
+	k, err := crand.Int(rand, max)
+	if err != nil {
+		return nil, errors.New("could not generate rand value")
+	}
+	return k, nil
+}
+
+// Uniform index in [0, n)
+func randIndex(rand io.Reader, n int) (int, error) {
+	i, err := randInt(rand, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(i.Int64()), nil
+}
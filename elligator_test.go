@@ -0,0 +1,246 @@
+package ECwrap
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"math"
+	"math/big"
+	"testing"
+)
+
+// Uniformly random point of the Montgomery curve: random u
+// until u^(3) + A*u^(2) + u is a square, random sign of v
+func randMontgomeryPoint(t *testing.T, c *MontgomeryCurve) *ECPoint {
+	for {
+		u, _ := rand.Int(rand.Reader, c.P)
+		rhs := new(big.Int).Mul(new(big.Int).Add(new(big.Int).Mul(new(big.Int).Add(u, c.A), u), big.NewInt(1)), u)
+		rhs.Mul(rhs, new(big.Int).ModInverse(c.B, c.P))
+		v := new(big.Int).ModSqrt(rhs.Mod(rhs, c.P), c.P)
+		if v == nil {
+			continue
+		}
+		if b, _ := rand.Int(rand.Reader, big.NewInt(2)); b.Sign() == 1 {
+			v.Sub(c.P, v).Mod(v, c.P)
+		}
+		P := new(ECPoint)
+		P.SetCoords(u, v, big.NewInt(1))
+		return P
+	}
+}
+
+// Checks that every bit of the samples is set in about half of
+// them, the count of ones is within 5.5 standard deviations
+// of n/2. Returns false if some bit is off
+func uniformBits(samples [][]byte) bool {
+	n := float64(len(samples))
+	for i := 0; i < 8*len(samples[0]); i++ {
+		ones := 0
+		for _, s := range samples {
+			ones += int(s[i/8]>>(i%8)) & 1
+		}
+		if math.Abs(float64(ones)-n/2) > 5.5*math.Sqrt(n)/2 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestElligator2Inverse(t *testing.T) {
+	for _, c := range []*MontgomeryCurve{Curve25519(), Curve448()} {
+		z := c.elligator2Z()
+		for i := 0; i < 16; i++ {
+			u, _ := rand.Int(rand.Reader, c.P)
+			P, err := c.MapToCurveElligator2(u, z)
+			if err != nil || !c.IsOnCurve(P.X, P.Y) {
+				t.Fatalf(`%s: MapToCurveElligator2() = %v, err = %v`, c.Name, P, err)
+			}
+			inv := c.Elligator2Inverse(P, z)
+			if len(inv) != 2 || (inv[0].Cmp(u) != 0 && inv[1].Cmp(u) != 0) {
+				t.Fatalf(`%s: Elligator2Inverse() = %v, expected u = %v and -u`, c.Name, inv, u)
+			}
+
+			b, err := c.Elligator2Encode(P, rand.Reader)
+			if err != nil || len(b) != c.Elligator2Len() {
+				t.Fatalf(`%s: Elligator2Encode() = %x, err = %v`, c.Name, b, err)
+			}
+			Q, err := c.Elligator2Decode(b)
+			if err != nil || Q.X.Cmp(P.X) != 0 || Q.Y.Cmp(P.Y) != 0 {
+				t.Fatalf(`%s: Elligator2Decode() = %v, expected = %v, err = %v`, c.Name, Q, P, err)
+			}
+		}
+	}
+}
+
+func TestElligator2Uniformity(t *testing.T) {
+	c := Curve25519()
+	var encoded, plain [][]byte
+	for len(encoded) < 2000 {
+		P := randMontgomeryPoint(t, c)
+		b, err := c.Elligator2Encode(P, rand.Reader)
+		if err != nil {
+			// no representative, about half of the points
+			continue
+		}
+		encoded = append(encoded, b)
+		plain = append(plain, leBytes(P.X, 32))
+	}
+	if !uniformBits(encoded) {
+		t.Fatalf(`Elligator 2 representatives are not uniform`)
+	}
+	// the top bit of u is always 0, the test has to see it
+	if uniformBits(plain) {
+		t.Fatalf(`u coordinates are reported as uniform`)
+	}
+}
+
+// Representatives of keys of order n are distinguishable: they
+// decode to points of order n, while the cosets of the subgroup
+// are equally likely for random strings. Keys of
+// `GenerateElligator2Key` must fall in all h of them
+func TestElligator2Keys(t *testing.T) {
+	for _, x := range []*XDH{X25519(), X448()} {
+		c := x.Curve
+		W, err := c.Weierstrass()
+		if err != nil {
+			t.Fatal(err)
+		}
+		coset := func(b []byte) string {
+			P, err := c.Elligator2Decode(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			Q := W.ECPAffine(W.ECPScalarMul(c.ToWeierstrass(P), c.N))
+			if Q.IsInfinity() {
+				return "O"
+			}
+			return Q.X.String() + " " + Q.Y.String()
+		}
+
+		for i := 0; i < 16; {
+			priv, pub, err := x.GenerateKey(rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			P := c.FromWeierstrass(W.ECPScalarBaseMul(x.clamp(priv)))
+			if !bytes.Equal(leBytes(P.X, x.Size()), pub) {
+				t.Fatalf(`%s: u(k*G) = %x, expected = %x`, x.Name, leBytes(P.X, x.Size()), pub)
+			}
+			b, err := c.Elligator2Encode(P, rand.Reader)
+			if err != nil {
+				continue
+			}
+			if coset(b) != "O" {
+				t.Fatalf(`%s: key of order n decodes out of the subgroup`, x.Name)
+			}
+			i++
+		}
+
+		cosets := make(map[string]bool)
+		// 24*h keys miss one of the cosets with probability < 2^(-30)
+		for i := int64(0); i < 24*c.H.Int64(); i++ {
+			priv, pub, b, err := x.GenerateElligator2Key(rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			P, _ := c.Elligator2Decode(b)
+			if !bytes.Equal(leBytes(P.X, x.Size()), pub) {
+				t.Fatalf(`%s: representative decodes to %x, expected = %x`, x.Name, leBytes(P.X, x.Size()), pub)
+			}
+			cosets[coset(b)] = true
+
+			if i >= 8 {
+				continue
+			}
+			// shared secret is the same as with the clean key
+			peer, peerPub, _ := x.GenerateKey(rand.Reader)
+			clean, _ := x.ScalarBaseMult(priv)
+			s1, err1 := x.ScalarMult(peer, pub)
+			s2, err2 := x.ScalarMult(peer, clean)
+			s3, err3 := x.ScalarMult(priv, peerPub)
+			if err1 != nil || err2 != nil || err3 != nil || !bytes.Equal(s1, s2) || !bytes.Equal(s1, s3) {
+				t.Fatalf(`%s: shared secrets differ, err = %v, %v, %v`, x.Name, err1, err2, err3)
+			}
+		}
+		if int64(len(cosets)) != c.H.Int64() {
+			t.Fatalf(`%s: keys fall in %d cosets, expected = %v`, x.Name, len(cosets), c.H)
+		}
+	}
+}
+
+func TestElligatorSquared(t *testing.T) {
+	var suites []*HashToCurveSuite
+	for _, id := range []string{
+		"P256_XMD:SHA-256_SSWU_RO_",
+		"secp256k1_XMD:SHA-256_SSWU_RO_",
+		"curve25519_XMD:SHA-512_ELL2_RO_",
+	} {
+		s, err := NewHashToCurveSuite(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		suites = append(suites, s)
+	}
+	for _, c := range []*Curve{Secp256k1(), toyCurve(t)} {
+		s, err := NewSVDWSuite("svdw "+c.Name, c, XMD(sha256.New), 128, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		suites = append(suites, s)
+	}
+
+	for _, s := range suites {
+		c := s.Curve
+		for i := 0; i < 8; i++ {
+			u, _ := rand.Int(rand.Reader, c.P)
+			P, err := s.MapToCurve(u)
+			if err != nil {
+				t.Fatal(err)
+			}
+			found := false
+			for _, v := range s.Preimages(P) {
+				found = found || v.Cmp(u) == 0
+			}
+			if !found {
+				t.Fatalf(`%s: Preimages(MapToCurve(%v)) has no %v`, s.ID, u, u)
+			}
+
+			k, _ := rand.Int(rand.Reader, c.N)
+			P = c.ECPScalarBaseMul(k)
+			b, err := s.ElligatorSquaredEncode(P, rand.Reader)
+			if err != nil || len(b) != 2*s.ElligatorSquaredLen() {
+				t.Fatalf(`%s: ElligatorSquaredEncode() = %x, err = %v`, s.ID, b, err)
+			}
+			Q, err := s.ElligatorSquaredDecode(b)
+			if err != nil || !c.ECPEqual(P, Q) {
+				t.Fatalf(`%s: ElligatorSquaredDecode() = %v, expected = %v, err = %v`, s.ID, Q, P, err)
+			}
+		}
+	}
+}
+
+func TestElligatorSquaredUniformity(t *testing.T) {
+	s, _ := NewHashToCurveSuite("P256_XMD:SHA-256_SSWU_RO_")
+	c := s.Curve
+	n := 1000
+	if testing.Short() {
+		n = 200
+	}
+	var encoded, plain [][]byte
+	for i := 0; i < n; i++ {
+		k, _ := rand.Int(rand.Reader, c.N)
+		P := c.ECPScalarBaseMul(k)
+		b, err := s.ElligatorSquaredEncode(P, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded = append(encoded, b)
+		plain = append(plain, c.ECPMarshal(P))
+	}
+	if !uniformBits(encoded) {
+		t.Fatalf(`Elligator Squared encodings are not uniform`)
+	}
+	if uniformBits(plain) {
+		t.Fatalf(`SEC 1 encodings are reported as uniform`)
+	}
+}
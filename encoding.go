@@ -0,0 +1,99 @@
+package ECwrap
+
+import (
+	"errors"
+	"math/big"
+)
+
+// SEC 1 (section 2.3.3) point encodings
+// https://www.secg.org/sec1-v2.pdf
+
+// Returns uncompressed encoding 0x04 || X || Y of P.
+// Point at infinity is encoded as the single 0x00 byte
+func (c *Curve) ECPMarshal(P *ECPoint) []byte {
+	if P.IsInfinity() {
+		return []byte{0x00}
+	}
+	A := c.ECPAffine(P)
+	size := c.byteLen()
+	out := make([]byte, 1+2*size)
+	out[0] = 0x04
+	A.X.FillBytes(out[1 : 1+size])
+	A.Y.FillBytes(out[1+size:])
+	return out
+}
+
+// Returns compressed encoding 0x02 || X (even Y)
+// or 0x03 || X (odd Y) of P
+func (c *Curve) ECPMarshalCompressed(P *ECPoint) []byte {
+	if P.IsInfinity() {
+		return []byte{0x00}
+	}
+	A := c.ECPAffine(P)
+	size := c.byteLen()
+	out := make([]byte, 1+size)
+	out[0] = 0x02 | byte(A.Y.Bit(0))
+	A.X.FillBytes(out[1:])
+	return out
+}
+
+// Parses compressed or uncompressed point, checking
+// that it lies on the curve. Point at infinity (0x00)
+// is rejected, as it is never a valid public key
+func (c *Curve) ECPUnmarshal(b []byte) (*ECPoint, error) {
+	size := c.byteLen()
+	if len(b) == 0 {
+		return nil, errors.New("empty point encoding")
+	}
+	switch b[0] {
+	case 0x04:
+		if len(b) != 1+2*size {
+			return nil, errors.New("invalid uncompressed point length")
+		}
+		P := new(ECPoint)
+		P.SetCoords(
+			new(big.Int).SetBytes(b[1:1+size]),
+			new(big.Int).SetBytes(b[1+size:]),
+			big.NewInt(1),
+		)
+		if !c.IsOnCurve(P.X, P.Y) {
+			return nil, errors.New("point is not on curve")
+		}
+		return P, nil
+	case 0x02, 0x03:
+		if len(b) != 1+size {
+			return nil, errors.New("invalid compressed point length")
+		}
+		return c.ECPFromX(new(big.Int).SetBytes(b[1:]), b[0] == 0x03)
+	case 0x00:
+		return nil, errors.New("point at infinity")
+	}
+	return nil, errors.New("unknown point encoding")
+}
+
+// Returns the point with given x coordinate and Y parity
+// (point decompression). Fails if x is not in [0, p) or
+// x^(3) + a*x + b is not a square mod p
+func (c *Curve) ECPFromX(x *big.Int, odd bool) (*ECPoint, error) {
+	if x.Sign() < 0 || x.Cmp(c.P) >= 0 {
+		return nil, errors.New("x is out of range [0, p)")
+	}
+	y := new(big.Int).ModSqrt(c.Polynomial(x), c.P)
+	if y == nil {
+		return nil, errors.New("no point with such x on curve")
+	}
+	if y.Sign() == 0 && odd {
+		return nil, errors.New("no point with such x and odd y")
+	}
+	if (y.Bit(0) == 1) != odd {
+		y.Sub(c.P, y).Mod(y, c.P)
+	}
+	P := new(ECPoint)
+	P.SetCoords(x, y, big.NewInt(1))
+	return P, nil
+}
+
+// Byte length of the field elements
+func (c *Curve) byteLen() int {
+	return (c.P.BitLen() + 7) / 8
+}
@@ -0,0 +1,65 @@
+package ECwrap
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+func TestECPMarshal(t *testing.T) {
+	testAllCurves(t, func(t *testing.T, c *Curve) {
+		k, _ := rand.Int(rand.Reader, c.N)
+		P := c.ECPScalarBaseMul(k)
+		A := c.ECPAffine(P)
+
+		b := c.ECPMarshal(P)
+		if !bytes.Equal(b, elliptic.Marshal(c, A.X, A.Y)) {
+			t.Fatalf(`ECPMarshal() does not match elliptic.Marshal()`)
+		}
+		bc := c.ECPMarshalCompressed(P)
+		if !bytes.Equal(bc, elliptic.MarshalCompressed(c, A.X, A.Y)) {
+			t.Fatalf(`ECPMarshalCompressed() does not match elliptic.MarshalCompressed()`)
+		}
+
+		for _, enc := range [][]byte{b, bc} {
+			Q, err := c.ECPUnmarshal(enc)
+			if err != nil {
+				t.Fatalf(`ECPUnmarshal() error = %v`, err)
+			}
+			if !c.ECPEqual(P, Q) {
+				t.Fatalf(`ECPUnmarshal() did not round trip`)
+			}
+		}
+
+		if _, err := c.ECPUnmarshal([]byte{0x00}); err == nil {
+			t.Fatalf(`ECPUnmarshal(∞) error = nil`)
+		}
+		b[len(b)-1] ^= 1
+		if _, err := c.ECPUnmarshal(b); err == nil {
+			t.Fatalf(`ECPUnmarshal(off curve) error = nil`)
+		}
+	})
+}
+
+func TestECPMultiScalarMul(t *testing.T) {
+	c := Secp256k1()
+	var points []*ECPoint
+	var scalars []*big.Int
+	want := PointAtInfinity()
+	for i := 0; i < 6; i++ {
+		k, _ := rand.Int(rand.Reader, c.N)
+		s, _ := rand.Int(rand.Reader, c.N)
+		if i%2 == 1 {
+			s.Neg(s)
+		}
+		P := c.ECPScalarBaseMul(k)
+		points = append(points, P)
+		scalars = append(scalars, s)
+		want = c.ECPAdd(want, c.ECPScalarMul(P, s))
+	}
+	if !c.ECPEqual(c.ECPMultiScalarMul(points, scalars), want) {
+		t.Fatalf(`ECPMultiScalarMul() does not match sum of ECPScalarMul()`)
+	}
+}
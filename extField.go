@@ -0,0 +1,270 @@
+package ECwrap
+
+import (
+	"errors"
+	"io"
+	"math/big"
+)
+
+// Extension fields F_(p^k) = F_p[x]/(m) for irreducible m of
+// degree k. Elements are `Poly`s of degree below k, which keeps
+// everything generic and slow: fine for pairings on toy curves
+// and for watching the MOV attack, not for BN or BLS curves
+
+// The largest degree `NewExtField` works with
+const extMaxDegree = 32
+
+// Field F_(p^k). Elements are polynomials reduced mod Modulus,
+// the methods never modify their arguments
+type ExtField struct {
+	P       *big.Int
+	K       int
+	Modulus *Poly
+
+	pm *polyModulus
+	// element which is not a square, nil for p = 2
+	nonSquare *Poly
+}
+
+// Returns F_(p^k), p prime, modulus is the first monic
+// irreducible polynomial of degree k with coefficients (from
+// the constant term up) counting in base min(p, 256), which
+// is x for k = 1
+func NewExtField(p *big.Int, k int) (*ExtField, error) {
+	if k < 1 || k > extMaxDegree {
+		return nil, errors.New("degree must be from 1 to 32")
+	}
+	if !p.ProbablyPrime(20) {
+		return nil, errors.New("p must be prime")
+	}
+	base := big.NewInt(256)
+	if p.Cmp(base) < 0 {
+		base = p
+	}
+	// about one of k polynomials is irreducible
+	for i := int64(0); i < 1<<16; i++ {
+		m := make([]*big.Int, k+1)
+		digits := big.NewInt(i)
+		for j := 0; j < k; j++ {
+			m[j] = new(big.Int)
+			digits.QuoRem(digits, base, m[j])
+		}
+		m[k] = big.NewInt(1)
+		if polyIrreducible(m, p) {
+			return newExtField(NewPoly(p, m...)), nil
+		}
+	}
+	return nil, errors.New("no irreducible polynomial found")
+}
+
+// Returns F_p[x]/(m) for irreducible m of degree 1 to 32,
+// m is made monic
+func NewExtFieldModulus(m *Poly) (*ExtField, error) {
+	if m.Degree() < 1 || m.Degree() > extMaxDegree {
+		return nil, errors.New("degree must be from 1 to 32")
+	}
+	if !m.p.ProbablyPrime(20) {
+		return nil, errors.New("p must be prime")
+	}
+	m = m.Monic()
+	if !polyIrreducible(m.c, m.p) {
+		return nil, errors.New("modulus is not irreducible")
+	}
+	return newExtField(m), nil
+}
+
+func newExtField(m *Poly) *ExtField {
+	F := &ExtField{P: m.p, K: m.Degree(), Modulus: m, pm: newPolyModulus(m.c, m.p)}
+	if m.p.Bit(0) == 1 {
+		// a^((q - 1)/2) is -1 for half of the elements
+		e := new(big.Int).Rsh(F.Order(), 1)
+		minusOne := F.FromInt(big.NewInt(-1))
+		for i := int64(2); ; i++ {
+			z := F.elemFromIndex(i)
+			if F.Equal(F.Exp(z, e), minusOne) {
+				F.nonSquare = z
+				break
+			}
+		}
+	}
+	return F
+}
+
+// Reports whether monic m is irreducible over F_p (Rabin's test):
+// x^(p^k) = x mod m for k = deg m, and x^(p^(k/q)) - x is coprime
+// to m for every prime q dividing k
+func polyIrreducible(m []*big.Int, p *big.Int) bool {
+	k := len(m) - 1
+	pm := newPolyModulus(m, p)
+	// x^(p^i) mod m
+	pows := [][]*big.Int{pm.reduce([]*big.Int{big.NewInt(0), big.NewInt(1)})}
+	for i := 1; i <= k; i++ {
+		pows = append(pows, pm.pow(pows[i-1], p))
+	}
+	if len(polySub(pows[k], pows[0], p)) != 0 {
+		return false
+	}
+	for _, pp := range factorize(big.NewInt(int64(k))) {
+		g := polyGcd(polySub(pows[k/int(pp.p.Int64())], pows[0], p), m, p)
+		if len(g) != 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns element whose coefficients are the digits of i in
+// base min(p, 256)
+func (F *ExtField) elemFromIndex(i int64) *Poly {
+	base := big.NewInt(256)
+	if F.P.Cmp(base) < 0 {
+		base = F.P
+	}
+	c := make([]*big.Int, F.K)
+	digits := big.NewInt(i)
+	for j := range c {
+		c[j] = new(big.Int)
+		digits.QuoRem(digits, base, c[j])
+	}
+	return F.Elem(c...)
+}
+
+// Returns q = p^(k), the number of elements
+func (F *ExtField) Order() *big.Int {
+	return new(big.Int).Exp(F.P, big.NewInt(int64(F.K)), nil)
+}
+
+// Returns element with given coefficients, from the constant
+// term up, reduced mod the modulus
+func (F *ExtField) Elem(coeffs ...*big.Int) *Poly {
+	return NewPoly(F.P, coeffs...).Mod(F.Modulus)
+}
+
+// Returns x mod p as an element of the field
+func (F *ExtField) FromInt(x *big.Int) *Poly {
+	return F.Elem(x)
+}
+
+func (F *ExtField) Zero() *Poly {
+	return F.Elem()
+}
+
+func (F *ExtField) One() *Poly {
+	return F.Elem(big.NewInt(1))
+}
+
+// Returns uniformly random element
+func (F *ExtField) Rand(rand io.Reader) (*Poly, error) {
+	c := make([]*big.Int, F.K)
+	for i := range c {
+		r, err := randInt(rand, F.P)
+		if err != nil {
+			return nil, err
+		}
+		c[i] = r
+	}
+	return F.Elem(c...), nil
+}
+
+func (F *ExtField) Add(a, b *Poly) *Poly {
+	return a.Add(b)
+}
+
+func (F *ExtField) Sub(a, b *Poly) *Poly {
+	return a.Sub(b)
+}
+
+func (F *ExtField) Neg(a *Poly) *Poly {
+	return a.Neg()
+}
+
+func (F *ExtField) Mul(a, b *Poly) *Poly {
+	a.check(b)
+	return a.wrap(F.pm.mul(a.c, b.c))
+}
+
+// Returns a^(-1). Panics if a is zero
+func (F *ExtField) Inv(a *Poly) *Poly {
+	if a.IsZero() {
+		panic("ECwrap: inverse of zero")
+	}
+	inv, err := a.InvMod(F.Modulus)
+	if err != nil {
+		// modulus is irreducible
+		panic("ECwrap: " + err.Error())
+	}
+	return inv
+}
+
+// Returns a/b. Panics if b is zero
+func (F *ExtField) Div(a, b *Poly) *Poly {
+	return F.Mul(a, F.Inv(b))
+}
+
+// Returns a^(e), negative e inverts a first
+func (F *ExtField) Exp(a *Poly, e *big.Int) *Poly {
+	if e.Sign() < 0 {
+		return F.Exp(F.Inv(a), new(big.Int).Neg(e))
+	}
+	return a.wrap(F.pm.pow(a.c, e))
+}
+
+// Returns a^(p), the Frobenius automorphism
+func (F *ExtField) Frobenius(a *Poly) *Poly {
+	return F.Exp(a, F.P)
+}
+
+// Reports whether a == b
+func (F *ExtField) Equal(a, b *Poly) bool {
+	return a.Equal(b)
+}
+
+// Reports whether a is a square (zero is)
+func (F *ExtField) IsSquare(a *Poly) bool {
+	if a.IsZero() || F.nonSquare == nil {
+		return true
+	}
+	return F.Equal(F.Exp(a, new(big.Int).Rsh(F.Order(), 1)), F.One())
+}
+
+// Returns square root of a, nil if there is none.
+// Tonelli-Shanks with q - 1 = 2^(s)*t
+func (F *ExtField) Sqrt(a *Poly) *Poly {
+	q := F.Order()
+	if F.nonSquare == nil {
+		// squaring is a bijection in characteristic 2
+		return F.Exp(a, new(big.Int).Rsh(q, 1))
+	}
+	if a.IsZero() {
+		return a
+	}
+	if !F.IsSquare(a) {
+		return nil
+	}
+	t := new(big.Int).Sub(q, big.NewInt(1))
+	s := 0
+	for t.Bit(0) == 0 {
+		t.Rsh(t, 1)
+		s++
+	}
+	// invariant r^(2) = a*b, b of order 2^(m - 1) at most
+	z := F.Exp(F.nonSquare, t)
+	b := F.Exp(a, t)
+	r := F.Exp(a, new(big.Int).Rsh(new(big.Int).Add(t, big.NewInt(1)), 1))
+	one := F.One()
+	for m := s; !F.Equal(b, one); {
+		i, b2 := 0, b
+		for !F.Equal(b2, one) {
+			b2 = F.Mul(b2, b2)
+			i++
+		}
+		for j := 0; j < m-i-1; j++ {
+			z = F.Mul(z, z)
+		}
+		r = F.Mul(r, z)
+		z = F.Mul(z, z)
+		b = F.Mul(b, z)
+		m = i
+	}
+	return r
+}
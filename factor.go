@@ -0,0 +1,137 @@
+package ECwrap
+
+import (
+	"math/big"
+	"sort"
+)
+
+// Integer factorization for group orders of toy curves: trial
+// division by small primes, then Pollard's rho (Brent's variant)
+// on what is left. Fine up to about 2^100 unless the two largest
+// factors are both big
+
+// Prime power p^e
+type primePower struct {
+	p *big.Int
+	e int
+}
+
+// Returns factorization of n > 0 as prime powers in ascending
+// order of primes, empty for n = 1
+func factorize(n *big.Int) []primePower {
+	factors, _ := factorizeLimited(n, 0)
+	return factors
+}
+
+// Same as `factorize`, but gives up on composites Pollard's rho
+// does not split in `steps` iterations (no limit if 0). Their
+// product is returned as the rest, 1 if n is fully factored.
+// Orders of the twists of real curves often have two factors
+// of 100+ bits, which would take forever
+func factorizeLimited(n *big.Int, steps int) ([]primePower, *big.Int) {
+	exps := map[string]*primePower{}
+	rest := big.NewInt(1)
+	var add func(m *big.Int)
+	add = func(m *big.Int) {
+		if m.Cmp(big.NewInt(1)) == 0 {
+			return
+		}
+		if m.ProbablyPrime(20) {
+			if pp, ok := exps[m.String()]; ok {
+				pp.e++
+			} else {
+				exps[m.String()] = &primePower{p: new(big.Int).Set(m), e: 1}
+			}
+			return
+		}
+		d := pollardBrentLimited(m, steps)
+		if d == nil {
+			rest.Mul(rest, m)
+			return
+		}
+		add(d)
+		add(new(big.Int).Quo(m, d))
+	}
+
+	m := new(big.Int).Set(n)
+	r := new(big.Int)
+	for p := int64(2); p < 1000; p++ {
+		d := big.NewInt(p)
+		if !d.ProbablyPrime(1) {
+			continue
+		}
+		for {
+			q, _ := new(big.Int).QuoRem(m, d, r)
+			if r.Sign() != 0 {
+				break
+			}
+			m = q
+			add(d)
+		}
+	}
+	add(m)
+
+	out := make([]primePower, 0, len(exps))
+	for _, pp := range exps {
+		out = append(out, *pp)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].p.Cmp(out[j].p) < 0 })
+	return out, rest
+}
+
+// Returns non-trivial factor of composite n, which has
+// no factors below 1000
+func pollardBrent(n *big.Int) *big.Int {
+	return pollardBrentLimited(n, 0)
+}
+
+// Same as `pollardBrent`, but returns nil after about `steps`
+// iterations of f (no limit if 0)
+func pollardBrentLimited(n *big.Int, steps int) *big.Int {
+	one := big.NewInt(1)
+	// f(x) = x^(2) + c mod n, next c if the cycle closes
+	// without splitting n
+	total := 0
+	for c := int64(1); steps == 0 || total < steps; c++ {
+		f := func(x *big.Int) *big.Int {
+			x.Mul(x, x).Add(x, big.NewInt(c))
+			return x.Mod(x, n)
+		}
+		y, x, ys := big.NewInt(2), new(big.Int), new(big.Int)
+		g, q := big.NewInt(1), big.NewInt(1)
+		diff := new(big.Int)
+		const batch = 128
+		for r := 1; g.Cmp(one) == 0; r *= 2 {
+			x.Set(y)
+			for i := 0; i < r; i++ {
+				f(y)
+			}
+			total += r
+			if steps != 0 && total > steps {
+				return nil
+			}
+			for k := 0; k < r && g.Cmp(one) == 0; k += batch {
+				ys.Set(y)
+				for i := 0; i < min(batch, r-k); i++ {
+					f(y)
+					q.Mul(q, diff.Abs(diff.Sub(x, y))).Mod(q, n)
+				}
+				g.GCD(nil, nil, q, n)
+			}
+		}
+		if g.Cmp(n) == 0 {
+			// the batch overshot, redo it one step at a time
+			for {
+				f(ys)
+				g.GCD(nil, nil, diff.Abs(diff.Sub(x, ys)), n)
+				if g.Cmp(one) != 0 {
+					break
+				}
+			}
+		}
+		if g.Cmp(n) != 0 {
+			return g
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package ECwrap
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestFactorize(t *testing.T) {
+	tests := []struct {
+		n        string
+		expected string
+	}{
+		{"1", ""},
+		{"67100544", "2^7 3^2 7 53 157"},
+		// two 40 bit primes, trial division does not help
+		{"1208925819737774477018543", "1099511627791 1099511627873"},
+		// secp256k1 group order is prime
+		{"115792089237316195423570985008687907852837564279074904382605163141518161494337", "115792089237316195423570985008687907852837564279074904382605163141518161494337"},
+	}
+	for _, test := range tests {
+		n, _ := new(big.Int).SetString(test.n, 10)
+		got := ""
+		for _, pp := range factorize(n) {
+			if got != "" {
+				got += " "
+			}
+			got += pp.p.String()
+			if pp.e > 1 {
+				got += "^" + big.NewInt(int64(pp.e)).String()
+			}
+		}
+		if got != test.expected {
+			t.Errorf(`factorize(%s) = %s, expected = %s`, test.n, got, test.expected)
+		}
+	}
+}
+
+func TestFactorizeLimited(t *testing.T) {
+	// 3 * 1099511627791 * 1099511627873, 100 steps are far too
+	// few to split the product of the 40 bit primes
+	n, _ := new(big.Int).SetString("3626777459213323431055629", 10)
+	factors, rest := factorizeLimited(n, 100)
+	if len(factors) != 1 || factors[0].p.Int64() != 3 || rest.String() != "1208925819737774477018543" {
+		t.Fatalf(`factorizeLimited() = %v, rest = %v`, factors, rest)
+	}
+	if _, rest = factorizeLimited(n, 0); rest.Int64() != 1 {
+		t.Fatalf(`factorizeLimited() without limit left %v`, rest)
+	}
+}
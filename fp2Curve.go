@@ -0,0 +1,139 @@
+package ECwrap
+
+import (
+	"errors"
+	"io"
+	"math/big"
+)
+
+// Curves y^(2) = x^(3) + a*x + b over Fp2 of a `Tower`. G2 of BN
+// and BLS curves lives on such a curve, the sextic twist of the
+// curve over Fp, where b is b/xi (D-type twist, BN254) or b*xi
+// (M-type twist, BLS12-381). Points are affine, an inversion in
+// Fp2 is a single inversion in Fp
+
+// Curve over Fp2
+type Fp2Curve struct {
+	T    *Tower
+	A, B *Fp2
+}
+
+// Affine point of `Fp2Curve`
+type Fp2Point struct {
+	X, Y *Fp2
+	Inf  bool
+}
+
+func Fp2PointAtInfinity() *Fp2Point {
+	return &Fp2Point{Inf: true}
+}
+
+// Returns y^(2) = x^(3) + a*x + b over Fp2, error if it is singular
+func NewFp2Curve(t *Tower, a, b *Fp2) (*Fp2Curve, error) {
+	a, b = t.Fp2(a.A0, a.A1), t.Fp2(b.A0, b.A1)
+	// 4*a^(3) + 27*b^(2)
+	disc := t.Fp2Add(
+		t.Fp2MulScalar(t.Fp2Mul(t.Fp2Square(a), a), big.NewInt(4)),
+		t.Fp2MulScalar(t.Fp2Square(b), big.NewInt(27)))
+	if t.Fp2IsZero(disc) {
+		return nil, errors.New("curve is singular")
+	}
+	return &Fp2Curve{T: t, A: a, B: b}, nil
+}
+
+// Returns x^(3) + a*x + b
+func (c *Fp2Curve) Polynomial(x *Fp2) *Fp2 {
+	t := c.T
+	return t.Fp2Add(t.Fp2Mul(t.Fp2Add(t.Fp2Square(x), c.A), x), c.B)
+}
+
+func (c *Fp2Curve) IsOnCurve(P *Fp2Point) bool {
+	return P.Inf || c.T.Fp2Equal(c.T.Fp2Square(P.Y), c.Polynomial(P.X))
+}
+
+func (c *Fp2Curve) Equal(P, Q *Fp2Point) bool {
+	if P.Inf || Q.Inf {
+		return P.Inf == Q.Inf
+	}
+	return c.T.Fp2Equal(P.X, Q.X) && c.T.Fp2Equal(P.Y, Q.Y)
+}
+
+func (c *Fp2Curve) Neg(P *Fp2Point) *Fp2Point {
+	if P.Inf {
+		return P
+	}
+	return &Fp2Point{X: P.X, Y: c.T.Fp2Neg(P.Y)}
+}
+
+// Returns slope of the line through P and Q (the tangent for
+// P = Q), nil if it is vertical. Neither is the point at infinity
+func (c *Fp2Curve) slope(P, Q *Fp2Point) *Fp2 {
+	t := c.T
+	if !t.Fp2Equal(P.X, Q.X) {
+		return t.Fp2Mul(t.Fp2Sub(Q.Y, P.Y), t.Fp2Inv(t.Fp2Sub(Q.X, P.X)))
+	}
+	if !t.Fp2Equal(P.Y, Q.Y) || t.Fp2IsZero(P.Y) {
+		return nil
+	}
+	// (3*x^(2) + a)/(2*y)
+	num := t.Fp2Add(t.Fp2MulScalar(t.Fp2Square(P.X), big.NewInt(3)), c.A)
+	return t.Fp2Mul(num, t.Fp2Inv(t.Fp2Add(P.Y, P.Y)))
+}
+
+// Returns P + Q given the slope l of the line through them
+func (c *Fp2Curve) addWithSlope(P, Q *Fp2Point, l *Fp2) *Fp2Point {
+	t := c.T
+	x := t.Fp2Sub(t.Fp2Sub(t.Fp2Square(l), P.X), Q.X)
+	y := t.Fp2Sub(t.Fp2Mul(l, t.Fp2Sub(P.X, x)), P.Y)
+	return &Fp2Point{X: x, Y: y}
+}
+
+func (c *Fp2Curve) Add(P, Q *Fp2Point) *Fp2Point {
+	if P.Inf {
+		return Q
+	}
+	if Q.Inf {
+		return P
+	}
+	l := c.slope(P, Q)
+	if l == nil {
+		return Fp2PointAtInfinity()
+	}
+	return c.addWithSlope(P, Q, l)
+}
+
+func (c *Fp2Curve) Double(P *Fp2Point) *Fp2Point {
+	return c.Add(P, P)
+}
+
+func (c *Fp2Curve) Sub(P, Q *Fp2Point) *Fp2Point {
+	return c.Add(P, c.Neg(Q))
+}
+
+// Returns k*P, double-and-add
+func (c *Fp2Curve) ScalarMul(P *Fp2Point, k *big.Int) *Fp2Point {
+	if k.Sign() < 0 {
+		return c.ScalarMul(c.Neg(P), new(big.Int).Neg(k))
+	}
+	R := Fp2PointAtInfinity()
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		R = c.Double(R)
+		if k.Bit(i) == 1 {
+			R = c.Add(R, P)
+		}
+	}
+	return R
+}
+
+// Returns random affine point
+func (c *Fp2Curve) RandPoint(rand io.Reader) (*Fp2Point, error) {
+	for {
+		x, err := c.T.Fp2Rand(rand)
+		if err != nil {
+			return nil, err
+		}
+		if y := c.T.Fp2Sqrt(c.Polynomial(x)); y != nil {
+			return &Fp2Point{X: x, Y: y}, nil
+		}
+	}
+}
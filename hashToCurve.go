@@ -0,0 +1,598 @@
+package ECwrap
+
+import (
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/sha3"
+	"crypto/sha512"
+	"errors"
+	"math/big"
+	"sync"
+)
+
+// Hashing to elliptic curves, RFC 9380
+// https://www.rfc-editor.org/rfc/rfc9380
+//
+// Unlike `RandPoint` (k*G for random k), discrete logarithm
+// of the resulting points is unknown to anybody, so they can
+// serve as nothing-up-my-sleeve generators, password hashes
+// for PAKE, BLS message points and so on
+
+// Simplified Shallue-van de Woestijne-Ulas map (RFC 9380, 6.6.2)
+// from field element `u` to the curve, for curves with a != 0
+// and b != 0. `z` must satisfy the criteria of RFC 9380 (6.6.2),
+// the one found by the algorithm of appendix H.2 is used by
+// `NewSSWUSuite`
+func (c *Curve) MapToCurveSSWU(u, z *big.Int) (*ECPoint, error) {
+	p := c.P
+	if c.A.Sign() == 0 || c.B.Sign() == 0 {
+		return nil, errors.New("SSWU needs a != 0 and b != 0")
+	}
+	// tv1 = 1/(Z^(2)*u^(4) + Z*u^(2)), 0 if not invertible
+	zu2 := new(big.Int).Mul(z, new(big.Int).Mul(u, u))
+	zu2.Mod(zu2, p)
+	tv1 := new(big.Int).Mul(zu2, new(big.Int).Add(zu2, big.NewInt(1)))
+	tv1 = inv0(tv1, p)
+
+	aInv := new(big.Int).ModInverse(c.A, p)
+	var x1 *big.Int
+	if tv1.Sign() == 0 {
+		// B/(Z*A)
+		x1 = new(big.Int).Mul(c.B, new(big.Int).ModInverse(new(big.Int).Mul(z, c.A), p))
+	} else {
+		// (-B/A)*(1 + tv1)
+		x1 = new(big.Int).Mul(new(big.Int).Neg(new(big.Int).Mul(c.B, aInv)), new(big.Int).Add(tv1, big.NewInt(1)))
+	}
+	x1.Mod(x1, p)
+
+	x := x1
+	y := new(big.Int).ModSqrt(c.Polynomial(x1), p)
+	if y == nil {
+		// x2 = Z*u^(2)*x1
+		x = new(big.Int).Mod(new(big.Int).Mul(zu2, x1), p)
+		y = new(big.Int).ModSqrt(c.Polynomial(x), p)
+		if y == nil {
+			return nil, errors.New("Z is not suitable for the curve")
+		}
+	}
+	if sgn0(u, p) != sgn0(y, p) {
+		y.Sub(p, y).Mod(y, p)
+	}
+	P := new(ECPoint)
+	P.SetCoords(x, y, big.NewInt(1))
+	return P, nil
+}
+
+// Elligator 2 map (RFC 9380, 6.7.1) from field element `u` to
+// the curve, `z` must be a non-square in F_p (see `NewElligator2Suite`).
+// Result is Montgomery point (u, v, 1)
+func (c *MontgomeryCurve) MapToCurveElligator2(u, z *big.Int) (*ECPoint, error) {
+	p := c.P
+	kInv := new(big.Int).ModInverse(c.B, p)
+	// J/K, 1/K^(2)
+	jk := new(big.Int).Mod(new(big.Int).Mul(c.A, kInv), p)
+	kk := new(big.Int).Mod(new(big.Int).Mul(kInv, kInv), p)
+	// g(x) = x^(3) + (J/K)*x^(2) + x/K^(2)
+	g := func(x *big.Int) *big.Int {
+		gx := new(big.Int).Mul(new(big.Int).Add(new(big.Int).Mul(new(big.Int).Add(x, jk), x), kk), x)
+		return gx.Mod(gx, p)
+	}
+
+	// x1 = -(J/K) / (1 + Z*u^(2)), -(J/K) if not invertible
+	tv1 := new(big.Int).Add(new(big.Int).Mul(z, new(big.Int).Mul(u, u)), big.NewInt(1))
+	x1 := new(big.Int).Mul(new(big.Int).Neg(jk), inv0(tv1, p))
+	x1.Mod(x1, p)
+	if x1.Sign() == 0 {
+		x1.Sub(p, jk).Mod(x1, p)
+	}
+
+	x := x1
+	y := new(big.Int).ModSqrt(g(x1), p)
+	// sgn0(y) == 1 for x1 and 0 for x2
+	odd := uint(1)
+	if y == nil {
+		// x2 = -x1 - J/K
+		x = new(big.Int).Neg(new(big.Int).Add(x1, jk))
+		x.Mod(x, p)
+		y = new(big.Int).ModSqrt(g(x), p)
+		if y == nil {
+			return nil, errors.New("Z is not suitable for the curve")
+		}
+		odd = 0
+	}
+	if sgn0(y, p) != odd {
+		y.Sub(p, y).Mod(y, p)
+	}
+	// (s, t) = (x*K, y*K)
+	P := new(ECPoint)
+	P.SetCoords(
+		new(big.Int).Mod(new(big.Int).Mul(x, c.B), p),
+		new(big.Int).Mod(new(big.Int).Mul(y, c.B), p),
+		big.NewInt(1),
+	)
+	return P, nil
+}
+
+// Shallue-van de Woestijne map (RFC 9380, 6.6.1) from field
+// element `u` to the curve. Slower than SSWU, but works for any
+// curve, including the ones with a = 0 or b = 0. `z` must satisfy
+// the criteria of RFC 9380 (6.6.1), see `NewSVDWSuite`
+func (c *Curve) MapToCurveSVDW(u, z *big.Int) (*ECPoint, error) {
+	p := c.P
+	c1, c2, c3, c4, err := c.svdwConstants(z)
+	if err != nil {
+		return nil, err
+	}
+	// tv1 = 1 - c1*u^(2), tv2 = 1 + c1*u^(2), tv3 = 1/(tv1*tv2)
+	c1u2 := new(big.Int).Mul(c1, new(big.Int).Mul(u, u))
+	tv1 := new(big.Int).Sub(big.NewInt(1), c1u2)
+	tv2 := new(big.Int).Add(big.NewInt(1), c1u2)
+	tv3 := inv0(new(big.Int).Mul(tv1, tv2), p)
+	// tv4 = u*tv1*tv3*c3
+	tv4 := new(big.Int).Mul(new(big.Int).Mul(u, tv1), new(big.Int).Mul(tv3, c3))
+	tv4.Mod(tv4, p)
+
+	// x1 = c2 - tv4, x2 = c2 + tv4, x3 = Z + c4*(tv2^(2)*tv3)^(2)
+	x := new(big.Int).Mod(new(big.Int).Sub(c2, tv4), p)
+	if !isSquare(c.Polynomial(x), p) {
+		x.Add(c2, tv4).Mod(x, p)
+		if !isSquare(c.Polynomial(x), p) {
+			t := new(big.Int).Mul(new(big.Int).Mul(tv2, tv2), tv3)
+			t.Mul(t, t)
+			x.Add(new(big.Int).Mul(c4, t), z).Mod(x, p)
+		}
+	}
+	y := new(big.Int).ModSqrt(c.Polynomial(x), p)
+	if y == nil {
+		return nil, errors.New("Z is not suitable for the curve")
+	}
+	if sgn0(u, p) != sgn0(y, p) {
+		y.Sub(p, y).Mod(y, p)
+	}
+	P := new(ECPoint)
+	P.SetCoords(x, y, big.NewInt(1))
+	return P, nil
+}
+
+// Constants of SVDW map: c1 = g(Z), c2 = -Z/2,
+// c3 = sqrt(-g(Z)*(3*Z^(2) + 4*A)) with sgn0(c3) = 0,
+// c4 = -4*g(Z)/(3*Z^(2) + 4*A)
+func (c *Curve) svdwConstants(z *big.Int) (c1, c2, c3, c4 *big.Int, err error) {
+	p := c.P
+	c1 = c.Polynomial(z)
+	c2 = new(big.Int).Mul(new(big.Int).Neg(z), new(big.Int).ModInverse(big.NewInt(2), p))
+	c2.Mod(c2, p)
+	// 3*Z^(2) + 4*A
+	t := new(big.Int).Add(new(big.Int).Mul(big.NewInt(3), new(big.Int).Mul(z, z)), new(big.Int).Lsh(c.A, 2))
+	t.Mod(t, p)
+	c3 = new(big.Int).Mul(new(big.Int).Neg(c1), t)
+	c3 = new(big.Int).ModSqrt(c3.Mod(c3, p), p)
+	if c3 == nil || t.Sign() == 0 {
+		return nil, nil, nil, nil, errors.New("Z is not suitable for the curve")
+	}
+	if sgn0(c3, p) == 1 {
+		c3.Sub(p, c3)
+	}
+	c4 = new(big.Int).Mul(new(big.Int).Mul(big.NewInt(-4), c1), new(big.Int).ModInverse(t, p))
+	return c1, c2, c3, c4.Mod(c4, p), nil
+}
+
+// sgn0 of RFC 9380 (4.1) for prime fields, the parity of x mod p
+func sgn0(x, p *big.Int) uint {
+	return new(big.Int).Mod(x, p).Bit(0)
+}
+
+// Inverse of x mod p, 0 for x = 0
+func inv0(x, p *big.Int) *big.Int {
+	r := new(big.Int).ModInverse(new(big.Int).Mod(x, p), p)
+	if r == nil {
+		return new(big.Int)
+	}
+	return r
+}
+
+// Reports whether x is a square mod p (0 is)
+func isSquare(x, p *big.Int) bool {
+	return big.Jacobi(new(big.Int).Mod(x, p), p) >= 0
+}
+
+// Z for SSWU, RFC 9380 (appendix H.2): the first of 1, -1, 2, -2, ...
+// that is a non-square other than -1, such that g(x) - Z is
+// irreducible and g(B/(Z*A)) is a square
+func (c *Curve) sswuZ() *big.Int {
+	p := c.P
+	minusOne := new(big.Int).Sub(p, big.NewInt(1))
+	for ctr := int64(1); ; ctr++ {
+		for _, z := range []*big.Int{big.NewInt(ctr), big.NewInt(-ctr)} {
+			z.Mod(z, p)
+			if isSquare(z, p) || z.Cmp(minusOne) == 0 {
+				continue
+			}
+			// cubic is irreducible when it has no roots
+			if len(cubicRoots(c.A, new(big.Int).Sub(c.B, z), p)) != 0 {
+				continue
+			}
+			x := new(big.Int).Mul(c.B, new(big.Int).ModInverse(new(big.Int).Mul(z, c.A), p))
+			if isSquare(c.Polynomial(x.Mod(x, p)), p) {
+				return z
+			}
+		}
+	}
+}
+
+// Z for SVDW, RFC 9380 (appendix H.1): the first of 1, -1, 2, -2, ...
+// such that g(Z) != 0, h(Z) = -(3*Z^(2) + 4*A)/(4*g(Z)) is a non-zero
+// square, and g(Z) or g(-Z/2) is a square
+func (c *Curve) svdwZ() *big.Int {
+	p := c.P
+	for ctr := int64(1); ; ctr++ {
+		for _, z := range []*big.Int{big.NewInt(ctr), big.NewInt(-ctr)} {
+			z.Mod(z, p)
+			gz := c.Polynomial(z)
+			if gz.Sign() == 0 {
+				continue
+			}
+			h := new(big.Int).Add(new(big.Int).Mul(big.NewInt(3), new(big.Int).Mul(z, z)), new(big.Int).Lsh(c.A, 2))
+			h.Mul(h.Neg(h), new(big.Int).ModInverse(new(big.Int).Lsh(gz, 2), p))
+			if h.Mod(h, p).Sign() == 0 || !isSquare(h, p) {
+				continue
+			}
+			g2 := new(big.Int).Mul(new(big.Int).Sub(p, z), new(big.Int).ModInverse(big.NewInt(2), p))
+			g2 = c.Polynomial(g2.Mod(g2, p))
+			if isSquare(gz, p) || isSquare(g2, p) {
+				return z
+			}
+		}
+	}
+}
+
+// Z for Elligator 2, RFC 9380 (appendix H.3): the first
+// non-square of 1, -1, 2, -2, ...
+func (c *MontgomeryCurve) elligator2Z() *big.Int {
+	p := c.P
+	for ctr := int64(1); ; ctr++ {
+		for _, z := range []*big.Int{big.NewInt(ctr), big.NewInt(-ctr)} {
+			if !isSquare(z, p) {
+				return z.Mod(z, p)
+			}
+		}
+	}
+}
+
+// Hash-to-curve suite, RFC 9380 (section 8). Both hash_to_curve
+// (random oracle, `RO`) and encode_to_curve (nonuniform) are
+// supported, see `Hash`
+//
+// Points are added and cofactor is cleared on `Curve`. For
+// Elligator 2 suites it is the short Weierstrass form of
+// `Montgomery`, results are mapped back to `Montgomery`, and,
+// if `Edwards` is set, to `Edwards` (see `HashEdwards`)
+type HashToCurveSuite struct {
+	ID         string
+	Curve      *Curve
+	Montgomery *MontgomeryCurve
+	Edwards    *EdwardsCurve
+
+	RO     bool
+	Expand MessageExpander
+	// bytes per field element in hash_to_field
+	L int
+	// non-square of the map
+	Z *big.Int
+	// scalar clearing the cofactor
+	HEff *big.Int
+
+	// curve isogenous to `Curve` where SSWU is done (for
+	// the curves with a = 0 or b = 0) and the isogeny
+	iso    *Curve
+	isoMap func(*ECPoint) *ECPoint
+	isoInv func(*ECPoint) []*ECPoint
+	// SVDW is used instead of SSWU
+	svdw bool
+	// map from `Montgomery` to `Edwards`
+	toEdwards func(*ECPoint) (*EdPoint, error)
+}
+
+// Returns SSWU suite for curve `c` with a != 0 and b != 0, security
+// level `k` bits and h_eff = h. Z is found as RFC 9380 (appendix H.2)
+// describes, so for P-256, P-384 and P-521 the suites are the same as
+// the ones in RFC 9380 (see `NewHashToCurveSuite` for them)
+func NewSSWUSuite(id string, c *Curve, expand MessageExpander, k int, ro bool) (*HashToCurveSuite, error) {
+	if c.A.Sign() == 0 || c.B.Sign() == 0 {
+		return nil, errors.New("SSWU needs a != 0 and b != 0, use SVDW or an isogenous curve")
+	}
+	return &HashToCurveSuite{
+		ID:     id,
+		Curve:  c,
+		RO:     ro,
+		Expand: expand,
+		L:      fieldLen(c.P, k),
+		Z:      c.sswuZ(),
+		HEff:   new(big.Int).Set(c.H),
+	}, nil
+}
+
+// Returns SVDW suite for any curve `c`, security level `k` bits and
+// h_eff = h. Z is found as RFC 9380 (appendix H.1) describes
+func NewSVDWSuite(id string, c *Curve, expand MessageExpander, k int, ro bool) (*HashToCurveSuite, error) {
+	z := c.svdwZ()
+	if _, _, _, _, err := c.svdwConstants(z); err != nil {
+		return nil, err
+	}
+	return &HashToCurveSuite{
+		ID:     id,
+		Curve:  c,
+		RO:     ro,
+		Expand: expand,
+		L:      fieldLen(c.P, k),
+		Z:      z,
+		HEff:   new(big.Int).Set(c.H),
+		svdw:   true,
+	}, nil
+}
+
+// Returns Elligator 2 suite for Montgomery curve `m`, security
+// level `k` bits and h_eff = h. Z is found as RFC 9380
+// (appendix H.3) describes
+func NewElligator2Suite(id string, m *MontgomeryCurve, expand MessageExpander, k int, ro bool) (*HashToCurveSuite, error) {
+	w, err := m.Weierstrass()
+	if err != nil {
+		return nil, err
+	}
+	return &HashToCurveSuite{
+		ID:         id,
+		Curve:      w,
+		Montgomery: m,
+		RO:         ro,
+		Expand:     expand,
+		L:          fieldLen(m.P, k),
+		Z:          m.elligator2Z(),
+		HEff:       new(big.Int).Set(m.H),
+	}, nil
+}
+
+// Returns one of the suites of RFC 9380 (section 8) by its ID,
+// P256_, P384_, P521_, secp256k1_XMD:SHA-256_SSWU_, curve25519_
+// and edwards25519_XMD:SHA-512_ELL2_, curve448_ and
+// edwards448_XOF:SHAKE256_ELL2_ (each with RO_ and NU_ endings)
+func NewHashToCurveSuite(id string) (*HashToCurveSuite, error) {
+	if len(id) < 4 {
+		return nil, errors.New("unknown hash-to-curve suite")
+	}
+	name, ro := id[:len(id)-3], id[len(id)-3:] == "RO_"
+	if !ro && id[len(id)-3:] != "NU_" {
+		return nil, errors.New("unknown hash-to-curve suite")
+	}
+
+	switch name {
+	case "P256_XMD:SHA-256_SSWU_":
+		return NewSSWUSuite(id, FromElliptic(elliptic.P256()), XMD(sha256.New), 128, ro)
+	case "P384_XMD:SHA-384_SSWU_":
+		return NewSSWUSuite(id, FromElliptic(elliptic.P384()), XMD(sha512.New384), 192, ro)
+	case "P521_XMD:SHA-512_SSWU_":
+		return NewSSWUSuite(id, FromElliptic(elliptic.P521()), XMD(sha512.New), 256, ro)
+	case "secp256k1_XMD:SHA-256_SSWU_":
+		c := Secp256k1()
+		return &HashToCurveSuite{
+			ID:     id,
+			Curve:  c,
+			RO:     ro,
+			Expand: XMD(sha256.New),
+			L:      fieldLen(c.P, 128),
+			Z:      new(big.Int).Sub(c.P, big.NewInt(11)),
+			HEff:   big.NewInt(1),
+			iso:    secp256k1Iso(),
+			isoMap: secp256k1IsoMap,
+			isoInv: secp256k1IsoPreimages,
+		}, nil
+	case "curve25519_XMD:SHA-512_ELL2_", "edwards25519_XMD:SHA-512_ELL2_":
+		s, err := NewElligator2Suite(id, Curve25519(), XMD(sha512.New), 128, ro)
+		if err != nil || name[0] == 'c' {
+			return s, err
+		}
+		// RFC 9380 (appendix D.1) fixes sqrt(-486664) to the even one
+		p := s.Montgomery.P
+		S := new(big.Int).ModSqrt(new(big.Int).Sub(p, big.NewInt(486664)), p)
+		if S.Bit(0) == 1 {
+			S.Sub(p, S)
+		}
+		mp := &EdMontMap{Edwards: Edwards25519(), Montgomery: s.Montgomery, S: S}
+		s.Edwards, s.toEdwards = mp.Edwards, mp.ToEdwards
+		return s, nil
+	case "curve448_XOF:SHAKE256_ELL2_", "edwards448_XOF:SHAKE256_ELL2_":
+		s, err := NewElligator2Suite(id, Curve448(), XOF(sha3.NewSHAKE256, 224), 224, ro)
+		if err != nil || name[0] == 'c' {
+			return s, err
+		}
+		s.Edwards, s.toEdwards = Edwards448(), curve448ToEdwards448
+		return s, nil
+	}
+	return nil, errors.New("unknown hash-to-curve suite")
+}
+
+// hash_to_field of the suite, `count` elements of F_p
+func (s *HashToCurveSuite) HashToField(msg, dst []byte, count int) ([]*big.Int, error) {
+	return HashToField(s.Expand, msg, dst, s.Curve.P, count, 1, s.L)
+}
+
+// map_to_curve of the suite, returns point on `Curve`
+func (s *HashToCurveSuite) MapToCurve(u *big.Int) (*ECPoint, error) {
+	if s.Montgomery != nil {
+		P, err := s.Montgomery.MapToCurveElligator2(u, s.Z)
+		if err != nil {
+			return nil, err
+		}
+		return s.Montgomery.ToWeierstrass(P), nil
+	}
+	if s.iso != nil {
+		P, err := s.iso.MapToCurveSSWU(u, s.Z)
+		if err != nil {
+			return nil, err
+		}
+		return s.isoMap(P), nil
+	}
+	if s.svdw {
+		return s.Curve.MapToCurveSVDW(u, s.Z)
+	}
+	return s.Curve.MapToCurveSSWU(u, s.Z)
+}
+
+// hash_to_curve (for RO suites) or encode_to_curve of `msg`
+// with domain separation tag `dst`, point on `Curve`
+func (s *HashToCurveSuite) hash(msg, dst []byte) (*ECPoint, error) {
+	count := 1
+	if s.RO {
+		count = 2
+	}
+	u, err := s.HashToField(msg, dst, count)
+	if err != nil {
+		return nil, err
+	}
+	Q := PointAtInfinity()
+	for _, e := range u {
+		P, err := s.MapToCurve(e)
+		if err != nil {
+			return nil, err
+		}
+		Q = s.Curve.ECPAdd(Q, P)
+	}
+	return s.Curve.ECPAffine(s.Curve.ECPScalarMul(Q, s.HEff)), nil
+}
+
+// Hashes `msg` with domain separation tag `dst` to the curve,
+// with hash_to_curve for RO suites and encode_to_curve otherwise.
+// Result is on `Montgomery` for Elligator 2 suites and on
+// `Curve` for the others
+func (s *HashToCurveSuite) Hash(msg, dst []byte) (*ECPoint, error) {
+	P, err := s.hash(msg, dst)
+	if err != nil {
+		return nil, err
+	}
+	if s.Montgomery != nil {
+		return s.Montgomery.FromWeierstrass(P), nil
+	}
+	return P, nil
+}
+
+// Same as `Hash`, but the result is on `Edwards`
+func (s *HashToCurveSuite) HashEdwards(msg, dst []byte) (*EdPoint, error) {
+	if s.Edwards == nil {
+		return nil, errors.New("suite has no Edwards curve")
+	}
+	P, err := s.Hash(msg, dst)
+	if err != nil {
+		return nil, err
+	}
+	return s.toEdwards(P)
+}
+
+var initSecp256k1Iso sync.Once
+var secp256k1IsoCurve *Curve
+
+// coefficients of the 3-isogeny map, lowest degree first:
+// x numerator, x denominator, y numerator, y denominator
+var secp256k1IsoK [4][]*big.Int
+
+// Returns curve E' isogenous to secp256k1, RFC 9380 (8.7).
+// It has a != 0, so SSWU can be done on it
+func secp256k1Iso() *Curve {
+	initSecp256k1Iso.Do(func() {
+		h := func(s string) *big.Int {
+			v, _ := new(big.Int).SetString(s, 16)
+			return v
+		}
+		// RFC 9380 (appendix E.1)
+		secp256k1IsoK = [4][]*big.Int{
+			{
+				h("8e38e38e38e38e38e38e38e38e38e38e38e38e38e38e38e38e38e38daaaaa8c7"),
+				h("07d3d4c80bc321d5b9f315cea7fd44c5d595d2fc0bf63b92dfff1044f17c6581"),
+				h("534c328d23f234e6e2a413deca25caece4506144037c40314ecbd0b53d9dd262"),
+				h("8e38e38e38e38e38e38e38e38e38e38e38e38e38e38e38e38e38e38daaaaa88c"),
+			},
+			{
+				h("d35771193d94918a9ca34ccbb7b640dd86cd409542f8487d9fe6b745781eb49b"),
+				h("edadc6f64383dc1df7c4b2d51b54225406d36b641f5e41bbc52a56612a8c6d14"),
+				big.NewInt(1),
+			},
+			{
+				h("4bda12f684bda12f684bda12f684bda12f684bda12f684bda12f684b8e38e23c"),
+				h("c75e0c32d5cb7c0fa9d0a54b12a0a6d5647ab046d686da6fdffc90fc201d71a3"),
+				h("29a6194691f91a73715209ef6512e576722830a201be2018a765e85a9ecee931"),
+				h("2f684bda12f684bda12f684bda12f684bda12f684bda12f684bda12f38e38d84"),
+			},
+			{
+				h("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffff93b"),
+				h("7a06534bb8bdb49fd5e9e6632722c2989467c1bfc8e8d978dfb425d2685c2573"),
+				h("6484aa716545ca2cf3a70c3fa8fe337e0a3d21162f0d6299a7bf8192bfd2a76f"),
+				big.NewInt(1),
+			},
+		}
+		secp256k1IsoCurve = &Curve{
+			Name: "secp256k1 3-isogenous",
+			P:    Secp256k1().P,
+			A:    h("3f8731abdd661adca08a5558f0f5d272e953d363cb6f0e5d405447c01a444533"),
+			B:    big.NewInt(1771),
+		}
+	})
+	return secp256k1IsoCurve
+}
+
+// 3-isogeny from E' to secp256k1, (x', y') goes to
+// (x_num(x')/x_den(x'), y' * y_num(x')/y_den(x')). Points
+// where a denominator is 0 go to the point at infinity
+func secp256k1IsoMap(P *ECPoint) *ECPoint {
+	if P.IsInfinity() {
+		return PointAtInfinity()
+	}
+	p := secp256k1Iso().P
+	k := secp256k1IsoK
+	xDen, yDen := polyEval(k[1], P.X, p), polyEval(k[3], P.X, p)
+	if xDen.Sign() == 0 || yDen.Sign() == 0 {
+		return PointAtInfinity()
+	}
+	x := new(big.Int).Mul(polyEval(k[0], P.X, p), new(big.Int).ModInverse(xDen, p))
+	y := new(big.Int).Mul(polyEval(k[2], P.X, p), new(big.Int).ModInverse(yDen, p))
+	y.Mul(y, P.Y)
+	Q := new(ECPoint)
+	Q.SetCoords(x.Mod(x, p), y.Mod(y, p), big.NewInt(1))
+	return Q
+}
+
+// 4-isogeny from curve448 to edwards448, RFC 7748 (4.2):
+//
+//	x = 4*v*(u^(2) - 1) / (u^(4) - 2*u^(2) + 4*v^(2) + 1)
+//	y = -(u^(5) - 2*u^(3) - 4*u*v^(2) + u) /
+//	     (u^(5) - 2*u^(2)*v^(2) - 2*u^(3) - 2*v^(2) + u)
+//
+// Points where a denominator is 0 go to the identity
+func curve448ToEdwards448(P *ECPoint) (*EdPoint, error) {
+	e := Edwards448()
+	if P.IsInfinity() {
+		return e.Identity(), nil
+	}
+	p := e.P
+	u, v := P.X, P.Y
+	uu := new(big.Int).Mul(u, u)
+	vv := new(big.Int).Mul(v, v)
+	uuu := new(big.Int).Mul(uu, u)
+	two, four := big.NewInt(2), big.NewInt(4)
+
+	xNum := new(big.Int).Mul(new(big.Int).Mul(four, v), new(big.Int).Sub(uu, big.NewInt(1)))
+	xDen := new(big.Int).Mul(uu, uu)
+	xDen.Sub(xDen, new(big.Int).Mul(two, uu)).Add(xDen, new(big.Int).Mul(four, vv)).Add(xDen, big.NewInt(1))
+	yNum := new(big.Int).Mul(uuu, uu)
+	yNum.Sub(yNum, new(big.Int).Mul(two, uuu)).Sub(yNum, new(big.Int).Mul(four, new(big.Int).Mul(u, vv))).Add(yNum, u)
+	yNum.Neg(yNum)
+	yDen := new(big.Int).Mul(uuu, uu)
+	yDen.Sub(yDen, new(big.Int).Mul(two, new(big.Int).Mul(uu, vv))).Sub(yDen, new(big.Int).Mul(two, uuu))
+	yDen.Sub(yDen, new(big.Int).Mul(two, vv)).Add(yDen, u)
+
+	xDen.Mod(xDen, p)
+	yDen.Mod(yDen, p)
+	if xDen.Sign() == 0 || yDen.Sign() == 0 {
+		return e.Identity(), nil
+	}
+	x := new(big.Int).Mul(xNum, new(big.Int).ModInverse(xDen, p))
+	y := new(big.Int).Mul(yNum, new(big.Int).ModInverse(yDen, p))
+	return e.NewPoint(x.Mod(x, p), y.Mod(y, p)), nil
+}
@@ -0,0 +1,213 @@
+package ECwrap
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha3"
+	"crypto/sha512"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testdata/rfc9380 holds JSON vectors of RFC 9380 (appendix J and K)
+// as published with the draft, vectors.txt has the rest of suites
+
+func TestExpandMessage(t *testing.T) {
+	files, _ := filepath.Glob("testdata/rfc9380/expand_message_*.json")
+	if len(files) == 0 {
+		t.Fatal("no expand_message vectors")
+	}
+	for _, name := range files {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var v struct {
+			DST   string
+			Hash  string
+			K     int
+			Tests []struct {
+				Len     string `json:"len_in_bytes"`
+				Msg     string
+				Uniform string `json:"uniform_bytes"`
+			}
+		}
+		if err := json.Unmarshal(data, &v); err != nil {
+			t.Fatal(err)
+		}
+
+		var expand MessageExpander
+		switch v.Hash {
+		case "SHA256":
+			expand = XMD(sha256.New)
+		case "SHA512":
+			expand = XMD(sha512.New)
+		case "SHAKE128":
+			expand = XOF(sha3.NewSHAKE128, v.K)
+		case "SHAKE256":
+			expand = XOF(sha3.NewSHAKE256, v.K)
+		}
+		for i, c := range v.Tests {
+			length, _ := strconv.ParseInt(c.Len, 0, 32)
+			got, err := expand([]byte(c.Msg), []byte(v.DST), int(length))
+			if err != nil || !bytes.Equal(got, decodeHex(c.Uniform)) {
+				t.Errorf(`%s %d: expand() = %x, expected = %s, err = %v`, name, i, got, c.Uniform, err)
+			}
+		}
+	}
+}
+
+type h2cPoint struct {
+	X, Y string
+}
+
+func (p h2cPoint) equal(P *ECPoint) bool {
+	return P.X.Cmp(fromHex(strings.TrimPrefix(p.X, "0x"))) == 0 && P.Y.Cmp(fromHex(strings.TrimPrefix(p.Y, "0x"))) == 0
+}
+
+func TestHashToCurveVectors(t *testing.T) {
+	files, _ := filepath.Glob("testdata/rfc9380/P*.json")
+	if len(files) == 0 {
+		t.Fatal("no hash-to-curve vectors")
+	}
+	for _, name := range files {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var v struct {
+			Ciphersuite  string
+			DST          string
+			Z            string
+			RandomOracle bool
+			Vectors      []struct {
+				P, Q, Q0, Q1 h2cPoint
+				Msg          string
+				U            []string
+			}
+		}
+		if err := json.Unmarshal(data, &v); err != nil {
+			t.Fatal(err)
+		}
+
+		s, err := NewHashToCurveSuite(v.Ciphersuite)
+		if err != nil {
+			t.Fatalf(`%s: %v`, v.Ciphersuite, err)
+		}
+		if s.Z.Cmp(fromHex(strings.TrimPrefix(v.Z, "0x"))) != 0 || s.RO != v.RandomOracle {
+			t.Fatalf(`%s: Z = %x, RO = %t, expected = %s, %t`, v.Ciphersuite, s.Z, s.RO, v.Z, v.RandomOracle)
+		}
+		for _, c := range v.Vectors {
+			u, err := s.HashToField([]byte(c.Msg), []byte(v.DST), len(c.U))
+			if err != nil {
+				t.Fatal(err)
+			}
+			Q := []h2cPoint{c.Q}
+			if s.RO {
+				Q = []h2cPoint{c.Q0, c.Q1}
+			}
+			for i := range c.U {
+				if u[i].Cmp(fromHex(strings.TrimPrefix(c.U[i], "0x"))) != 0 {
+					t.Fatalf(`%s %q: u[%d] = %x, expected = %s`, v.Ciphersuite, c.Msg, i, u[i], c.U[i])
+				}
+				P, err := s.MapToCurve(u[i])
+				if err != nil || !Q[i].equal(P) {
+					t.Fatalf(`%s %q: MapToCurve(u[%d]) = %x, %x, expected = %s, %s`, v.Ciphersuite, c.Msg, i, P.X, P.Y, Q[i].X, Q[i].Y)
+				}
+			}
+			P, err := s.Hash([]byte(c.Msg), []byte(v.DST))
+			if err != nil || !c.P.equal(P) {
+				t.Fatalf(`%s %q: Hash() = %x, %x, expected = %s, %s`, v.Ciphersuite, c.Msg, P.X, P.Y, c.P.X, c.P.Y)
+			}
+		}
+	}
+}
+
+func TestHashToCurveSuites(t *testing.T) {
+	f, err := os.Open("testdata/rfc9380/vectors.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if strings.HasPrefix(sc.Text(), "#") {
+			continue
+		}
+		v := strings.Split(sc.Text(), "|")
+		s, err := NewHashToCurveSuite(v[0])
+		if err != nil {
+			t.Fatalf(`%s: %v`, v[0], err)
+		}
+		dst := []byte("QUUX-V01-CS02-with-" + v[0])
+		expected := h2cPoint{v[2], v[3]}
+
+		if s.Edwards == nil {
+			P, err := s.Hash([]byte(v[1]), dst)
+			if err != nil || !expected.equal(P) {
+				t.Errorf(`%s %q: Hash() = %x, %x, expected = %s, %s, err = %v`, v[0], v[1], P.X, P.Y, v[2], v[3], err)
+			}
+			continue
+		}
+		P, err := s.HashEdwards([]byte(v[1]), dst)
+		if err != nil {
+			t.Fatalf(`%s: %v`, v[0], err)
+		}
+		x, y := s.Edwards.Affine(P)
+		if !expected.equal(&ECPoint{X: x, Y: y}) {
+			t.Errorf(`%s %q: HashEdwards() = %x, %x, expected = %s, %s`, v[0], v[1], x, y, v[2], v[3])
+		}
+		if !s.Edwards.IsIdentity(s.Edwards.ScalarMul(P, s.Edwards.N)) {
+			t.Errorf(`%s %q: point is not in the prime order subgroup`, v[0], v[1])
+		}
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Custom suite over a curve that is not in RFC 9380
+func TestSSWUToyCurve(t *testing.T) {
+	c := toyCurve(t)
+	s, err := NewSSWUSuite("toy", c, XMD(sha256.New), 128, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := int64(0); i < c.P.Int64(); i++ {
+		P, err := s.MapToCurve(big.NewInt(i))
+		if err != nil || !c.ECPIsOnCurve(P) {
+			t.Fatalf(`MapToCurve(%d) = %v, err = %v`, i, P, err)
+		}
+	}
+	P, err := s.Hash([]byte("msg"), []byte("dst"))
+	if err != nil || !c.ECPIsOnCurve(P) || !c.ECPScalarMul(P, c.N).IsInfinity() {
+		t.Fatalf(`Hash() = %v, err = %v`, P, err)
+	}
+	if _, err := NewSSWUSuite("secp256k1", Secp256k1(), XMD(sha256.New), 128, true); err == nil {
+		t.Fatalf(`SSWU suite for a = 0 curve created`)
+	}
+}
+
+// Z of SVDW must satisfy g(Z) or g(-Z/2) being a square,
+// not g(-Z)/2. Z = 1 is the one of BN254G1_XMD:SHA-256_SVDW_RO_
+// (gnark-crypto), on the toy curve g(-Z)/2 would pick 2
+func TestSVDWZ(t *testing.T) {
+	for _, test := range []struct {
+		c *Curve
+		z int64
+	}{
+		{BN254().G1, 1},
+		{&Curve{P: big.NewInt(199), A: big.NewInt(198), B: big.NewInt(168)}, 4},
+	} {
+		if z := test.c.svdwZ(); z.Cmp(big.NewInt(test.z)) != 0 {
+			t.Fatalf(`svdwZ() over F_%v = %v, expected = %d`, test.c.P, z, test.z)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package ECwrap
+
+import (
+	"crypto/sha3"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"math/big"
+)
+
+// Hashing to a finite field, RFC 9380 (section 5)
+// https://www.rfc-editor.org/rfc/rfc9380
+
+// expand_message of RFC 9380: derives `length` uniformly random
+// bytes from `msg` and the domain separation tag `dst`
+type MessageExpander func(msg, dst []byte, length int) ([]byte, error)
+
+// expand_message_xmd (RFC 9380, 5.3.1) with Merkle-Damgard hash `h`.
+// DST longer than 255 bytes is hashed, as section 5.3.3 says
+func ExpandMessageXMD(h func() hash.Hash, msg, dst []byte, length int) ([]byte, error) {
+	md := h()
+	size := md.Size()
+	ell := (length + size - 1) / size
+	if length < 0 || length > 65535 || ell > 255 {
+		return nil, errors.New("requested length is too big")
+	}
+	if len(dst) > 255 {
+		md.Write([]byte("H2C-OVERSIZE-DST-"))
+		md.Write(dst)
+		dst = md.Sum(nil)
+	}
+	dstPrime := append(append([]byte(nil), dst...), byte(len(dst)))
+
+	// b_0 = H(Z_pad || msg || I2OSP(length, 2) || 0x00 || DST_prime)
+	md.Reset()
+	md.Write(make([]byte, md.BlockSize()))
+	md.Write(msg)
+	md.Write(binary.BigEndian.AppendUint16(nil, uint16(length)))
+	md.Write([]byte{0})
+	md.Write(dstPrime)
+	b0 := md.Sum(nil)
+
+	// b_i = H(strxor(b_0, b_(i - 1)) || I2OSP(i, 1) || DST_prime),
+	// b_1 = H(b_0 || 0x01 || DST_prime)
+	out := make([]byte, 0, ell*size)
+	bi := make([]byte, size)
+	for i := 1; i <= ell; i++ {
+		for j := range bi {
+			bi[j] ^= b0[j]
+		}
+		md.Reset()
+		md.Write(bi)
+		md.Write([]byte{byte(i)})
+		md.Write(dstPrime)
+		bi = md.Sum(nil)
+		out = append(out, bi...)
+	}
+	return out[:length], nil
+}
+
+// expand_message_xof (RFC 9380, 5.3.2) with extendable-output
+// function `h` (SHAKE128 or SHAKE256) of security level `k` bits.
+// DST longer than 255 bytes is hashed to ceil(2*k/8) bytes
+func ExpandMessageXOF(h func() *sha3.SHAKE, k int, msg, dst []byte, length int) ([]byte, error) {
+	if length < 0 || length > 65535 {
+		return nil, errors.New("requested length is too big")
+	}
+	if len(dst) > 255 {
+		xof := h()
+		xof.Write([]byte("H2C-OVERSIZE-DST-"))
+		xof.Write(dst)
+		dst = make([]byte, (2*k+7)/8)
+		xof.Read(dst)
+	}
+	// msg || I2OSP(length, 2) || DST || I2OSP(len(DST), 1)
+	xof := h()
+	xof.Write(msg)
+	xof.Write(binary.BigEndian.AppendUint16(nil, uint16(length)))
+	xof.Write(dst)
+	xof.Write([]byte{byte(len(dst))})
+	out := make([]byte, length)
+	xof.Read(out)
+	return out, nil
+}
+
+// Returns `ExpandMessageXMD` with fixed hash function
+func XMD(h func() hash.Hash) MessageExpander {
+	return func(msg, dst []byte, length int) ([]byte, error) {
+		return ExpandMessageXMD(h, msg, dst, length)
+	}
+}
+
+// Returns `ExpandMessageXOF` with fixed XOF and security level
+func XOF(h func() *sha3.SHAKE, k int) MessageExpander {
+	return func(msg, dst []byte, length int) ([]byte, error) {
+		return ExpandMessageXOF(h, k, msg, dst, length)
+	}
+}
+
+// hash_to_field (RFC 9380, 5.2): hashes `msg` to `count` elements
+// of F_(p^m), each of them is `m` consecutive values of the result,
+// reduced mod p from `l` bytes of `expand` output. For security
+// level k, l should be ceil((ceil(log2(p)) + k) / 8), see `fieldLen`
+func HashToField(expand MessageExpander, msg, dst []byte, p *big.Int, count, m, l int) ([]*big.Int, error) {
+	uniform, err := expand(msg, dst, count*m*l)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*big.Int, count*m)
+	for i := range out {
+		e := new(big.Int).SetBytes(uniform[i*l : (i+1)*l])
+		out[i] = e.Mod(e, p)
+	}
+	return out, nil
+}
+
+// Length L of hash_to_field for field of order p
+// and security level k bits
+func fieldLen(p *big.Int, k int) int {
+	return (p.BitLen() + k + 7) / 8
+}
@@ -0,0 +1,494 @@
+package ECwrap
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"io"
+	"math/big"
+)
+
+// Hybrid Public Key Encryption (RFC 9180) with DHKEM over
+// the curves of ECwrap: P-256, P-384, P-521 (as Curve) and
+// X25519, X448 (as XDH)
+// https://www.rfc-editor.org/rfc/rfc9180
+//
+// Keys are passed around serialized, as RFC 9180 defines:
+// uncompressed points and big-endian scalars for the NIST
+// curves, raw 32 / 56 bytes for X25519 / X448
+
+// KEM identifier (RFC 9180, 7.1)
+type HPKEKEM uint16
+
+const (
+	HPKEDHKEMP256   HPKEKEM = 0x0010
+	HPKEDHKEMP384   HPKEKEM = 0x0011
+	HPKEDHKEMP521   HPKEKEM = 0x0012
+	HPKEDHKEMX25519 HPKEKEM = 0x0020
+	HPKEDHKEMX448   HPKEKEM = 0x0021
+)
+
+// KDF identifier (RFC 9180, 7.2)
+type HPKEKDF uint16
+
+const (
+	HPKEHKDFSHA256 HPKEKDF = 0x0001
+	HPKEHKDFSHA384 HPKEKDF = 0x0002
+	HPKEHKDFSHA512 HPKEKDF = 0x0003
+)
+
+// AEAD identifier (RFC 9180, 7.3)
+type HPKEAEAD uint16
+
+const (
+	HPKEAES128GCM        HPKEAEAD = 0x0001
+	HPKEAES256GCM        HPKEAEAD = 0x0002
+	HPKEChaCha20Poly1305 HPKEAEAD = 0x0003
+	// No encryption, the context can only export secrets
+	HPKEExportOnly HPKEAEAD = 0xFFFF
+)
+
+// HPKE mode (RFC 9180, 5), chosen by `HPKEOptions`
+const (
+	hpkeModeBase    byte = 0x00
+	hpkeModePSK     byte = 0x01
+	hpkeModeAuth    byte = 0x02
+	hpkeModeAuthPSK byte = 0x03
+)
+
+// HPKE ciphersuite: KEM, KDF and AEAD
+type HPKESuite struct {
+	KEM  HPKEKEM
+	KDF  HPKEKDF
+	AEAD HPKEAEAD
+
+	// ChaCha20-Poly1305 is not in the standard library, pass
+	// its constructor (e.g. `chacha20poly1305.New` from
+	// golang.org/x/crypto) to use HPKEChaCha20Poly1305.
+	// Without it the context can still export secrets
+	NewChaCha20Poly1305 func(key []byte) (cipher.AEAD, error)
+
+	// KEM parameters
+	curve   *Curve
+	xdh     *XDH
+	kemHash func() hash.Hash
+	// Nsecret, Nenc (= Npk), Nsk of RFC 9180
+	nSecret int
+	nPk     int
+	nSk     int
+	// bitmask of DeriveKeyPair for the NIST curves
+	bitmask byte
+
+	kdfHash func() hash.Hash
+	// Nk and Nn of the AEAD
+	nK int
+	nN int
+}
+
+// Options of HPKE setup, they select the mode. With PSK
+// set the mode is psk, with sender's key set it is auth,
+// with both it is auth_psk, otherwise base
+type HPKEOptions struct {
+	// Pre-shared key and its identifier, both or none
+	PSK   []byte
+	PSKID []byte
+
+	// Sender's private key, for sender setup in auth modes
+	SenderPrivateKey []byte
+	// Sender's public key, for receiver setup in auth modes
+	SenderPublicKey []byte
+}
+
+// Returns ciphersuite with given identifiers
+func NewHPKESuite(kem HPKEKEM, kdf HPKEKDF, aead HPKEAEAD) (*HPKESuite, error) {
+	s := &HPKESuite{KEM: kem, KDF: kdf, AEAD: aead}
+	switch kem {
+	case HPKEDHKEMP256:
+		s.curve, s.kemHash, s.nSecret, s.bitmask = FromElliptic(elliptic.P256()), sha256.New, 32, 0xFF
+	case HPKEDHKEMP384:
+		s.curve, s.kemHash, s.nSecret, s.bitmask = FromElliptic(elliptic.P384()), sha512.New384, 48, 0xFF
+	case HPKEDHKEMP521:
+		s.curve, s.kemHash, s.nSecret, s.bitmask = FromElliptic(elliptic.P521()), sha512.New, 64, 0x01
+	case HPKEDHKEMX25519:
+		s.xdh, s.kemHash, s.nSecret = X25519(), sha256.New, 32
+	case HPKEDHKEMX448:
+		s.xdh, s.kemHash, s.nSecret = X448(), sha512.New, 64
+	default:
+		return nil, errors.New("unknown HPKE KEM")
+	}
+	if s.curve != nil {
+		s.nSk = s.curve.byteLen()
+		s.nPk = 1 + 2*s.nSk
+	} else {
+		s.nSk = s.xdh.Size()
+		s.nPk = s.xdh.Size()
+	}
+
+	switch kdf {
+	case HPKEHKDFSHA256:
+		s.kdfHash = sha256.New
+	case HPKEHKDFSHA384:
+		s.kdfHash = sha512.New384
+	case HPKEHKDFSHA512:
+		s.kdfHash = sha512.New
+	default:
+		return nil, errors.New("unknown HPKE KDF")
+	}
+
+	switch aead {
+	case HPKEAES128GCM:
+		s.nK, s.nN = 16, 12
+	case HPKEAES256GCM, HPKEChaCha20Poly1305:
+		s.nK, s.nN = 32, 12
+	case HPKEExportOnly:
+	default:
+		return nil, errors.New("unknown HPKE AEAD")
+	}
+	return s, nil
+}
+
+// "KEM" || I2OSP(kem_id, 2)
+func (s *HPKESuite) kemSuiteID() []byte {
+	return binary.BigEndian.AppendUint16([]byte("KEM"), uint16(s.KEM))
+}
+
+// "HPKE" || I2OSP(kem_id, 2) || I2OSP(kdf_id, 2) || I2OSP(aead_id, 2)
+func (s *HPKESuite) suiteID() []byte {
+	id := binary.BigEndian.AppendUint16([]byte("HPKE"), uint16(s.KEM))
+	id = binary.BigEndian.AppendUint16(id, uint16(s.KDF))
+	return binary.BigEndian.AppendUint16(id, uint16(s.AEAD))
+}
+
+// LabeledExtract(salt, label, ikm) of RFC 9180, 4
+func labeledExtract(h func() hash.Hash, suiteID, salt []byte, label string, ikm []byte) ([]byte, error) {
+	labeled := append(append(append([]byte("HPKE-v1"), suiteID...), label...), ikm...)
+	return hkdf.Extract(h, labeled, salt)
+}
+
+// LabeledExpand(prk, label, info, L) of RFC 9180, 4
+func labeledExpand(h func() hash.Hash, suiteID, prk []byte, label string, info []byte, length int) ([]byte, error) {
+	labeled := binary.BigEndian.AppendUint16(nil, uint16(length))
+	labeled = append(append(append(append(labeled, "HPKE-v1"...), suiteID...), label...), info...)
+	return hkdf.Expand(h, prk, string(labeled), length)
+}
+
+// Derives KEM key pair from input keying material `ikm`
+// (RFC 9180, 7.1.3), returns serialized private and public keys
+func (s *HPKESuite) DeriveKeyPair(ikm []byte) ([]byte, []byte, error) {
+	if len(ikm) < s.nSk {
+		return nil, nil, errors.New("input keying material is too short")
+	}
+	id := s.kemSuiteID()
+	prk, err := labeledExtract(s.kemHash, id, nil, "dkp_prk", ikm)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if s.xdh != nil {
+		sk, err := labeledExpand(s.kemHash, id, prk, "sk", nil, s.nSk)
+		if err != nil {
+			return nil, nil, err
+		}
+		pk, err := s.xdh.ScalarBaseMult(sk)
+		return sk, pk, err
+	}
+
+	for counter := 0; counter < 256; counter++ {
+		b, err := labeledExpand(s.kemHash, id, prk, "candidate", []byte{byte(counter)}, s.nSk)
+		if err != nil {
+			return nil, nil, err
+		}
+		b[0] &= s.bitmask
+		d := new(big.Int).SetBytes(b)
+		if d.Sign() != 0 && d.Cmp(s.curve.N) < 0 {
+			return b, s.curve.ECPMarshal(s.curve.ECPScalarBaseMul(d)), nil
+		}
+	}
+	return nil, nil, errors.New("could not derive key pair")
+}
+
+// Generates KEM key pair, Nsk bytes of `rand` are used
+// as input keying material of `DeriveKeyPair`
+func (s *HPKESuite) GenerateKeyPair(rand io.Reader) ([]byte, []byte, error) {
+	ikm := make([]byte, s.nSk)
+	if _, err := io.ReadFull(rand, ikm); err != nil {
+		return nil, nil, errors.New("could not generate rand value")
+	}
+	return s.DeriveKeyPair(ikm)
+}
+
+// Returns serialized public key of serialized private key `sk`
+func (s *HPKESuite) PublicKey(sk []byte) ([]byte, error) {
+	if len(sk) != s.nSk {
+		return nil, errors.New("invalid private key length")
+	}
+	if s.xdh != nil {
+		return s.xdh.ScalarBaseMult(sk)
+	}
+	d, err := s.curve.ScalarFromBytes(sk)
+	if err != nil {
+		return nil, err
+	}
+	if d.IsZero() {
+		return nil, errors.New("private key is zero")
+	}
+	return s.curve.ECPMarshal(s.curve.ECPBaseMul(d)), nil
+}
+
+// DH(sk, pk), shared secret of serialized keys
+func (s *HPKESuite) dh(sk, pk []byte) ([]byte, error) {
+	if len(sk) != s.nSk || len(pk) != s.nPk {
+		return nil, errors.New("invalid key length")
+	}
+	if s.xdh != nil {
+		return s.xdh.ScalarMult(sk, pk)
+	}
+	Q, err := s.curve.ECPUnmarshal(pk)
+	if err != nil {
+		return nil, err
+	}
+	d, err := s.curve.ScalarFromBytes(sk)
+	if err != nil {
+		return nil, err
+	}
+	return ECDH(s.curve, d, Q, nil)
+}
+
+// ExtractAndExpand of DHKEM
+func (s *HPKESuite) extractAndExpand(dh, kemContext []byte) ([]byte, error) {
+	id := s.kemSuiteID()
+	prk, err := labeledExtract(s.kemHash, id, nil, "eae_prk", dh)
+	if err != nil {
+		return nil, err
+	}
+	return labeledExpand(s.kemHash, id, prk, "shared_secret", kemContext, s.nSecret)
+}
+
+// Encap / AuthEncap (when skS is not nil), returns
+// shared secret and encapsulated key
+func (s *HPKESuite) encap(pkR, skS []byte, rand io.Reader) ([]byte, []byte, error) {
+	skE, pkE, err := s.GenerateKeyPair(rand)
+	if err != nil {
+		return nil, nil, err
+	}
+	dh, err := s.dh(skE, pkR)
+	if err != nil {
+		return nil, nil, err
+	}
+	kemContext := append(append([]byte(nil), pkE...), pkR...)
+	if skS != nil {
+		dhS, err := s.dh(skS, pkR)
+		if err != nil {
+			return nil, nil, err
+		}
+		pkS, err := s.PublicKey(skS)
+		if err != nil {
+			return nil, nil, err
+		}
+		dh = append(dh, dhS...)
+		kemContext = append(kemContext, pkS...)
+	}
+	shared, err := s.extractAndExpand(dh, kemContext)
+	return shared, pkE, err
+}
+
+// Decap / AuthDecap (when pkS is not nil)
+func (s *HPKESuite) decap(enc, skR, pkS []byte) ([]byte, error) {
+	dh, err := s.dh(skR, enc)
+	if err != nil {
+		return nil, err
+	}
+	pkR, err := s.PublicKey(skR)
+	if err != nil {
+		return nil, err
+	}
+	kemContext := append(append([]byte(nil), enc...), pkR...)
+	if pkS != nil {
+		dhS, err := s.dh(skR, pkS)
+		if err != nil {
+			return nil, err
+		}
+		dh = append(dh, dhS...)
+		kemContext = append(kemContext, pkS...)
+	}
+	return s.extractAndExpand(dh, kemContext)
+}
+
+// Returns mode for options and checks PSK inputs
+func hpkeMode(opts *HPKEOptions, auth bool) (byte, error) {
+	if (len(opts.PSK) == 0) != (len(opts.PSKID) == 0) {
+		return 0, errors.New("PSK and PSK ID must be both set or both empty")
+	}
+	switch {
+	case len(opts.PSK) != 0 && auth:
+		return hpkeModeAuthPSK, nil
+	case len(opts.PSK) != 0:
+		return hpkeModePSK, nil
+	case auth:
+		return hpkeModeAuth, nil
+	}
+	return hpkeModeBase, nil
+}
+
+// Sets up sender's context for public key `pkR`
+// (SetupBaseS / SetupPSKS / SetupAuthS / SetupAuthPSKS,
+// depending on the options, which may be nil). Returns
+// encapsulated key to be sent to the receiver
+func (s *HPKESuite) SetupSender(pkR, info []byte, opts *HPKEOptions, rand io.Reader) ([]byte, *HPKEContext, error) {
+	if opts == nil {
+		opts = &HPKEOptions{}
+	}
+	mode, err := hpkeMode(opts, opts.SenderPrivateKey != nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	shared, enc, err := s.encap(pkR, opts.SenderPrivateKey, rand)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, err := s.keySchedule(mode, shared, info, opts.PSK, opts.PSKID)
+	return enc, ctx, err
+}
+
+// Sets up receiver's context for encapsulated key `enc`
+// and private key `skR`, options must match the sender's
+// (with sender's public key instead of the private one)
+func (s *HPKESuite) SetupReceiver(enc, skR, info []byte, opts *HPKEOptions) (*HPKEContext, error) {
+	if opts == nil {
+		opts = &HPKEOptions{}
+	}
+	mode, err := hpkeMode(opts, opts.SenderPublicKey != nil)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := s.decap(enc, skR, opts.SenderPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return s.keySchedule(mode, shared, info, opts.PSK, opts.PSKID)
+}
+
+// HPKE encryption context, it keeps the sequence number,
+// so messages must be opened in the order they were sealed
+type HPKEContext struct {
+	suite          *HPKESuite
+	aead           cipher.AEAD
+	baseNonce      []byte
+	seq            uint64
+	exporterSecret []byte
+}
+
+// KeySchedule of RFC 9180, 5.1
+func (s *HPKESuite) keySchedule(mode byte, shared, info, psk, pskID []byte) (*HPKEContext, error) {
+	id := s.suiteID()
+	pskIDHash, err := labeledExtract(s.kdfHash, id, nil, "psk_id_hash", pskID)
+	if err != nil {
+		return nil, err
+	}
+	infoHash, err := labeledExtract(s.kdfHash, id, nil, "info_hash", info)
+	if err != nil {
+		return nil, err
+	}
+	ksContext := append(append([]byte{mode}, pskIDHash...), infoHash...)
+	secret, err := labeledExtract(s.kdfHash, id, shared, "secret", psk)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := &HPKEContext{suite: s}
+	ctx.exporterSecret, err = labeledExpand(s.kdfHash, id, secret, "exp", ksContext, s.kdfHash().Size())
+	if err != nil {
+		return nil, err
+	}
+	if s.AEAD == HPKEExportOnly {
+		return ctx, nil
+	}
+
+	key, err := labeledExpand(s.kdfHash, id, secret, "key", ksContext, s.nK)
+	if err != nil {
+		return nil, err
+	}
+	ctx.baseNonce, err = labeledExpand(s.kdfHash, id, secret, "base_nonce", ksContext, s.nN)
+	if err != nil {
+		return nil, err
+	}
+	switch s.AEAD {
+	case HPKEAES128GCM, HPKEAES256GCM:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		ctx.aead, err = cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+	case HPKEChaCha20Poly1305:
+		if s.NewChaCha20Poly1305 != nil {
+			ctx.aead, err = s.NewChaCha20Poly1305(key)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return ctx, nil
+}
+
+// base_nonce xor I2OSP(seq, Nn)
+func (ctx *HPKEContext) nonce() []byte {
+	nonce := append([]byte(nil), ctx.baseNonce...)
+	var seq [8]byte
+	binary.BigEndian.PutUint64(seq[:], ctx.seq)
+	for i := range seq {
+		nonce[len(nonce)-8+i] ^= seq[i]
+	}
+	return nonce
+}
+
+func (ctx *HPKEContext) checkAEAD() error {
+	if ctx.aead == nil {
+		return errors.New("context has no AEAD, it can only export secrets")
+	}
+	if ctx.seq == ^uint64(0) {
+		return errors.New("message limit reached")
+	}
+	return nil
+}
+
+// Encrypts plaintext `pt` with additional data `aad`
+func (ctx *HPKEContext) Seal(aad, pt []byte) ([]byte, error) {
+	if err := ctx.checkAEAD(); err != nil {
+		return nil, err
+	}
+	ct := ctx.aead.Seal(nil, ctx.nonce(), pt, aad)
+	ctx.seq++
+	return ct, nil
+}
+
+// Decrypts ciphertext `ct` with additional data `aad`
+func (ctx *HPKEContext) Open(aad, ct []byte) ([]byte, error) {
+	if err := ctx.checkAEAD(); err != nil {
+		return nil, err
+	}
+	pt, err := ctx.aead.Open(nil, ctx.nonce(), ct, aad)
+	if err != nil {
+		return nil, err
+	}
+	ctx.seq++
+	return pt, nil
+}
+
+// Exports `length` bytes of secret bound to `exporterContext`
+// (RFC 9180, 5.3), the same for both sides
+func (ctx *HPKEContext) Export(exporterContext []byte, length int) ([]byte, error) {
+	s := ctx.suite
+	if length > 255*s.kdfHash().Size() {
+		return nil, errors.New("export length is too large")
+	}
+	return labeledExpand(s.kdfHash, s.suiteID(), ctx.exporterSecret, "sec", exporterContext, length)
+}
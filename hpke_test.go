@@ -0,0 +1,163 @@
+package ECwrap
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+)
+
+type hexBytes []byte
+
+func (h *hexBytes) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	*h = decodeHex(s)
+	return nil
+}
+
+// testdata/rfc9180-test-vectors.json is the official test-vectors.json
+// of RFC 9180 with only the first two encryptions of each vector kept
+// (the full file is ~6MB, it has 257 of them each)
+type hpkeVector struct {
+	Mode    byte     `json:"mode"`
+	KEM     HPKEKEM  `json:"kem_id"`
+	KDF     HPKEKDF  `json:"kdf_id"`
+	AEAD    HPKEAEAD `json:"aead_id"`
+	Info    hexBytes `json:"info"`
+	IkmR    hexBytes `json:"ikmR"`
+	IkmS    hexBytes `json:"ikmS"`
+	IkmE    hexBytes `json:"ikmE"`
+	SkRm    hexBytes `json:"skRm"`
+	PkRm    hexBytes `json:"pkRm"`
+	PkSm    hexBytes `json:"pkSm"`
+	PSK     hexBytes `json:"psk"`
+	PSKID   hexBytes `json:"psk_id"`
+	Enc     hexBytes `json:"enc"`
+	Encrypt []struct {
+		AAD hexBytes `json:"aad"`
+		CT  hexBytes `json:"ct"`
+		PT  hexBytes `json:"pt"`
+	} `json:"encryptions"`
+	Exports []struct {
+		Context hexBytes `json:"exporter_context"`
+		L       int      `json:"L"`
+		Value   hexBytes `json:"exported_value"`
+	} `json:"exports"`
+}
+
+func TestHPKEVectors(t *testing.T) {
+	data, err := os.ReadFile("testdata/rfc9180-test-vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vectors []hpkeVector
+	if err := json.Unmarshal(data, &vectors); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, v := range vectors {
+		name := fmt.Sprintf("%d/mode=%d,kem=%#x,kdf=%d,aead=%#x", i, v.Mode, v.KEM, v.KDF, v.AEAD)
+		t.Run(name, func(t *testing.T) {
+			s, err := NewHPKESuite(v.KEM, v.KDF, v.AEAD)
+			if err != nil {
+				t.Fatal(err)
+			}
+			skR, pkR, err := s.DeriveKeyPair(v.IkmR)
+			if err != nil || !bytes.Equal(skR, v.SkRm) || !bytes.Equal(pkR, v.PkRm) {
+				t.Fatalf(`DeriveKeyPair() = %x, %x, expected = %x, %x, err = %v`, skR, pkR, v.SkRm, v.PkRm, err)
+			}
+
+			sOpts := &HPKEOptions{PSK: v.PSK, PSKID: v.PSKID}
+			rOpts := &HPKEOptions{PSK: v.PSK, PSKID: v.PSKID}
+			if v.IkmS != nil {
+				skS, pkS, err := s.DeriveKeyPair(v.IkmS)
+				if err != nil || !bytes.Equal(pkS, v.PkSm) {
+					t.Fatalf(`DeriveKeyPair(ikmS) = %x, expected = %x, err = %v`, pkS, v.PkSm, err)
+				}
+				sOpts.SenderPrivateKey = skS
+				rOpts.SenderPublicKey = pkS
+			}
+
+			// ephemeral key is derived from the Nsk bytes read from rand
+			enc, sender, err := s.SetupSender(pkR, v.Info, sOpts, bytes.NewReader(v.IkmE))
+			if err != nil || !bytes.Equal(enc, v.Enc) {
+				t.Fatalf(`SetupSender() enc = %x, expected = %x, err = %v`, enc, v.Enc, err)
+			}
+			receiver, err := s.SetupReceiver(enc, skR, v.Info, rOpts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if v.AEAD != HPKEChaCha20Poly1305 {
+				for j, e := range v.Encrypt {
+					ct, err := sender.Seal(e.AAD, e.PT)
+					if err != nil || !bytes.Equal(ct, e.CT) {
+						t.Fatalf(`encryption %d: Seal() = %x, expected = %x, err = %v`, j, ct, e.CT, err)
+					}
+					pt, err := receiver.Open(e.AAD, e.CT)
+					if err != nil || !bytes.Equal(pt, e.PT) {
+						t.Fatalf(`encryption %d: Open() = %x, expected = %x, err = %v`, j, pt, e.PT, err)
+					}
+				}
+			}
+			for j, e := range v.Exports {
+				for _, ctx := range []*HPKEContext{sender, receiver} {
+					got, err := ctx.Export(e.Context, e.L)
+					if err != nil || !bytes.Equal(got, e.Value) {
+						t.Fatalf(`export %d: Export() = %x, expected = %x, err = %v`, j, got, e.Value, err)
+					}
+				}
+			}
+		})
+	}
+}
+
+// DHKEM(P-384) has no vectors in RFC 9180
+func TestHPKEP384(t *testing.T) {
+	s, err := NewHPKESuite(HPKEDHKEMP384, HPKEHKDFSHA384, HPKEAES256GCM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	skR, pkR, _ := s.GenerateKeyPair(rand.Reader)
+	skS, pkS, _ := s.GenerateKeyPair(rand.Reader)
+	opts := []struct{ sender, receiver *HPKEOptions }{
+		{nil, nil},
+		{&HPKEOptions{PSK: []byte("psk"), PSKID: []byte("id")}, &HPKEOptions{PSK: []byte("psk"), PSKID: []byte("id")}},
+		{&HPKEOptions{SenderPrivateKey: skS}, &HPKEOptions{SenderPublicKey: pkS}},
+	}
+	for i, o := range opts {
+		enc, sender, err := s.SetupSender(pkR, []byte("info"), o.sender, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		receiver, err := s.SetupReceiver(enc, skR, []byte("info"), o.receiver)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; j < 3; j++ {
+			msg := []byte(fmt.Sprintf("message %d", j))
+			ct, _ := sender.Seal([]byte("aad"), msg)
+			pt, err := receiver.Open([]byte("aad"), ct)
+			if err != nil || !bytes.Equal(pt, msg) {
+				t.Fatalf(`options %d message %d: Open() = %q, err = %v`, i, j, pt, err)
+			}
+		}
+
+		// wrong mode on the receiver side
+		if wrong, err := s.SetupReceiver(enc, skR, []byte("info"), nil); err == nil && i > 0 {
+			ct, _ := sender.Seal(nil, []byte("x"))
+			if _, err := wrong.Open(nil, ct); err == nil {
+				t.Fatalf(`options %d: message opened in base mode`, i)
+			}
+		}
+	}
+
+	if _, _, err := s.SetupSender(pkR, nil, &HPKEOptions{PSK: []byte("psk")}, rand.Reader); err == nil {
+		t.Fatalf(`PSK without PSK ID accepted`)
+	}
+}
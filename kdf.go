@@ -0,0 +1,66 @@
+package ECwrap
+
+import (
+	"crypto/hkdf"
+	"encoding/binary"
+	"errors"
+	"hash"
+)
+
+// Key derivation functions turning ECDH shared secret Z
+// into the keys of the required length
+
+// ANSI X9.63 KDF (SEC 1, section 3.6.1), output is
+//
+//	H(Z || 00000001 || SharedInfo) || H(Z || 00000002 || SharedInfo) || ...
+//
+// truncated to `length` bytes
+func X963KDF(h func() hash.Hash, z, sharedInfo []byte, length int) ([]byte, error) {
+	return counterKDF(h, length, func(md hash.Hash, counter []byte) {
+		md.Write(z)
+		md.Write(counter)
+		md.Write(sharedInfo)
+	})
+}
+
+// NIST SP 800-56C one-step KDF with hash function (also known
+// as concatenation KDF, used by JOSE ECDH-ES), output is
+//
+//	H(00000001 || Z || OtherInfo) || H(00000002 || Z || OtherInfo) || ...
+//
+// truncated to `length` bytes
+func ConcatKDF(h func() hash.Hash, z, otherInfo []byte, length int) ([]byte, error) {
+	return counterKDF(h, length, func(md hash.Hash, counter []byte) {
+		md.Write(counter)
+		md.Write(z)
+		md.Write(otherInfo)
+	})
+}
+
+// HKDF (RFC 5869), extract with `salt` and expand with `info`
+// to `length` bytes. Thin wrapper over `crypto/hkdf`, so that
+// all the KDFs have the same shape
+func HKDF(h func() hash.Hash, z, salt, info []byte, length int) ([]byte, error) {
+	return hkdf.Key(h, z, salt, string(info), length)
+}
+
+// Hashes counter = 1, 2, ... (32-bit big-endian) with
+// `write` and concatenates the results
+func counterKDF(h func() hash.Hash, length int, write func(md hash.Hash, counter []byte)) ([]byte, error) {
+	if length < 0 {
+		return nil, errors.New("negative key length")
+	}
+	md := h()
+	if uint64(length) > uint64(md.Size())*(1<<32-1) {
+		return nil, errors.New("key length is too large")
+	}
+	out := make([]byte, 0, length+md.Size())
+	counter := make([]byte, 4)
+	for i := uint32(1); len(out) < length; i++ {
+		binary.BigEndian.PutUint32(counter, i)
+		md.Reset()
+		write(md, counter)
+		out = md.Sum(out)
+	}
+	return out[:length], nil
+}
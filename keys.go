@@ -0,0 +1,140 @@
+package ECwrap
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"math/big"
+)
+
+// Key pair over Curve. PrivateKey implements crypto.Signer
+// (ECDSA, DER encoded signatures) and crypto.Decrypter
+// (ECIES), so it plugs into `crypto/x509`, `crypto/tls`
+// and the like
+
+// Private key d with cached public key Q = d*G
+type PrivateKey struct {
+	Curve *Curve
+	D     *Scalar
+	// affine
+	Q *ECPoint
+}
+
+// Public key Q. `PrivateKey.Public` returns it for the
+// curves `crypto/ecdsa` does not know
+type PublicKey struct {
+	Curve *Curve
+	Q     *ECPoint
+}
+
+// Returns key pair for private key `d`
+func NewPrivateKey(c *Curve, d *Scalar) (*PrivateKey, error) {
+	if _, err := c.privateKey(d); err != nil {
+		return nil, err
+	}
+	return &PrivateKey{Curve: c, D: d, Q: c.ECPAffine(c.ECPBaseMul(d))}, nil
+}
+
+// Generates key pair, d is uniform in [1, n - 1]
+func GenerateKey(c *Curve, rand io.Reader) (*PrivateKey, error) {
+	d, err := c.RandomScalar(rand)
+	if err != nil {
+		return nil, err
+	}
+	return NewPrivateKey(c, d)
+}
+
+// Converts `crypto/ecdsa` private key
+func PrivateKeyFromECDSA(priv *ecdsa.PrivateKey) (*PrivateKey, error) {
+	c := FromElliptic(priv.Curve)
+	if priv.D.Sign() <= 0 || priv.D.Cmp(c.N) >= 0 {
+		return nil, errors.New("private key is out of range [1, n - 1]")
+	}
+	return NewPrivateKey(c, c.NewScalar(priv.D))
+}
+
+// Returns public key of k
+func (k *PrivateKey) PublicKey() *PublicKey {
+	return &PublicKey{Curve: k.Curve, Q: k.Q.Copy()}
+}
+
+// Implements crypto.Signer. For the NIST curves the result is
+// *ecdsa.PublicKey, so `crypto/x509` accepts it, for the
+// rest it is *PublicKey
+func (k *PrivateKey) Public() crypto.PublicKey {
+	if ec := k.Curve.stdCurve(); ec != nil {
+		return &ecdsa.PublicKey{Curve: ec, X: new(big.Int).Set(k.Q.X), Y: new(big.Int).Set(k.Q.Y)}
+	}
+	return k.PublicKey()
+}
+
+// Implements crypto.Signer: signs already hashed message
+// `digest` with ECDSA, the signature is ASN.1 DER. Nonce is
+// derived with RFC 6979 using HMAC over opts.HashFunc()
+// (SHA-256 if there is none), `rand` is not used
+func (k *PrivateKey) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	h := crypto.SHA256
+	if opts != nil && opts.HashFunc() != 0 {
+		h = opts.HashFunc()
+		if len(digest) != h.Size() {
+			return nil, errors.New("digest length does not match the hash function")
+		}
+	}
+	hf := sha256.New
+	if h.Available() {
+		hf = h.New
+	}
+	sig, err := ECDSASign(k.Curve, k.D, digest, hf)
+	if err != nil {
+		return nil, err
+	}
+	return sig.MarshalDER()
+}
+
+// Implements crypto.Decrypter: decrypts ECIES ciphertext
+// `msg`. `opts` is *ECIESParams or nil for the defaults,
+// `rand` is not used
+func (k *PrivateKey) Decrypt(rand io.Reader, msg []byte, opts crypto.DecrypterOpts) ([]byte, error) {
+	var params *ECIESParams
+	switch o := opts.(type) {
+	case nil:
+	case *ECIESParams:
+		params = o
+	default:
+		return nil, errors.New("decrypter options must be *ECIESParams")
+	}
+	return ECIESDecrypt(k.Curve, k.D, msg, params)
+}
+
+// Verifies ASN.1 DER ECDSA signature of already hashed
+// message `digest`
+func (pub *PublicKey) VerifyASN1(digest, sig []byte) bool {
+	s, err := ParseECDSASignatureDER(sig)
+	if err != nil {
+		return false
+	}
+	return ECDSAVerify(pub.Curve, pub.Q, digest, s)
+}
+
+// Encrypts `msg` with ECIES, `params` may be nil
+// for the defaults
+func (pub *PublicKey) Encrypt(msg []byte, params *ECIESParams, rand io.Reader) ([]byte, error) {
+	return ECIESEncrypt(pub.Curve, pub.Q, msg, params, rand)
+}
+
+// Returns the `crypto/elliptic` curve c is the same as,
+// nil if there is none
+func (c *Curve) stdCurve() elliptic.Curve {
+	for _, ec := range []elliptic.Curve{elliptic.P224(), elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		p := ec.Params()
+		a := new(big.Int).Sub(p.P, big.NewInt(3))
+		if c.P.Cmp(p.P) == 0 && c.N.Cmp(p.N) == 0 && c.A.Cmp(a) == 0 && c.B.Cmp(p.B) == 0 &&
+			c.Gx.Cmp(p.Gx) == 0 && c.Gy.Cmp(p.Gy) == 0 {
+			return ec
+		}
+	}
+	return nil
+}
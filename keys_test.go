@@ -0,0 +1,109 @@
+package ECwrap
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// Self-signed certificate made by `crypto/x509` with our key
+func TestPrivateKeyX509(t *testing.T) {
+	for _, ec := range []elliptic.Curve{elliptic.P256(), elliptic.P384()} {
+		k, err := GenerateKey(FromElliptic(ec), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := k.Public().(*ecdsa.PublicKey); !ok {
+			t.Fatalf(`%s: Public() = %T, expected = *ecdsa.PublicKey`, ec.Params().Name, k.Public())
+		}
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: "ECwrap"},
+			NotBefore:    time.Now(),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageCertSign,
+
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, k.Public(), k)
+		if err != nil {
+			t.Fatalf(`%s: CreateCertificate() error = %v`, ec.Params().Name, err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cert.CheckSignatureFrom(cert); err != nil {
+			t.Fatalf(`%s: CheckSignatureFrom() error = %v`, ec.Params().Name, err)
+		}
+	}
+}
+
+func TestPrivateKeySigner(t *testing.T) {
+	// curve `crypto/ecdsa` does not know
+	k, _ := GenerateKey(Secp256k1(), rand.Reader)
+	var signer crypto.Signer = k
+	pub, ok := signer.Public().(*PublicKey)
+	if !ok {
+		t.Fatalf(`Public() = %T, expected = *PublicKey`, signer.Public())
+	}
+	digest := sha512.Sum384([]byte("ECwrap"))
+	sig, err := signer.Sign(nil, digest[:], crypto.SHA384)
+	if err != nil || !pub.VerifyASN1(digest[:], sig) {
+		t.Fatalf(`Sign() = %x, err = %v`, sig, err)
+	}
+	if _, err := signer.Sign(nil, digest[:], crypto.SHA256); err == nil {
+		t.Fatalf(`Sign() accepted digest of wrong length`)
+	}
+
+	// and the other way around, key of `crypto/ecdsa`
+	priv, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	k, err = PrivateKeyFromECDSA(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := sha256.Sum256([]byte("ECwrap"))
+	sig, _ = k.Sign(rand.Reader, h[:], crypto.SHA256)
+	if !ecdsa.VerifyASN1(&priv.PublicKey, h[:], sig) {
+		t.Fatalf(`ecdsa.VerifyASN1() rejected Sign() signature`)
+	}
+	for _, d := range []*big.Int{big.NewInt(0), big.NewInt(-1), priv.Curve.Params().N} {
+		bad := &ecdsa.PrivateKey{PublicKey: priv.PublicKey, D: d}
+		if _, err := PrivateKeyFromECDSA(bad); err == nil || err.Error() != "private key is out of range [1, n - 1]" {
+			t.Fatalf(`PrivateKeyFromECDSA(d = %v) error = %v, expected out of range`, d, err)
+		}
+	}
+}
+
+func TestPrivateKeyDecrypter(t *testing.T) {
+	k, _ := GenerateKey(FromElliptic(elliptic.P256()), rand.Reader)
+	var dec crypto.Decrypter = k
+	msg := []byte("ECwrap")
+	for _, params := range []*ECIESParams{nil, {Mode: ECIESAESCTRHMAC, EphemeralInKDF: true}} {
+		ct, err := k.PublicKey().Encrypt(msg, params, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var opts crypto.DecrypterOpts
+		if params != nil {
+			opts = params
+		}
+		got, err := dec.Decrypt(nil, ct, opts)
+		if err != nil || !bytes.Equal(got, msg) {
+			t.Fatalf(`Decrypt(%+v) = %q, expected = %q, err = %v`, params, got, msg, err)
+		}
+	}
+	if _, err := dec.Decrypt(nil, nil, crypto.SHA256); err == nil {
+		t.Fatalf(`Decrypt() accepted options of wrong type`)
+	}
+}
@@ -0,0 +1,250 @@
+package ECwrap
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+)
+
+// Montgomery curve B*y^(2) = x^(3) + A*x^(2) + x over F_p
+//
+// Its main feature is the x-only ladder (see `Ladder`), which
+// computes u(k*P) from u(P) alone. Full points (u, v) are kept
+// in ECPoint with Z = 1 (and Z = 0 for the point at infinity),
+// they are needed only to move between this form and the short
+// Weierstrass one
+type MontgomeryCurve struct {
+	Name string
+
+	// order of the underlying field
+	P *big.Int
+	A *big.Int
+	B *big.Int
+	// order of the base point
+	N *big.Int
+	// cofactor, #E(F_p) = N * H
+	H *big.Int
+
+	// base point
+	Gu *big.Int
+	Gv *big.Int
+}
+
+// Returns new MontgomeryCurve with provided parameters. Checks
+// that B != 0, A^(2) != 4 and the base point lies on the curve
+func NewMontgomeryCurve(name string, p, a, b, gu, gv, n, h *big.Int) (*MontgomeryCurve, error) {
+	if p == nil || a == nil || b == nil || gu == nil || gv == nil || n == nil || h == nil {
+		return nil, errors.New("curve parameter is nil")
+	}
+	c := &MontgomeryCurve{
+		Name: name,
+		P:    new(big.Int).Set(p),
+		A:    new(big.Int).Mod(a, p),
+		B:    new(big.Int).Mod(b, p),
+		N:    new(big.Int).Set(n),
+		H:    new(big.Int).Set(h),
+		Gu:   new(big.Int).Set(gu),
+		Gv:   new(big.Int).Set(gv),
+	}
+	// B*(A^(2) - 4) != 0
+	disc := new(big.Int).Mul(c.B, new(big.Int).Sub(new(big.Int).Mul(c.A, c.A), big.NewInt(4)))
+	if disc.Mod(disc, c.P).Sign() == 0 {
+		return nil, errors.New("curve is singular")
+	}
+	if !c.IsOnCurve(c.Gu, c.Gv) {
+		return nil, errors.New("base point is not on curve")
+	}
+	return c, nil
+}
+
+func mustMontgomeryCurve(name string, p, a, b, gu, gv, n, h *big.Int) *MontgomeryCurve {
+	c, err := NewMontgomeryCurve(name, p, a, b, gu, gv, n, h)
+	if err != nil {
+		panic("ECwrap: bad parameters for " + name + ": " + err.Error())
+	}
+	return c
+}
+
+var initCurve25519, initCurve448 sync.Once
+var curve25519, curve448 *MontgomeryCurve
+
+// Returns curve25519, y^(2) = x^(3) + 486662*x^(2) + x over
+// 2^(255) - 19, the curve of X25519 (RFC 7748, 4.1)
+func Curve25519() *MontgomeryCurve {
+	initCurve25519.Do(func() {
+		ed := Edwards25519()
+		curve25519 = mustMontgomeryCurve("curve25519",
+			ed.P, big.NewInt(486662), big.NewInt(1),
+			big.NewInt(9),
+			mustDec("14781619447589544791020593568409986887264606134616475288964881837755586237401"),
+			ed.N, ed.H,
+		)
+	})
+	return curve25519
+}
+
+// Returns curve448, y^(2) = x^(3) + 156326*x^(2) + x over
+// 2^(448) - 2^(224) - 1, the curve of X448 (RFC 7748, 4.2)
+func Curve448() *MontgomeryCurve {
+	initCurve448.Do(func() {
+		ed := Edwards448()
+		curve448 = mustMontgomeryCurve("curve448",
+			ed.P, big.NewInt(156326), big.NewInt(1),
+			big.NewInt(5),
+			mustDec("355293926785568175264127502063783334808976399387714271831880898435169088786967410002932673765864550910142774147268105838985595290606362"),
+			ed.N, ed.H,
+		)
+	})
+	return curve448
+}
+
+// Reports whether affine (u, v) lies on the curve
+func (c *MontgomeryCurve) IsOnCurve(u, v *big.Int) bool {
+	if u.Sign() < 0 || u.Cmp(c.P) >= 0 ||
+		v.Sign() < 0 || v.Cmp(c.P) >= 0 {
+		return false
+	}
+	// B*v^(2)
+	left := new(big.Int).Mul(c.B, new(big.Int).Mul(v, v))
+	left.Mod(left, c.P)
+	// u^(3) + A*u^(2) + u = ((u + A)*u + 1)*u
+	right := new(big.Int).Add(new(big.Int).Mul(new(big.Int).Add(u, c.A), u), big.NewInt(1))
+	right.Mul(right, u)
+	right.Mod(right, c.P)
+	return left.Cmp(right) == 0
+}
+
+// Returns the base point as ECPoint (u, v, 1)
+func (c *MontgomeryCurve) Generator() *ECPoint {
+	G := new(ECPoint)
+	G.SetCoords(new(big.Int).Set(c.Gu), new(big.Int).Set(c.Gv), big.NewInt(1))
+	return G
+}
+
+// Montgomery ladder (RFC 7748, section 5), returns u(k*P)
+// for any point P with u(P) = u, k >= 0. The point at infinity
+// comes out as 0, same as in RFC 7748
+//
+// Only u is used, so P and -P (and, with the twist, points
+// that are not on the curve at all) give the same result.
+// The ladder does the same sequence of operations for every
+// bit, but big.Int arithmetic is not constant time anyway
+func (c *MontgomeryCurve) Ladder(k, u *big.Int) *big.Int {
+	p := c.P
+	// a24 = (A - 2) / 4
+	a24 := new(big.Int).Mul(
+		new(big.Int).Sub(c.A, big.NewInt(2)),
+		new(big.Int).ModInverse(big.NewInt(4), p),
+	)
+	a24.Mod(a24, p)
+
+	x1 := new(big.Int).Mod(u, p)
+	x2, z2 := big.NewInt(1), big.NewInt(0)
+	x3, z3 := new(big.Int).Set(x1), big.NewInt(1)
+	swap := uint(0)
+
+	for t := k.BitLen() - 1; t >= 0; t-- {
+		kt := k.Bit(t)
+		if swap^kt == 1 {
+			x2, x3 = x3, x2
+			z2, z3 = z3, z2
+		}
+		swap = kt
+
+		A := new(big.Int).Add(x2, z2)
+		AA := new(big.Int).Mod(new(big.Int).Mul(A, A), p)
+		B := new(big.Int).Sub(x2, z2)
+		BB := new(big.Int).Mod(new(big.Int).Mul(B, B), p)
+		E := new(big.Int).Sub(AA, BB)
+		C := new(big.Int).Add(x3, z3)
+		D := new(big.Int).Sub(x3, z3)
+		DA := new(big.Int).Mul(D, A)
+		CB := new(big.Int).Mul(C, B)
+
+		// x3 = (DA + CB)^(2), z3 = x1 * (DA - CB)^(2)
+		x3 = new(big.Int).Add(DA, CB)
+		x3.Mod(x3.Mul(x3, x3), p)
+		z3 = new(big.Int).Sub(DA, CB)
+		z3.Mod(z3.Mul(z3, z3), p)
+		z3.Mod(z3.Mul(z3, x1), p)
+		// x2 = AA * BB, z2 = E * (AA + a24 * E)
+		x2 = new(big.Int).Mod(new(big.Int).Mul(AA, BB), p)
+		z2 = new(big.Int).Add(AA, new(big.Int).Mul(a24, E))
+		z2.Mod(z2.Mul(z2, E), p)
+	}
+	if swap == 1 {
+		x2, z2 = x3, z3
+	}
+
+	// x2 * z2^(p - 2), which is 0 for z2 = 0
+	zInv := new(big.Int).Exp(z2, new(big.Int).Sub(p, big.NewInt(2)), p)
+	return zInv.Mod(zInv.Mul(zInv, x2), p)
+}
+
+// Returns the same curve in short Weierstrass form, with
+//
+//	a = (3 - A^(2)) / (3*B^(2)), b = (2*A^(3) - 9*A) / (27*B^(3))
+//
+// Base point, order and cofactor are carried over, see
+// `ToWeierstrass` for the map itself
+func (c *MontgomeryCurve) Weierstrass() (*Curve, error) {
+	p := c.P
+	B2 := new(big.Int).Mul(c.B, c.B)
+	B3 := new(big.Int).Mul(B2, c.B)
+	A2 := new(big.Int).Mul(c.A, c.A)
+
+	a := new(big.Int).Mul(
+		new(big.Int).Sub(big.NewInt(3), A2),
+		new(big.Int).ModInverse(new(big.Int).Mul(big.NewInt(3), B2), p),
+	)
+	b := new(big.Int).Mul(
+		new(big.Int).Sub(
+			new(big.Int).Mul(big.NewInt(2), new(big.Int).Mul(A2, c.A)),
+			new(big.Int).Mul(big.NewInt(9), c.A),
+		),
+		new(big.Int).ModInverse(new(big.Int).Mul(big.NewInt(27), B3), p),
+	)
+	G := c.ToWeierstrass(c.Generator())
+	return NewCurve(c.Name+" (Weierstrass form)",
+		p, a.Mod(a, p), b.Mod(b, p), G.X, G.Y, c.N, c.H,
+	)
+}
+
+// Maps point (u, v) of the curve to the point
+// (u/B + A/(3*B), v/B) of `Weierstrass` curve.
+// Point at infinity is mapped to itself
+func (c *MontgomeryCurve) ToWeierstrass(P *ECPoint) *ECPoint {
+	if P.IsInfinity() {
+		return PointAtInfinity()
+	}
+	p := c.P
+	bInv := new(big.Int).ModInverse(c.B, p)
+	// A/(3*B)
+	shift := new(big.Int).Mul(c.A, new(big.Int).ModInverse(new(big.Int).Mul(big.NewInt(3), c.B), p))
+
+	x := new(big.Int).Add(new(big.Int).Mul(P.X, bInv), shift)
+	y := new(big.Int).Mul(P.Y, bInv)
+	Q := new(ECPoint)
+	Q.SetCoords(x.Mod(x, p), y.Mod(y, p), big.NewInt(1))
+	return Q
+}
+
+// Maps point of `Weierstrass` curve back to (u, v),
+// u = B*x - A/3, v = B*y. Point at infinity is mapped
+// to itself. P can be in Jacobian coordinates
+func (c *MontgomeryCurve) FromWeierstrass(P *ECPoint) *ECPoint {
+	if P.IsInfinity() {
+		return PointAtInfinity()
+	}
+	p := c.P
+	A := P.Copy()
+	A.ECPNormalize(p)
+	// A/3
+	shift := new(big.Int).Mul(c.A, new(big.Int).ModInverse(big.NewInt(3), p))
+
+	u := new(big.Int).Sub(new(big.Int).Mul(c.B, A.X), shift)
+	v := new(big.Int).Mul(c.B, A.Y)
+	Q := new(ECPoint)
+	Q.SetCoords(u.Mod(u, p), v.Mod(v, p), big.NewInt(1))
+	return Q
+}
@@ -0,0 +1,157 @@
+package ECwrap
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRFC7748(t *testing.T) {
+	f, err := os.Open("testdata/rfc7748-vectors.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		if strings.HasPrefix(s.Text(), "#") {
+			continue
+		}
+		v := strings.Split(s.Text(), "|")
+		x := X25519()
+		if strings.HasPrefix(v[0], "X448") {
+			x = X448()
+		}
+
+		if !strings.HasSuffix(v[0], "-iter") {
+			got, err := x.ScalarMult(decodeHex(v[1]), decodeHex(v[2]))
+			if err != nil || !bytes.Equal(got, decodeHex(v[3])) {
+				t.Errorf(`line %d %s ScalarMult() = %x, expected = %s, err = %v`, n, v[0], got, v[3], err)
+			}
+			continue
+		}
+
+		// k = u = base point, then k, u = f(k, u), k
+		times, _ := strconv.Atoi(v[1])
+		if times > 1000 {
+			// takes hours with big.Int arithmetic
+			continue
+		}
+		k := leBytes(x.Curve.Gu, x.Size())
+		u := k
+		for i := 0; i < times; i++ {
+			r, err := x.ScalarMult(k, u)
+			if err != nil {
+				t.Fatalf(`%s iteration %d: %v`, v[0], i, err)
+			}
+			k, u = r, k
+		}
+		if !bytes.Equal(k, decodeHex(v[2])) {
+			t.Errorf(`%s after %d iterations = %x, expected = %s`, v[0], times, k, v[2])
+		}
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestX25519MatchesStd(t *testing.T) {
+	for i := 0; i < 4; i++ {
+		a, _ := ecdh.X25519().GenerateKey(rand.Reader)
+		b, _ := ecdh.X25519().GenerateKey(rand.Reader)
+
+		pub, err := X25519().ScalarBaseMult(a.Bytes())
+		if err != nil || !bytes.Equal(pub, a.PublicKey().Bytes()) {
+			t.Fatalf(`ScalarBaseMult() = %x, expected = %x, err = %v`, pub, a.PublicKey().Bytes(), err)
+		}
+		got, err := X25519().ScalarMult(a.Bytes(), b.PublicKey().Bytes())
+		expected, _ := a.ECDH(b.PublicKey())
+		if err != nil || !bytes.Equal(got, expected) {
+			t.Fatalf(`ScalarMult() = %x, expected = %x, err = %v`, got, expected, err)
+		}
+	}
+
+	// u = 0 and u = 1 are of small order
+	for _, u := range []byte{0, 1} {
+		low := make([]byte, 32)
+		low[0] = u
+		if _, err := X25519().ScalarMult(make([]byte, 32), low); err == nil {
+			t.Errorf(`ScalarMult(u = %d) did not fail`, u)
+		}
+	}
+}
+
+func TestMontgomeryWeierstrass(t *testing.T) {
+	for _, c := range []*MontgomeryCurve{Curve25519(), Curve448()} {
+		t.Run(c.Name, func(t *testing.T) {
+			w, err := c.Weierstrass()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !w.ECPScalarMul(w.Generator(), w.N).IsInfinity() {
+				t.Fatalf(`N*G != infinity on Weierstrass form`)
+			}
+
+			k, _ := rand.Int(rand.Reader, c.N)
+			P := w.ECPScalarMul(w.Generator(), k)
+			M := c.FromWeierstrass(P)
+			if !c.IsOnCurve(M.X, M.Y) {
+				t.Fatalf(`FromWeierstrass(k*G) is not on curve`)
+			}
+			// ladder agrees with the Weierstrass arithmetic
+			if u := c.Ladder(k, c.Gu); u.Cmp(M.X) != 0 {
+				t.Fatalf(`Ladder() = %v, expected = %v`, u, M.X)
+			}
+			if !w.ECPEqual(c.ToWeierstrass(M), P) {
+				t.Fatalf(`ToWeierstrass(FromWeierstrass(P)) != P`)
+			}
+			if c.Ladder(c.N, c.Gu).Sign() != 0 {
+				t.Fatalf(`Ladder(N, u(G)) != 0`)
+			}
+			if !c.FromWeierstrass(PointAtInfinity()).IsInfinity() {
+				t.Fatalf(`infinity is not mapped to itself`)
+			}
+		})
+	}
+
+	// B != 1: 3*y^(2) = x^(3) + 5*x^(2) + x over F_101,
+	// ladder still works as it does not depend on B
+	p := big.NewInt(101)
+	var c *MontgomeryCurve
+	for u := int64(1); c == nil; u++ {
+		// v^(2) = (u^(3) + 5*u^(2) + u) / 3
+		rhs := big.NewInt(((u*u*u + 5*u*u + u) * 34) % 101)
+		if v := new(big.Int).ModSqrt(rhs, p); v != nil {
+			c, _ = NewMontgomeryCurve("toy", p, big.NewInt(5), big.NewInt(3),
+				big.NewInt(u), v, big.NewInt(1), big.NewInt(1))
+		}
+	}
+	w, err := c.Weierstrass()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for u := int64(1); u < 101; u++ {
+		x := c.ToWeierstrass(&ECPoint{big.NewInt(u), big.NewInt(0), big.NewInt(1)}).X
+		P, err := w.ECPFromX(x, false)
+		if err != nil {
+			continue
+		}
+		for k := int64(2); k < 10; k++ {
+			Q := w.ECPScalarMul(P, big.NewInt(k))
+			expected := big.NewInt(0)
+			if !Q.IsInfinity() {
+				expected = c.FromWeierstrass(Q).X
+			}
+			if got := c.Ladder(big.NewInt(k), big.NewInt(u)); got.Cmp(expected) != 0 {
+				t.Fatalf(`toy Ladder(%d, %d) = %v, expected = %v`, k, u, got, expected)
+			}
+		}
+	}
+}
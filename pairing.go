@@ -0,0 +1,437 @@
+package ECwrap
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/big"
+)
+
+// Pairings of points of order r on a curve over F_(q), q = p^(k).
+// Both are values of Miller's function f_(r,P), the function with
+// divisor r*(P) - r*(O), built up by double-and-add from the lines
+// of the additions. Weil pairing needs the whole E[r], so k is at
+// least the embedding degree (the order of p mod r) and often twice
+// that, Tate pairing needs only r | q - 1
+//
+// Over toy curves this is the MOV attack: the pairing carries the
+// discrete logarithm into F_(q)^(*), where it is subexponential, so
+// curves with small embedding degree (supersingular ones have k <= 2)
+// are weak. It is also where pairing based crypto starts
+
+// Curve y^(2) = x^(3) + a*x + b of a `Curve` over F_(p^k)
+type ExtCurve struct {
+	Curve *Curve
+	F     *ExtField
+
+	a, b *Poly
+}
+
+// Affine point of `ExtCurve`
+type ExtPoint struct {
+	X, Y *Poly
+	Inf  bool
+}
+
+func ExtPointAtInfinity() *ExtPoint {
+	return &ExtPoint{Inf: true}
+}
+
+// Returns the curve over F_(p^k), see `NewExtField`
+func (c *Curve) Extend(k int) (*ExtCurve, error) {
+	F, err := NewExtField(c.P, k)
+	if err != nil {
+		return nil, err
+	}
+	return c.ExtendTo(F), nil
+}
+
+// Returns the curve over F, which must be an extension of F_p
+func (c *Curve) ExtendTo(F *ExtField) *ExtCurve {
+	if F.P.Cmp(c.P) != 0 {
+		panic("ECwrap: field is not an extension of F_p")
+	}
+	return &ExtCurve{Curve: c, F: F, a: F.FromInt(c.A), b: F.FromInt(c.B)}
+}
+
+// Returns P as a point over F_(p^k)
+func (E *ExtCurve) Lift(P *ECPoint) *ExtPoint {
+	if P.IsInfinity() {
+		return ExtPointAtInfinity()
+	}
+	P = E.Curve.ECPAffine(P)
+	return &ExtPoint{X: E.F.FromInt(P.X), Y: E.F.FromInt(P.Y)}
+}
+
+// Returns x^(3) + a*x + b
+func (E *ExtCurve) Polynomial(x *Poly) *Poly {
+	F := E.F
+	return F.Add(F.Mul(F.Add(F.Mul(x, x), E.a), x), E.b)
+}
+
+func (E *ExtCurve) IsOnCurve(P *ExtPoint) bool {
+	return P.Inf || E.F.Equal(E.F.Mul(P.Y, P.Y), E.Polynomial(P.X))
+}
+
+func (E *ExtCurve) Equal(P, Q *ExtPoint) bool {
+	if P.Inf || Q.Inf {
+		return P.Inf == Q.Inf
+	}
+	return E.F.Equal(P.X, Q.X) && E.F.Equal(P.Y, Q.Y)
+}
+
+func (E *ExtCurve) Neg(P *ExtPoint) *ExtPoint {
+	if P.Inf {
+		return P
+	}
+	return &ExtPoint{X: P.X, Y: P.Y.Neg()}
+}
+
+// Returns slope of the line through P and Q (the tangent for
+// P = Q), nil if it is vertical. Neither is the point at infinity
+func (E *ExtCurve) slope(P, Q *ExtPoint) *Poly {
+	F := E.F
+	if !F.Equal(P.X, Q.X) {
+		return F.Div(F.Sub(Q.Y, P.Y), F.Sub(Q.X, P.X))
+	}
+	if !F.Equal(P.Y, Q.Y) || P.Y.IsZero() {
+		return nil
+	}
+	three := F.FromInt(big.NewInt(3))
+	return F.Div(F.Add(F.Mul(three, F.Mul(P.X, P.X)), E.a), F.Add(P.Y, P.Y))
+}
+
+// Returns the third point of the line with slope l through P
+// and Q, negated
+func (E *ExtCurve) addWithSlope(P, Q *ExtPoint, l *Poly) *ExtPoint {
+	F := E.F
+	x := F.Sub(F.Sub(F.Mul(l, l), P.X), Q.X)
+	y := F.Sub(F.Mul(l, F.Sub(P.X, x)), P.Y)
+	return &ExtPoint{X: x, Y: y}
+}
+
+func (E *ExtCurve) Add(P, Q *ExtPoint) *ExtPoint {
+	if P.Inf {
+		return Q
+	}
+	if Q.Inf {
+		return P
+	}
+	l := E.slope(P, Q)
+	if l == nil {
+		return ExtPointAtInfinity()
+	}
+	return E.addWithSlope(P, Q, l)
+}
+
+func (E *ExtCurve) Double(P *ExtPoint) *ExtPoint {
+	return E.Add(P, P)
+}
+
+// Returns k*P, double-and-add
+func (E *ExtCurve) ScalarMul(P *ExtPoint, k *big.Int) *ExtPoint {
+	if k.Sign() < 0 {
+		return E.ScalarMul(E.Neg(P), new(big.Int).Neg(k))
+	}
+	R := ExtPointAtInfinity()
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		R = E.Double(R)
+		if k.Bit(i) == 1 {
+			R = E.Add(R, P)
+		}
+	}
+	return R
+}
+
+// Returns (x^(p), y^(p))
+func (E *ExtCurve) Frobenius(P *ExtPoint) *ExtPoint {
+	if P.Inf {
+		return P
+	}
+	return &ExtPoint{X: E.F.Frobenius(P.X), Y: E.F.Frobenius(P.Y)}
+}
+
+// Returns random affine point
+func (E *ExtCurve) RandPoint(rand io.Reader) (*ExtPoint, error) {
+	for {
+		x, err := E.F.Rand(rand)
+		if err != nil {
+			return nil, err
+		}
+		y := E.F.Sqrt(E.Polynomial(x))
+		if y == nil {
+			continue
+		}
+		return &ExtPoint{X: x, Y: y}, nil
+	}
+}
+
+// Returns #E(F_(p^k)) = p^(k) + 1 - t_k, where t_1 is the trace of
+// Frobenius and t_(i+1) = t_1*t_i - p*t_(i-1), t_0 = 2. The order over
+// F_p is n*h of the curve if it has them, otherwise it is counted
+// (rand is used by `CountPoints` only)
+func (E *ExtCurve) Order(rand io.Reader) (*big.Int, error) {
+	c := E.Curve
+	var order *big.Int
+	if c.N != nil && c.H != nil && c.N.Sign() > 0 && c.H.Sign() > 0 {
+		order = new(big.Int).Mul(c.N, c.H)
+	} else {
+		var err error
+		if order, _, err = c.CountPoints(rand); err != nil {
+			return nil, err
+		}
+	}
+	t1 := new(big.Int).Sub(new(big.Int).Add(c.P, big.NewInt(1)), order)
+	t0, t := big.NewInt(2), new(big.Int).Set(t1)
+	for i := 1; i < E.F.K; i++ {
+		next := new(big.Int).Sub(new(big.Int).Mul(t1, t), new(big.Int).Mul(c.P, t0))
+		t0, t = t, next
+	}
+	q := E.F.Order()
+	return q.Add(q, big.NewInt(1)).Sub(q, t), nil
+}
+
+// Returns random point of prime order r, which must divide
+// #E(F_(p^k)), the same way as `FindGenerator`
+func (E *ExtCurve) RandTorsionPoint(r *big.Int, rand io.Reader) (*ExtPoint, error) {
+	cofactor, err := E.Order(rand)
+	if err != nil {
+		return nil, err
+	}
+	if r.Sign() <= 0 || !r.ProbablyPrime(20) || new(big.Int).Mod(cofactor, r).Sign() != 0 {
+		return nil, errors.New("r must be a prime factor of the group order")
+	}
+	e := 0
+	for new(big.Int).Mod(cofactor, r).Sign() == 0 {
+		cofactor.Quo(cofactor, r)
+		e++
+	}
+	for tries := 0; tries < 128; tries++ {
+		R, err := E.RandPoint(rand)
+		if err != nil {
+			return nil, err
+		}
+		G := E.ScalarMul(R, cofactor)
+		if G.Inf {
+			continue
+		}
+		for i := 0; i < e; i++ {
+			rG := E.ScalarMul(G, r)
+			if rG.Inf {
+				return G, nil
+			}
+			G = rG
+		}
+		return nil, errors.New("group order is wrong")
+	}
+	return nil, errors.New("no point of order r found")
+}
+
+// Miller's algorithm evaluation hit a zero or a pole
+var errMillerDegenerate = errors.New("Q is a zero or pole of the Miller function")
+
+// Returns the line through T and P divided by the vertical line
+// through T + P, at Q, as numerator and denominator, and T + P
+func (E *ExtCurve) millerLine(T, P, Q *ExtPoint) (num, den *Poly, S *ExtPoint) {
+	F := E.F
+	if T.Inf || P.Inf {
+		return F.One(), F.One(), E.Add(T, P)
+	}
+	l := E.slope(T, P)
+	if l == nil {
+		// T = -P, the vertical line and O
+		return F.Sub(Q.X, T.X), F.One(), ExtPointAtInfinity()
+	}
+	S = E.addWithSlope(T, P, l)
+	num = F.Sub(F.Sub(Q.Y, T.Y), F.Mul(l, F.Sub(Q.X, T.X)))
+	return num, F.Sub(Q.X, S.X), S
+}
+
+// Returns f_(r,P)(Q), where div(f_(r,P)) = r*(P) - r*(O) and f is
+// normalized at O (the lines are y - ... and x - ...). r*P must be
+// the point at infinity, Q must not be a zero or a pole of any of
+// the lines, that is, not a multiple of P (or error is returned)
+func (E *ExtCurve) Miller(P, Q *ExtPoint, r *big.Int) (*Poly, error) {
+	if r.Sign() <= 0 {
+		return nil, errors.New("r must be positive")
+	}
+	if !E.IsOnCurve(P) || !E.IsOnCurve(Q) {
+		return nil, errors.New("point is not on the curve")
+	}
+	if !E.ScalarMul(P, r).Inf {
+		return nil, errors.New("r*P is not the point at infinity")
+	}
+	if Q.Inf {
+		return nil, errMillerDegenerate
+	}
+	F := E.F
+	num, den := F.One(), F.One()
+	T := P
+	for i := r.BitLen() - 2; i >= 0; i-- {
+		n, d, S := E.millerLine(T, T, Q)
+		num = F.Mul(F.Mul(num, num), n)
+		den = F.Mul(F.Mul(den, den), d)
+		T = S
+		if r.Bit(i) == 1 {
+			n, d, S := E.millerLine(T, P, Q)
+			num = F.Mul(num, n)
+			den = F.Mul(den, d)
+			T = S
+		}
+	}
+	if num.IsZero() || den.IsZero() {
+		return nil, errMillerDegenerate
+	}
+	return F.Div(num, den), nil
+}
+
+// Returns Weil pairing e_r(P, Q) = (-1)^(r)*f_(r,P)(Q)/f_(r,Q)(P)
+// (Miller, 2004), an r-th root of unity. P and Q must be of order
+// dividing r. It is 1 when one of them is a multiple of the other,
+// and bilinear, alternating and non-degenerate on E[r]
+func (E *ExtCurve) WeilPairing(P, Q *ExtPoint, r *big.Int) (*Poly, error) {
+	if !E.IsOnCurve(Q) || !E.ScalarMul(Q, r).Inf {
+		return nil, errors.New("r*Q is not the point at infinity")
+	}
+	fPQ, err := E.Miller(P, Q, r)
+	if err == errMillerDegenerate {
+		return E.F.One(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	fQP, err := E.Miller(Q, P, r)
+	if err == errMillerDegenerate {
+		return E.F.One(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	e := E.F.Div(fPQ, fQP)
+	if r.Bit(0) == 1 {
+		e = e.Neg()
+	}
+	return e, nil
+}
+
+// Returns reduced Tate pairing t_r(P, Q) = f_(r,P)(Q)^((q - 1)/r),
+// r must divide q - 1 and r*P must be O, Q is any point over
+// F_(q). It is bilinear, and non-degenerate on E(F_(q))[r] and
+// E(F_(q))/r*E(F_(q)). If Q is a zero or a pole, the value is
+// f_(r,P)(Q + S)/f_(r,P)(S) for a point S found by trying small x
+func (E *ExtCurve) TatePairing(P, Q *ExtPoint, r *big.Int) (*Poly, error) {
+	F := E.F
+	if r.Sign() <= 0 {
+		return nil, errors.New("r must divide q - 1")
+	}
+	q1 := new(big.Int).Sub(F.Order(), big.NewInt(1))
+	exp, rem := new(big.Int).QuoRem(q1, r, new(big.Int))
+	if rem.Sign() != 0 {
+		return nil, errors.New("r must divide q - 1")
+	}
+	if !E.IsOnCurve(Q) {
+		return nil, errors.New("point is not on the curve")
+	}
+	if Q.Inf {
+		return F.One(), nil
+	}
+	f, err := E.Miller(P, Q, r)
+	for x := int64(0); err == errMillerDegenerate && x < 64; x++ {
+		S := &ExtPoint{X: F.FromInt(big.NewInt(x))}
+		if S.Y = F.Sqrt(E.Polynomial(S.X)); S.Y == nil {
+			continue
+		}
+		var fS *Poly
+		if f, err = E.Miller(P, E.Add(Q, S), r); err != nil {
+			continue
+		}
+		if fS, err = E.Miller(P, S, r); err != nil {
+			continue
+		}
+		f = F.Div(f, fS)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return F.Exp(f, exp), nil
+}
+
+// MOV reduction (Menezes, Okamoto and Vanstone): for P of prime
+// order n and Q = m*P returns g = t_n(P, R) and h = t_n(Q, R) in
+// F_(p^k), k the embedding degree, with h = g^(m) and g of order
+// n. R is a random point over F_(p^k) with g != 1
+func (c *Curve) MOVReduce(P, Q *ECPoint, n *big.Int, rand io.Reader) (g, h *Poly, E *ExtCurve, err error) {
+	if !n.ProbablyPrime(20) {
+		return nil, nil, nil, errors.New("order of P must be prime")
+	}
+	k := embeddingDegree(c.P, n)
+	if k == nil || k.Cmp(big.NewInt(extMaxDegree)) > 0 {
+		return nil, nil, nil, errors.New("embedding degree is too big")
+	}
+	if E, err = c.Extend(int(k.Int64())); err != nil {
+		return nil, nil, nil, err
+	}
+	P1, Q1 := E.Lift(P), E.Lift(Q)
+	for tries := 0; tries < 64; tries++ {
+		R, err := E.RandPoint(rand)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if g, err = E.TatePairing(P1, R, n); err != nil {
+			return nil, nil, nil, err
+		}
+		if E.F.Equal(g, E.F.One()) {
+			continue
+		}
+		if h, err = E.TatePairing(Q1, R, n); err != nil {
+			return nil, nil, nil, err
+		}
+		return g, h, E, nil
+	}
+	return nil, nil, nil, errors.New("no point with non-trivial pairing found")
+}
+
+// Returns m with m*P = Q by `MOVReduce` and baby-step giant-step
+// in F_(p^k)^(*) (a real attack would use index calculus there)
+func (c *Curve) MOVAttack(ctx context.Context, P, Q *ECPoint, n *big.Int, rand io.Reader, opts *ECDLPOptions) (*big.Int, error) {
+	g, h, E, err := c.MOVReduce(P, Q, n, rand)
+	if err != nil {
+		return nil, err
+	}
+	return extFieldBSGS(newDLPSteps(ctx, opts), E.F, g, h, n)
+}
+
+// Returns m in [0, n - 1] with g^(m) = h
+func extFieldBSGS(s *dlpSteps, F *ExtField, g, h *Poly, n *big.Int) (*big.Int, error) {
+	m := new(big.Int).Add(new(big.Int).Sqrt(new(big.Int).Sub(n, big.NewInt(1))), big.NewInt(1))
+	if m.BitLen() > 32 {
+		return nil, errors.New("group order is too big for baby-step giant-step")
+	}
+	size := m.Int64()
+
+	baby := make(map[string]int64, size)
+	x := F.One()
+	for j := int64(0); j < size; j++ {
+		if _, ok := baby[x.String()]; !ok {
+			baby[x.String()] = j
+		}
+		x = F.Mul(x, g)
+		if err := s.step(); err != nil {
+			return nil, err
+		}
+	}
+
+	gm := F.Exp(g, new(big.Int).Neg(m))
+	x = h
+	for i := int64(0); i <= size; i++ {
+		if j, ok := baby[x.String()]; ok {
+			k := new(big.Int).Add(new(big.Int).Mul(big.NewInt(i), m), big.NewInt(j))
+			return k.Mod(k, n), nil
+		}
+		x = F.Mul(x, gm)
+		if err := s.step(); err != nil {
+			return nil, err
+		}
+	}
+	return nil, errors.New("logarithm not found, h is not a power of g")
+}
@@ -0,0 +1,333 @@
+package ECwrap
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+)
+
+// Pairing-friendly curves BN254 (alt_bn128 of Ethereum, EIP-196
+// and EIP-197, used by most zk-SNARKs) and BLS12-381 (Zcash,
+// Ethereum consensus layer, BLS signatures, EIP-2537), with the
+// optimal ate pairing e: G1 x G2 -> GT
+//
+// G1 is the base point subgroup of the `Curve` y^(2) = x^(3) + b
+// over Fp, so the whole point API works on it. G2 is the subgroup
+// of order r of the sextic twist over Fp2 (`Fp2Curve`), GT the
+// r-th roots of unity in Fp12 of the `Tower`. Both curves belong
+// to families parameterized by an integer u (the seed): p, r and
+// the loop of Miller's algorithm are polynomials in it
+
+// Family of a pairing-friendly curve
+type pairingFamily int
+
+const (
+	// Barreto-Naehrig, p = 36*u^(4) + 36*u^(3) + 24*u^(2) + 6*u + 1
+	familyBN pairingFamily = iota
+	// Barreto-Lynn-Scott with k = 12, p = (u - 1)^(2)*r/3 + u
+	familyBLS12
+)
+
+// Pairing-friendly curve with embedding degree 12
+type PairingCurve struct {
+	Name string
+
+	// curve over Fp, the base point generates G1
+	G1 *Curve
+	// the twist over Fp2 and the generator of G2
+	G2    *Fp2Curve
+	G2Gen *Fp2Point
+	Tower *Tower
+
+	// order of G1, G2 and GT
+	R *big.Int
+	// cofactor of G2, #E'(Fp2) = R * H2
+	H2 *big.Int
+	// seed of the family
+	U *big.Int
+
+	family pairingFamily
+	// the twist is y^(2) = x^(3) + b*xi (M-type) rather than
+	// y^(2) = x^(3) + b/xi (D-type)
+	mType bool
+	// 6*u + 2 for BN, u for BLS12
+	ateLoop *big.Int
+	// (p^(4) - p^(2) + 1)/r, the hard part of the final exponentiation
+	hardExp *big.Int
+	// size of a field element in bytes
+	fpBytes int
+}
+
+var initBN254, initBLS12381 sync.Once
+var bn254, bls12381 *PairingCurve
+
+// Returns BN254 (also called alt_bn128 and bn256), the curve of
+// the Ethereum precompiles, u = 4965661367192848881. Its pairing
+// has about 100 bits of security (Kim-Barbulescu, 2016)
+func BN254() *PairingCurve {
+	initBN254.Do(func() {
+		g1 := mustCurveDec("BN254",
+			"21888242871839275222246405745257275088696311157297823662689037894645226208583",
+			"0", "3", "1", "2",
+			"21888242871839275222246405745257275088548364400416034343698204186575808495617",
+			"1")
+		bn254 = newPairingCurve(g1, familyBN, false,
+			big.NewInt(4965661367192848881), &Fp2{big.NewInt(9), big.NewInt(1)},
+			[4]string{
+				"10857046999023057135944570762232829481370756359578518086990519993285655852781",
+				"11559732032986387107991004021392285783925812861821192530917403151452391805634",
+				"8495653923123431417604973247489272438418190587263600148770280649306958101930",
+				"4082367875863433681332203403145435568316851327593401208105741076214120093531",
+			})
+	})
+	return bn254
+}
+
+// Returns BLS12-381 with u = -0xd201000000010000, about 120
+// bits of security
+func BLS12381() *PairingCurve {
+	initBLS12381.Do(func() {
+		g1 := mustCurveDec("BLS12-381",
+			"4002409555221667393417789825735904156556882819939007885332058136124031650490837864442687629129015664037894272559787",
+			"0", "4",
+			"3685416753713387016781088315183077757961620795782546409894578378688607592378376318836054947676345821548104185464507",
+			"1339506544944476473020471379941921221584933875938349620426543736416511423956333506472724655353366534992391756441569",
+			"52435875175126190479447740508185965837690552500527637822603658699938581184513",
+			"76329603384216526031706109802092473003")
+		bls12381 = newPairingCurve(g1, familyBLS12, true,
+			new(big.Int).Neg(mustDec("15132376222941642752")), &Fp2{big.NewInt(1), big.NewInt(1)},
+			[4]string{
+				"352701069587466618187139116011060144890029952792775240219908644239793785735715026873347600343865175952761926303160",
+				"3059144344244213709971259814753781636986470325476647558659373206291635324768958432433509563104347017837885763365758",
+				"1985150602287291935568054521177171638300868978215655730859378665066344726373823718423869104263333984641494340347905",
+				"927553665492332455747201965776037880757740193453592970025027978793976877002675564980949289727957565575433344219582",
+			})
+	})
+	return bls12381
+}
+
+func mustCurveDec(name, p, a, b, gx, gy, n, h string) *Curve {
+	c, err := NewCurveStr(name, p, a, b, gx, gy, n, h, 10)
+	if err != nil {
+		panic("ECwrap: bad parameters for " + name + ": " + err.Error())
+	}
+	return c
+}
+
+// Builds the curve from G1, the seed, xi of the tower (beta = -1)
+// and the coordinates x0, x1, y0, y1 of the G2 generator. Cofactor
+// of G2 is #E'(Fp2)/r, which is 2*p - r for BN curves and
+// (u^(8) - 4*u^(7) + 5*u^(6) - 4*u^(4) + 6*u^(3) - 4*u^(2) - 4*u + 13)/9
+// for BLS12 ones
+func newPairingCurve(g1 *Curve, family pairingFamily, mType bool, u *big.Int, xi *Fp2, g2 [4]string) *PairingCurve {
+	name := g1.Name
+	t, err := NewTower(g1.P, big.NewInt(-1), xi)
+	if err != nil {
+		panic("ECwrap: bad parameters for " + name + ": " + err.Error())
+	}
+	b := t.Fp2(g1.B, new(big.Int))
+	if mType {
+		b = t.Fp2Mul(b, t.Xi)
+	} else {
+		b = t.Fp2Mul(b, t.Fp2Inv(t.Xi))
+	}
+	twist, err := NewFp2Curve(t, t.Fp2Zero(), b)
+	if err != nil {
+		panic("ECwrap: bad parameters for " + name + ": " + err.Error())
+	}
+	gen := &Fp2Point{X: t.Fp2(mustDec(g2[0]), mustDec(g2[1])), Y: t.Fp2(mustDec(g2[2]), mustDec(g2[3]))}
+	if !twist.IsOnCurve(gen) {
+		panic("ECwrap: bad parameters for " + name + ": G2 generator is not on the twist")
+	}
+
+	pc := &PairingCurve{
+		Name: name, G1: g1, G2: twist, G2Gen: gen, Tower: t,
+		R: g1.N, U: new(big.Int).Set(u),
+		family: family, mType: mType,
+		fpBytes: (g1.P.BitLen() + 7) / 8,
+	}
+	// polynomial in u with coefficients from the highest degree
+	poly := func(coeffs ...int64) *big.Int {
+		out := new(big.Int)
+		for _, c := range coeffs {
+			out.Mul(out, u).Add(out, big.NewInt(c))
+		}
+		return out
+	}
+	switch family {
+	case familyBN:
+		pc.ateLoop = poly(6, 2)
+		pc.H2 = new(big.Int).Sub(new(big.Int).Lsh(g1.P, 1), g1.N)
+	case familyBLS12:
+		pc.ateLoop = new(big.Int).Set(u)
+		pc.H2 = poly(1, -4, 5, 0, -4, 6, -4, -4, 13)
+		pc.H2.Quo(pc.H2, big.NewInt(9))
+	}
+	p2 := new(big.Int).Mul(g1.P, g1.P)
+	pc.hardExp = new(big.Int).Mul(p2, p2)
+	pc.hardExp.Sub(pc.hardExp, p2).Add(pc.hardExp, big.NewInt(1)).Quo(pc.hardExp, g1.N)
+	return pc
+}
+
+// Reports whether Q is on the twist and r*Q = O
+func (pc *PairingCurve) G2IsInSubgroup(Q *Fp2Point) bool {
+	return pc.G2.IsOnCurve(Q) && pc.G2.ScalarMul(Q, pc.R).Inf
+}
+
+// Returns k*G for the generator G of G2
+func (pc *PairingCurve) G2ScalarBaseMul(k *big.Int) *Fp2Point {
+	return pc.G2.ScalarMul(pc.G2Gen, k)
+}
+
+// Returns the image of the p-power Frobenius of E(Fp12) on the
+// twist. With the untwisting map (x, y) -> (x*w^(2), y*w^(3)) of
+// D-type twists it is (conj(x)*gamma_2, conj(y)*gamma_3), M-type
+// twists map by (x/w^(2), y/w^(3)) and divide instead
+func (pc *PairingCurve) twistFrobenius(Q *Fp2Point) *Fp2Point {
+	t := pc.Tower
+	if Q.Inf {
+		return Q
+	}
+	gx, gy := t.gamma[2], t.gamma[3]
+	if pc.mType {
+		gx, gy = t.Fp2Inv(gx), t.Fp2Inv(gy)
+	}
+	return &Fp2Point{X: t.Fp2Mul(t.Fp2Conj(Q.X), gx), Y: t.Fp2Mul(t.Fp2Conj(Q.Y), gy)}
+}
+
+// Returns the line through T and R of the twist evaluated at P
+// (affine, over Fp), and T + R. Untwisted, the line with slope
+// l at P is yP - l*xP*w + (l*xT - yT)*w^(3) for D-type twists and,
+// times w^(3), (l*xT - yT) - l*xP*w^(2) + yP*w^(3) for M-type ones.
+// Vertical lines are in Fp6 and the final exponentiation kills
+// them, so 1 is returned for them
+func (pc *PairingCurve) lineStep(T, R *Fp2Point, P *ECPoint) (*Fp12, *Fp2Point) {
+	t := pc.Tower
+	l := pc.G2.slope(T, R)
+	if l == nil {
+		return t.Fp12One(), Fp2PointAtInfinity()
+	}
+	S := pc.G2.addWithSlope(T, R, l)
+	c := t.Fp2Sub(t.Fp2Mul(l, T.X), T.Y)
+	lx := t.Fp2Neg(t.Fp2MulScalar(l, P.X))
+	y := t.Fp2(P.Y, new(big.Int))
+	zero := t.Fp2Zero()
+	if pc.mType {
+		return &Fp12{&Fp6{c, lx, zero}, &Fp6{zero, y, zero}}, S
+	}
+	return &Fp12{&Fp6{y, zero, zero}, &Fp6{lx, c, zero}}, S
+}
+
+// Returns Miller's function of the optimal ate pairing at P in
+// G1 and Q in G2, before the final exponentiation: f_(6u+2,Q)(P)
+// times the lines through (6u+2)*Q, pi(Q) and -pi^(2)(Q) for BN,
+// f_(u,Q)(P) for BLS12. Inputs are not checked
+func (pc *PairingCurve) MillerLoop(P *ECPoint, Q *Fp2Point) *Fp12 {
+	t := pc.Tower
+	if P.IsInfinity() || Q.Inf {
+		return t.Fp12One()
+	}
+	P = pc.G1.ECPAffine(P)
+	loop := new(big.Int).Abs(pc.ateLoop)
+	f, T := t.Fp12One(), Q
+	for i := loop.BitLen() - 2; i >= 0; i-- {
+		l, S := pc.lineStep(T, T, P)
+		f, T = t.Fp12Mul(t.Fp12Square(f), l), S
+		if loop.Bit(i) == 1 {
+			l, S = pc.lineStep(T, Q, P)
+			f, T = t.Fp12Mul(f, l), S
+		}
+	}
+	// f_(-m,Q) = 1/(f_(m,Q)*v), and the conjugate is the
+	// inverse after the final exponentiation
+	if pc.ateLoop.Sign() < 0 {
+		f, T = t.Fp12Conj(f), pc.G2.Neg(T)
+	}
+	if pc.family == familyBN {
+		Q1 := pc.twistFrobenius(Q)
+		Q2 := pc.G2.Neg(pc.twistFrobenius(Q1))
+		l, S := pc.lineStep(T, Q1, P)
+		f, T = t.Fp12Mul(f, l), S
+		l, _ = pc.lineStep(T, Q2, P)
+		f = t.Fp12Mul(f, l)
+	}
+	return f
+}
+
+// Returns f^((p^(12) - 1)/r). The easy part (p^(6) - 1)(p^(2) + 1)
+// takes a conjugate, an inversion and a Frobenius and puts f in
+// the cyclotomic subgroup. The hard part h = (p^(4) - p^(2) + 1)/r
+// is written in base p, h = h0 + h1*p + h2*p^(2) + h3*p^(3), and
+// f^(h) = f^(h0) * pi(f)^(h1) * pi^(2)(f)^(h2) * pi^(3)(f)^(h3) is
+// computed at once (Straus' trick), with one cyclotomic squaring
+// per bit of p
+func (pc *PairingCurve) FinalExponentiation(f *Fp12) *Fp12 {
+	t := pc.Tower
+	f = t.Fp12Mul(t.Fp12Conj(f), t.Fp12Inv(f))
+	f = t.Fp12Mul(t.Fp12Frobenius(f, 2), f)
+
+	var digits [4]*big.Int
+	h := new(big.Int).Set(pc.hardExp)
+	for i := range digits {
+		digits[i] = new(big.Int)
+		h.QuoRem(h, pc.G1.P, digits[i])
+	}
+	// table[m] = product of pi^(i)(f) for the bits i of m
+	var table [16]*Fp12
+	table[0] = t.Fp12One()
+	for i := 0; i < 4; i++ {
+		fi := t.Fp12Frobenius(f, i)
+		for m := 1 << i; m < 1<<(i+1); m++ {
+			table[m] = t.Fp12Mul(table[m-1<<i], fi)
+		}
+	}
+	out := t.Fp12One()
+	for i := pc.G1.P.BitLen() - 1; i >= 0; i-- {
+		out = t.Fp12CyclotomicSquare(out)
+		m := 0
+		for j := range digits {
+			m |= int(digits[j].Bit(i)) << j
+		}
+		if m != 0 {
+			out = t.Fp12Mul(out, table[m])
+		}
+	}
+	return out
+}
+
+// Returns e(P, Q), P in G1 and Q in G2. Error is returned if
+// either is not in its group
+func (pc *PairingCurve) Pair(P *ECPoint, Q *Fp2Point) (*Fp12, error) {
+	return pc.PairProduct([]*ECPoint{P}, []*Fp2Point{Q})
+}
+
+// Returns e(P_1, Q_1)*...*e(P_n, Q_n) with a single final
+// exponentiation
+func (pc *PairingCurve) PairProduct(Ps []*ECPoint, Qs []*Fp2Point) (*Fp12, error) {
+	if len(Ps) != len(Qs) {
+		return nil, errors.New("numbers of G1 and G2 points differ")
+	}
+	t := pc.Tower
+	f := t.Fp12One()
+	for i := range Ps {
+		if !pc.G1.ECPIsInSubgroup(Ps[i]) {
+			return nil, errors.New("point is not in G1")
+		}
+		if !pc.G2IsInSubgroup(Qs[i]) {
+			return nil, errors.New("point is not in G2")
+		}
+		f = t.Fp12Mul(f, pc.MillerLoop(Ps[i], Qs[i]))
+	}
+	return pc.FinalExponentiation(f), nil
+}
+
+// Reports whether e(P_1, Q_1)*...*e(P_n, Q_n) = 1, which is what
+// BLS signatures and Groth16 proofs are checked with (and what the
+// pairing precompiles of EIP-197 and EIP-2537 compute)
+func (pc *PairingCurve) PairingCheck(Ps []*ECPoint, Qs []*Fp2Point) (bool, error) {
+	e, err := pc.PairProduct(Ps, Qs)
+	if err != nil {
+		return false, err
+	}
+	return pc.Tower.Fp12IsOne(e), nil
+}
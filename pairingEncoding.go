@@ -0,0 +1,276 @@
+package ECwrap
+
+import (
+	"errors"
+	"math/big"
+)
+
+// Encodings of G1 and G2 points of the pairing curves. Field
+// elements are big-endian, Fp2 ones as a1 || a0, points as x || y
+// or x alone with the sign of y in the flags
+//
+// BLS12-381 uses the Zcash format (the one of the IETF BLS
+// signature draft and of the Ethereum consensus layer): the three
+// top bits of the first byte are compression, infinity and sign
+// (y is the larger of y and -y), infinity is 0x40 or 0xc0 and zeroes.
+//
+// BN254 uses the format of gnark-crypto, whose uncompressed form is
+// that of EIP-196 and EIP-197: no flags, infinity is all zeroes.
+// Compressed points have the top two bits 10 (smaller y), 11 (larger
+// y) or 01 (infinity), there is room for them as p has 254 bits
+
+const (
+	zcashCompressed = 0x80
+	zcashInfinity   = 0x40
+	zcashSign       = 0x20
+
+	gnarkMask         = 0xc0
+	gnarkSmallest     = 0x80
+	gnarkLargest      = 0xc0
+	gnarkCompInfinity = 0x40
+	gnarkUncompressed = 0x00
+)
+
+// Reports whether x > (p - 1)/2
+func fpLargest(x, p *big.Int) bool {
+	return new(big.Int).Lsh(x, 1).Cmp(p) > 0
+}
+
+// Reports whether a is the larger of a and -a: a1 decides,
+// a0 if a1 = 0
+func (t *Tower) fp2Largest(a *Fp2) bool {
+	if a.A1.Sign() != 0 {
+		return fpLargest(a.A1, t.P)
+	}
+	return fpLargest(a.A0, t.P)
+}
+
+// Returns the flags for a point with given sign of y
+func (pc *PairingCurve) flags(compressed, inf, largest bool) byte {
+	if pc.family == familyBLS12 {
+		var f byte
+		if compressed {
+			f |= zcashCompressed
+		}
+		if inf {
+			f |= zcashInfinity
+		} else if compressed && largest {
+			f |= zcashSign
+		}
+		return f
+	}
+	switch {
+	case !compressed:
+		return gnarkUncompressed
+	case inf:
+		return gnarkCompInfinity
+	case largest:
+		return gnarkLargest
+	}
+	return gnarkSmallest
+}
+
+// Splits the flags off the first byte, error if they make no sense
+func (pc *PairingCurve) parseFlags(b []byte) (compressed, inf, largest bool, err error) {
+	if pc.family == familyBLS12 {
+		compressed = b[0]&zcashCompressed != 0
+		inf = b[0]&zcashInfinity != 0
+		largest = b[0]&zcashSign != 0
+		if largest && (inf || !compressed) {
+			return false, false, false, errors.New("invalid point encoding flags")
+		}
+		b[0] &^= zcashCompressed | zcashInfinity | zcashSign
+		return compressed, inf, largest, nil
+	}
+	switch b[0] & gnarkMask {
+	case gnarkUncompressed:
+	case gnarkCompInfinity:
+		compressed, inf = true, true
+	case gnarkSmallest:
+		compressed = true
+	case gnarkLargest:
+		compressed, largest = true, true
+	}
+	b[0] &^= gnarkMask
+	if !compressed {
+		// infinity is all zeroes
+		inf = true
+		for _, c := range b {
+			inf = inf && c == 0
+		}
+	}
+	return compressed, inf, largest, nil
+}
+
+// Reads field elements of the encoding, error if any is >= p
+func (pc *PairingCurve) readFp(b []byte, n int) ([]*big.Int, error) {
+	out := make([]*big.Int, n)
+	for i := range out {
+		out[i] = new(big.Int).SetBytes(b[i*pc.fpBytes : (i+1)*pc.fpBytes])
+		if out[i].Cmp(pc.G1.P) >= 0 {
+			return nil, errors.New("coordinate is out of range [0, p)")
+		}
+	}
+	return out, nil
+}
+
+func (pc *PairingCurve) appendFp(b []byte, xs ...*big.Int) []byte {
+	for _, x := range xs {
+		b = append(b, x.FillBytes(make([]byte, pc.fpBytes))...)
+	}
+	return b
+}
+
+// Returns uncompressed encoding of P in G1, 2 field elements
+func (pc *PairingCurve) MarshalG1(P *ECPoint) []byte {
+	return pc.marshalG1(P, false)
+}
+
+// Returns compressed encoding of P in G1, 1 field element
+func (pc *PairingCurve) MarshalG1Compressed(P *ECPoint) []byte {
+	return pc.marshalG1(P, true)
+}
+
+func (pc *PairingCurve) marshalG1(P *ECPoint, compressed bool) []byte {
+	n := 2
+	if compressed {
+		n = 1
+	}
+	if P.IsInfinity() {
+		b := make([]byte, n*pc.fpBytes)
+		b[0] = pc.flags(compressed, true, false)
+		return b
+	}
+	P = pc.G1.ECPAffine(P)
+	var b []byte
+	if compressed {
+		b = pc.appendFp(nil, P.X)
+	} else {
+		b = pc.appendFp(nil, P.X, P.Y)
+	}
+	b[0] |= pc.flags(compressed, false, fpLargest(P.Y, pc.G1.P))
+	return b
+}
+
+// Returns point of G1 from its compressed or uncompressed encoding.
+// Error is returned if the encoding is invalid, or the point is not
+// on the curve or not in G1
+func (pc *PairingCurve) UnmarshalG1(data []byte) (*ECPoint, error) {
+	if len(data) != pc.fpBytes && len(data) != 2*pc.fpBytes {
+		return nil, errors.New("invalid point encoding length")
+	}
+	b := append([]byte(nil), data...)
+	compressed, inf, largest, err := pc.parseFlags(b)
+	if err != nil {
+		return nil, err
+	}
+	if compressed != (len(b) == pc.fpBytes) {
+		return nil, errors.New("invalid point encoding length")
+	}
+	if inf {
+		for _, c := range b {
+			if c != 0 {
+				return nil, errors.New("invalid encoding of the point at infinity")
+			}
+		}
+		return PointAtInfinity(), nil
+	}
+	xy, err := pc.readFp(b, len(b)/pc.fpBytes)
+	if err != nil {
+		return nil, err
+	}
+	P := &ECPoint{xy[0], nil, big.NewInt(1)}
+	if compressed {
+		if P.Y = new(big.Int).ModSqrt(pc.G1.Polynomial(P.X), pc.G1.P); P.Y == nil {
+			return nil, errors.New("no point with such x on curve")
+		}
+		if fpLargest(P.Y, pc.G1.P) != largest {
+			P.Y.Sub(pc.G1.P, P.Y)
+		}
+	} else {
+		P.Y = xy[1]
+	}
+	if !pc.G1.ECPIsOnCurve(P) {
+		return nil, errors.New("point is not on curve")
+	}
+	if !pc.G1.ECPIsInSubgroup(P) {
+		return nil, errors.New("point is not in G1")
+	}
+	return P, nil
+}
+
+// Returns uncompressed encoding of Q in G2, 4 field elements
+func (pc *PairingCurve) MarshalG2(Q *Fp2Point) []byte {
+	return pc.marshalG2(Q, false)
+}
+
+// Returns compressed encoding of Q in G2, 2 field elements
+func (pc *PairingCurve) MarshalG2Compressed(Q *Fp2Point) []byte {
+	return pc.marshalG2(Q, true)
+}
+
+func (pc *PairingCurve) marshalG2(Q *Fp2Point, compressed bool) []byte {
+	n := 4
+	if compressed {
+		n = 2
+	}
+	if Q.Inf {
+		b := make([]byte, n*pc.fpBytes)
+		b[0] = pc.flags(compressed, true, false)
+		return b
+	}
+	b := pc.appendFp(nil, Q.X.A1, Q.X.A0)
+	if !compressed {
+		b = pc.appendFp(b, Q.Y.A1, Q.Y.A0)
+	}
+	b[0] |= pc.flags(compressed, false, pc.Tower.fp2Largest(Q.Y))
+	return b
+}
+
+// Returns point of G2 from its compressed or uncompressed encoding.
+// Error is returned if the encoding is invalid, or the point is not
+// on the twist or not in G2
+func (pc *PairingCurve) UnmarshalG2(data []byte) (*Fp2Point, error) {
+	if len(data) != 2*pc.fpBytes && len(data) != 4*pc.fpBytes {
+		return nil, errors.New("invalid point encoding length")
+	}
+	b := append([]byte(nil), data...)
+	compressed, inf, largest, err := pc.parseFlags(b)
+	if err != nil {
+		return nil, err
+	}
+	if compressed != (len(b) == 2*pc.fpBytes) {
+		return nil, errors.New("invalid point encoding length")
+	}
+	if inf {
+		for _, c := range b {
+			if c != 0 {
+				return nil, errors.New("invalid encoding of the point at infinity")
+			}
+		}
+		return Fp2PointAtInfinity(), nil
+	}
+	t := pc.Tower
+	xy, err := pc.readFp(b, len(b)/pc.fpBytes)
+	if err != nil {
+		return nil, err
+	}
+	Q := &Fp2Point{X: &Fp2{xy[1], xy[0]}}
+	if compressed {
+		if Q.Y = t.Fp2Sqrt(pc.G2.Polynomial(Q.X)); Q.Y == nil {
+			return nil, errors.New("no point with such x on curve")
+		}
+		if t.fp2Largest(Q.Y) != largest {
+			Q.Y = t.Fp2Neg(Q.Y)
+		}
+	} else {
+		Q.Y = &Fp2{xy[3], xy[2]}
+	}
+	if !pc.G2.IsOnCurve(Q) {
+		return nil, errors.New("point is not on curve")
+	}
+	if !pc.G2IsInSubgroup(Q) {
+		return nil, errors.New("point is not in G2")
+	}
+	return Q, nil
+}
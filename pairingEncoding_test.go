@@ -0,0 +1,131 @@
+package ECwrap
+
+import (
+	"bytes"
+	"math/big"
+	"os"
+	"testing"
+)
+
+// testdata/pairing/zcash_*.dat are the serialization vectors of the
+// bls12_381 crate of zkcrypto: i*G of G1 and G2 for i = 0..99, each
+// compressed and uncompressed
+func TestZCashVectors(t *testing.T) {
+	pc := BLS12381()
+	files := []struct {
+		name       string
+		size       int
+		compressed bool
+		g2         bool
+	}{
+		{"zcash_g1_compressed.dat", 48, true, false},
+		{"zcash_g1_uncompressed.dat", 96, false, false},
+		{"zcash_g2_compressed.dat", 96, true, true},
+		{"zcash_g2_uncompressed.dat", 192, false, true},
+	}
+	for _, f := range files {
+		data, err := os.ReadFile("testdata/pairing/" + f.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) != 100*f.size {
+			t.Fatalf(`%s: len = %d, expected = %d`, f.name, len(data), 100*f.size)
+		}
+		P, Q := PointAtInfinity(), Fp2PointAtInfinity()
+		for i := 0; i < 100; i++ {
+			enc := data[i*f.size : (i+1)*f.size]
+			var got []byte
+			if f.g2 {
+				dec, err := pc.UnmarshalG2(enc)
+				if err != nil || !pc.G2.Equal(dec, Q) {
+					t.Fatalf(`%s: UnmarshalG2() of %d*G = %v, err = %v`, f.name, i, dec, err)
+				}
+				if f.compressed {
+					got = pc.MarshalG2Compressed(Q)
+				} else {
+					got = pc.MarshalG2(Q)
+				}
+				Q = pc.G2.Add(Q, pc.G2Gen)
+			} else {
+				dec, err := pc.UnmarshalG1(enc)
+				if err != nil || !pc.G1.ECPEqual(dec, P) {
+					t.Fatalf(`%s: UnmarshalG1() of %d*G = %v, err = %v`, f.name, i, dec, err)
+				}
+				if f.compressed {
+					got = pc.MarshalG1Compressed(P)
+				} else {
+					got = pc.MarshalG1(P)
+				}
+				P = pc.G1.ECPAffine(pc.G1.ECPAdd(P, pc.G1.Generator()))
+			}
+			if !bytes.Equal(got, enc) {
+				t.Fatalf(`%s: encoding of %d*G = %x, expected = %x`, f.name, i, got, enc)
+			}
+		}
+	}
+}
+
+func TestPairingEncoding(t *testing.T) {
+	rnd := NewTestReader([]byte("pairing encoding"))
+	for _, pc := range []*PairingCurve{BN254(), BLS12381()} {
+		// round trips, both signs of y and infinity
+		for i := 0; i < 8; i++ {
+			k, err := randInt(rnd, pc.R)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if i == 0 {
+				k.SetInt64(0)
+			}
+			P := pc.G1.ECPAffine(pc.G1.ECPScalarBaseMul(k))
+			Q := pc.G2ScalarBaseMul(k)
+			for _, b := range [][]byte{pc.MarshalG1(P), pc.MarshalG1Compressed(P)} {
+				got, err := pc.UnmarshalG1(b)
+				if err != nil || !pc.G1.ECPEqual(got, P) {
+					t.Fatalf(`%s: UnmarshalG1(%x) = %v, expected = %v, err = %v`, pc.Name, b, got, P, err)
+				}
+			}
+			for _, b := range [][]byte{pc.MarshalG2(Q), pc.MarshalG2Compressed(Q)} {
+				got, err := pc.UnmarshalG2(b)
+				if err != nil || !pc.G2.Equal(got, Q) {
+					t.Fatalf(`%s: UnmarshalG2(%x) = %v, expected = %v, err = %v`, pc.Name, b, got, Q, err)
+				}
+			}
+		}
+
+		off := pc.MarshalG1(pc.G1.Generator())
+		off[len(off)-1] ^= 1
+		long := append(pc.MarshalG1Compressed(pc.G1.Generator()), 0)
+		xIsP := pc.appendFp(nil, pc.G1.P, new(big.Int))
+		xIsP[0] |= pc.flags(false, false, false)
+		infWithY := pc.MarshalG1Compressed(PointAtInfinity())
+		infWithY[len(infWithY)-1] = 1
+		infWithSign := pc.MarshalG1Compressed(PointAtInfinity())
+		if pc == BLS12381() {
+			infWithSign[0] |= zcashSign
+		} else {
+			// BN254 has no flag for uncompressed infinity
+			infWithSign = pc.MarshalG1(PointAtInfinity())
+			infWithSign[0] |= gnarkCompInfinity
+		}
+		bad := map[string][]byte{
+			"wrong length":      long,
+			"point off curve":   off,
+			"x = p":             xIsP,
+			"infinity with y":   infWithY,
+			"flags of infinity": infWithSign,
+		}
+		notG2, err := pc.G2.RandPoint(rnd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := pc.UnmarshalG2(pc.MarshalG2(notG2)); err == nil {
+			t.Fatalf(`%s: UnmarshalG2() of a point not in G2 returned no error`, pc.Name)
+		}
+		for name, b := range bad {
+			if _, err := pc.UnmarshalG1(b); err == nil {
+				t.Fatalf(`%s: UnmarshalG1() with %s returned no error`, pc.Name, name)
+			}
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package ECwrap
+
+import (
+	"context"
+	"math/big"
+	"testing"
+)
+
+func TestExtField(t *testing.T) {
+	rnd := NewTestReader([]byte("ext field"))
+	for _, tc := range []struct {
+		p int64
+		k int
+	}{{1019, 1}, {1019, 2}, {97, 3}, {2, 8}, {3, 12}} {
+		F, err := NewExtField(big.NewInt(tc.p), tc.k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if F.Modulus.Degree() != tc.k {
+			t.Fatalf(`NewExtField(%d, %d) modulus = %v`, tc.p, tc.k, F.Modulus)
+		}
+		nonSquares := 0
+		for i := 0; i < 16; i++ {
+			a, err := F.Rand(rnd)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a.IsZero() {
+				continue
+			}
+			if !F.Equal(F.Mul(a, F.Inv(a)), F.One()) {
+				t.Fatalf(`a*a^(-1) != 1 for a = %v`, a)
+			}
+			b := a
+			for j := 0; j < tc.k; j++ {
+				b = F.Frobenius(b)
+			}
+			if !F.Equal(a, b) {
+				t.Fatalf(`a^(p^k) != a for a = %v`, a)
+			}
+			if !F.Equal(F.Exp(a, F.Order()), a) {
+				t.Fatalf(`a^(q) != a for a = %v`, a)
+			}
+			r := F.Sqrt(a)
+			if r == nil {
+				nonSquares++
+				if F.IsSquare(a) {
+					t.Fatalf(`Sqrt(%v) = nil for a square`, a)
+				}
+				continue
+			}
+			if !F.Equal(F.Mul(r, r), a) {
+				t.Fatalf(`Sqrt(%v)^2 = %v`, a, F.Mul(r, r))
+			}
+		}
+		if tc.p != 2 && nonSquares == 0 {
+			t.Fatalf(`no non-squares in F_(%d^%d)`, tc.p, tc.k)
+		}
+	}
+
+	p := big.NewInt(1019)
+	if _, err := NewExtFieldModulus(NewPoly(p, big.NewInt(1), big.NewInt(0), big.NewInt(1))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewExtFieldModulus(NewPoly(p, big.NewInt(-1), big.NewInt(0), big.NewInt(1))); err == nil {
+		t.Fatalf(`NewExtFieldModulus(x^2 - 1) returned no error`)
+	}
+}
+
+// Checks that e is bilinear and non-degenerate on P and Q
+// of order r
+func checkPairing(t *testing.T, name string, E *ExtCurve, P, Q *ExtPoint, r *big.Int, e func(P, Q *ExtPoint) *Poly) {
+	F := E.F
+	ePQ := e(P, Q)
+	if F.Equal(ePQ, F.One()) {
+		t.Fatalf(`%s: e(P, Q) = 1`, name)
+	}
+	if !F.Equal(F.Exp(ePQ, r), F.One()) {
+		t.Fatalf(`%s: e(P, Q)^r = %v, expected = 1`, name, F.Exp(ePQ, r))
+	}
+	for _, ab := range [][2]int64{{2, 1}, {1, 3}, {5, 7}, {-1, 4}} {
+		a, b := big.NewInt(ab[0]), big.NewInt(ab[1])
+		got := e(E.ScalarMul(P, a), E.ScalarMul(Q, b))
+		expected := F.Exp(ePQ, new(big.Int).Mul(a, b))
+		if !F.Equal(got, expected) {
+			t.Fatalf(`%s: e(%d*P, %d*Q) = %v, expected = %v`, name, ab[0], ab[1], got, expected)
+		}
+	}
+	sum := e(E.Add(P, Q), Q)
+	if !F.Equal(sum, F.Mul(ePQ, e(Q, Q))) {
+		t.Fatalf(`%s: e(P + Q, Q) = %v, expected = %v`, name, sum, F.Mul(ePQ, e(Q, Q)))
+	}
+}
+
+func TestPairings(t *testing.T) {
+	rnd := NewTestReader([]byte("pairing"))
+	for _, tc := range []struct {
+		name          string
+		p, a, b, r, k int64
+	}{
+		// supersingular, #E = p + 1 = 2^2*3*5*17
+		{"y^2 = x^3 + x", 1019, 1, 0, 17, 2},
+		// #E = 75, E[5] over F_p
+		{"y^2 = x^3 + 4", 61, 0, 4, 5, 1},
+		// #E = 52, 13 | 61^3 - 1
+		{"y^2 = x^3 + 2*x + 4", 61, 2, 4, 13, 3},
+	} {
+		c := &Curve{P: big.NewInt(tc.p), A: big.NewInt(tc.a), B: big.NewInt(tc.b)}
+		r := big.NewInt(tc.r)
+		E, err := c.Extend(int(tc.k))
+		if err != nil {
+			t.Fatal(err)
+		}
+		weil := func(P, Q *ExtPoint) *Poly {
+			e, err := E.WeilPairing(P, Q, r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return e
+		}
+		tate := func(P, Q *ExtPoint) *Poly {
+			e, err := E.TatePairing(P, Q, r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return e
+		}
+
+		// P over F_p, Q with e(P, Q) != 1
+		base, err := c.Extend(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		P0, err := base.RandTorsionPoint(r, rnd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		P := E.Lift(&ECPoint{P0.X.Coeff(0), P0.Y.Coeff(0), big.NewInt(1)})
+		var Q *ExtPoint
+		for i := 0; i < 16; i++ {
+			if Q, err = E.RandTorsionPoint(r, rnd); err != nil {
+				t.Fatal(err)
+			}
+			if !E.F.Equal(weil(P, Q), E.F.One()) {
+				break
+			}
+		}
+		checkPairing(t, tc.name+" Weil", E, P, Q, r, weil)
+		checkPairing(t, tc.name+" Tate", E, P, Q, r, tate)
+
+		if !E.F.Equal(weil(P, P), E.F.One()) || !E.F.Equal(weil(P, E.ScalarMul(P, big.NewInt(3))), E.F.One()) {
+			t.Fatalf(`%s: e(P, m*P) != 1`, tc.name)
+		}
+		if !E.F.Equal(weil(Q, P), E.F.Inv(weil(P, Q))) {
+			t.Fatalf(`%s: e(Q, P) != e(P, Q)^(-1)`, tc.name)
+		}
+		R, err := c.randAffinePoint(rnd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := E.WeilPairing(P, E.Add(Q, E.Lift(R)), r); err == nil {
+			t.Fatalf(`%s: WeilPairing() of a point of other order returned no error`, tc.name)
+		}
+		for _, bad := range []int64{0, -tc.r} {
+			if _, err := E.TatePairing(P, Q, big.NewInt(bad)); err == nil {
+				t.Fatalf(`%s: TatePairing(r = %d) returned no error`, tc.name, bad)
+			}
+		}
+	}
+}
+
+func TestMOVAttack(t *testing.T) {
+	// supersingular y^2 = x^3 + x over p = 4*n - 1 has
+	// embedding degree 2
+	n := big.NewInt(1048601)
+	c := &Curve{P: big.NewInt(4194403), A: big.NewInt(1), B: big.NewInt(0), N: n, H: big.NewInt(4)}
+	rnd := NewTestReader([]byte("mov"))
+	P, err := c.FindGenerator(n, rnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := big.NewInt(777777)
+	got, err := c.MOVAttack(context.Background(), P, c.ECPScalarMul(P, m), n, rnd, nil)
+	if err != nil || got.Cmp(m) != 0 {
+		t.Fatalf(`MOVAttack() = %v, expected = %v, err = %v`, got, m, err)
+	}
+
+	// embedding degree of the prime order toy curve is huge
+	prime, _ := dlpCurves(t)
+	if _, _, _, err := prime.MOVReduce(prime.Generator(), prime.Generator(), prime.N, rnd); err == nil {
+		t.Fatalf(`MOVReduce() returned no error`)
+	}
+}
@@ -0,0 +1,406 @@
+package ECwrap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Parallel Pollard rho of van Oorschot and Wiener
+// https://people.scs.carleton.ca/~paulv/papers/JoC97.pdf
+//
+// Workers run independent walks and put the distinguished
+// points they reach into one shared table, a point reached
+// by two walks gives the logarithm. With W workers it is W
+// times faster than `PollardRho`, and the table is all the
+// state there is, so it can be saved and a run resumed later
+//
+// With the negation map the walk goes over classes {X, -X},
+// which halves the space and saves a factor sqrt(2). It makes
+// fruitless cycles though (X -> Y -> X most often), they are
+// avoided with look-ahead and the rest are detected and escaped
+// by doubling the smallest point of the cycle
+
+// Options of `ParallelRho`, may be nil
+type ParallelRhoOptions struct {
+	// Progress is called from the workers, one call at a time
+	ECDLPOptions
+	// Number of goroutines, runtime.NumCPU() if zero
+	Workers int
+	// Walk over classes {X, -X}
+	NegationMap bool
+	// Point is distinguished if this many lowest bits of its
+	// x are zero. If zero, it is picked so that each worker
+	// reaches about 32 points per sqrt(n) steps
+	DistinguishedBits int
+	// File to keep the table in. If it exists, the run goes on
+	// from it, it is written every CheckpointInterval (one
+	// minute if zero) and when the run stops
+	Checkpoint         string
+	CheckpointInterval time.Duration
+}
+
+// Fruitless cycles up to this length are detected
+const rhoCycleCheck = 64
+
+// State shared by the workers
+type rhoState struct {
+	c    *Curve
+	P, Q *ECPoint
+	n    *big.Int
+	opts *ParallelRhoOptions
+	bits int
+
+	// r-adding walk, R_j = a_j*P + b_j*Q
+	R    []*ECPoint
+	a, b []*big.Int
+
+	mu    sync.Mutex
+	rand  io.Reader
+	table map[string][2]*big.Int
+	k     *big.Int
+	err   error
+
+	steps      atomic.Uint64
+	progressMu sync.Mutex
+}
+
+// Same as `PollardRho`, run by a pool of goroutines, see
+// `ParallelRhoOptions`. n is the prime order of P
+func (c *Curve) ParallelRho(ctx context.Context, P, Q *ECPoint, n *big.Int, rand io.Reader, opts *ParallelRhoOptions) (*big.Int, error) {
+	if opts == nil {
+		opts = &ParallelRhoOptions{}
+	}
+	if !n.ProbablyPrime(20) {
+		return nil, errors.New("group order must be prime")
+	}
+	if !c.ECPScalarMul(Q, n).IsInfinity() {
+		return nil, errors.New("Q is not in the subgroup of order n")
+	}
+	if n.BitLen() <= 16 {
+		return c.bsgs(newDLPSteps(ctx, &opts.ECDLPOptions), P, Q, n)
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	s := &rhoState{
+		c: c, P: c.ECPAffine(P), Q: c.ECPAffine(Q), n: n, opts: opts,
+		bits:  opts.DistinguishedBits,
+		rand:  rand,
+		table: map[string][2]*big.Int{},
+	}
+	if s.bits == 0 {
+		s.bits = max(0, n.BitLen()/2-5-big.NewInt(int64(workers)).BitLen()+1)
+	}
+	loaded := false
+	if opts.Checkpoint != "" {
+		var err error
+		if loaded, err = s.load(opts.Checkpoint); err != nil {
+			return nil, err
+		}
+	}
+	if !loaded {
+		for j := 0; j < rhoPartitions; j++ {
+			a, b, err := s.randPair()
+			if err != nil {
+				return nil, err
+			}
+			s.a, s.b = append(s.a, a), append(s.b, b)
+		}
+	}
+	for j := range s.a {
+		s.R = append(s.R, c.ECPAffine(c.ECPMultiScalarMul([]*ECPoint{s.P, s.Q}, []*big.Int{s.a[j], s.b[j]})))
+	}
+
+	wctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.worker(wctx); err != nil && wctx.Err() == nil {
+				s.mu.Lock()
+				s.err = err
+				s.mu.Unlock()
+			}
+			// one is done, for whatever reason, so are the rest
+			cancel()
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	var tick <-chan time.Time
+	if opts.Checkpoint != "" {
+		interval := opts.CheckpointInterval
+		if interval == 0 {
+			interval = time.Minute
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-tick:
+			if err := s.save(opts.Checkpoint); err != nil {
+				cancel()
+				<-done
+				return nil, err
+			}
+		case <-done:
+			if opts.Checkpoint != "" {
+				if err := s.save(opts.Checkpoint); err != nil {
+					return nil, err
+				}
+			}
+			switch {
+			case s.k != nil:
+				return s.k, nil
+			case s.err != nil:
+				return nil, s.err
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Returns random a, b in [0, n - 1], rand is shared
+func (s *rhoState) randPair() (*big.Int, *big.Int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, err := randInt(s.rand, s.n)
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := randInt(s.rand, s.n)
+	return a, b, err
+}
+
+// Counts `steps` more group operations, calls Progress
+// for each interval they cross
+func (s *rhoState) count(steps uint64) {
+	total := s.steps.Add(steps)
+	o := &s.opts.ECDLPOptions
+	if o.Progress == nil {
+		return
+	}
+	interval := o.ProgressInterval
+	if interval == 0 {
+		interval = 1 << 16
+	}
+	if total/interval != (total-steps)/interval {
+		s.progressMu.Lock()
+		o.Progress(total)
+		s.progressMu.Unlock()
+	}
+}
+
+// Replaces X with -X (and a, b with -a, -b) if the y of X
+// is above (p - 1)/2, so X and -X walk the same way
+func (s *rhoState) canonical(X *ECPoint, a, b *big.Int) *ECPoint {
+	if !s.opts.NegationMap || X.IsInfinity() {
+		return X
+	}
+	if new(big.Int).Lsh(X.Y, 1).Cmp(s.c.P) <= 0 {
+		return X
+	}
+	a.Sub(s.n, a).Mod(a, s.n)
+	b.Sub(s.n, b).Mod(b, s.n)
+	return s.c.ECPAffine(s.c.ECPNeg(X))
+}
+
+// One step of the walk. With the negation map, partitions which
+// bring the walk back to the same partition are skipped (they
+// make 2-cycles X -> -(X + R_j) -> X)
+func (s *rhoState) next(X *ECPoint, a, b *big.Int) *ECPoint {
+	j := walkIndex(X, rhoPartitions)
+	for i := 0; i < rhoPartitions; i++ {
+		jj := (j + i) % rhoPartitions
+		Y := s.c.ECPAffine(s.c.ECPAdd(X, s.R[jj]))
+		if s.opts.NegationMap && i < rhoPartitions-1 && walkIndex(Y, rhoPartitions) == jj {
+			continue
+		}
+		a.Add(a, s.a[jj]).Mod(a, s.n)
+		b.Add(b, s.b[jj]).Mod(b, s.n)
+		return s.canonical(Y, a, b)
+	}
+	panic("unreachable")
+}
+
+// Leaves fruitless cycle through X: goes round it once more and
+// doubles its point with the smallest x, so every walk that
+// falls into the cycle leaves it the same way
+func (s *rhoState) escape(X *ECPoint, a, b *big.Int) *ECPoint {
+	minX, minA, minB := X, new(big.Int).Set(a), new(big.Int).Set(b)
+	Y, ya, yb := X, new(big.Int).Set(a), new(big.Int).Set(b)
+	for i := 0; i < rhoCycleCheck; i++ {
+		Y = s.next(Y, ya, yb)
+		if s.c.ECPEqual(Y, X) {
+			break
+		}
+		if Y.X.Cmp(minX.X) < 0 {
+			minX, minA, minB = Y, new(big.Int).Set(ya), new(big.Int).Set(yb)
+		}
+	}
+	a.Lsh(minA, 1).Mod(a, s.n)
+	b.Lsh(minB, 1).Mod(b, s.n)
+	return s.canonical(s.c.ECPAffine(s.c.ECPAdd(minX, minX)), a, b)
+}
+
+func (s *rhoState) worker(ctx context.Context) error {
+	maxWalk := int64(math.MaxInt64)
+	if s.bits < 58 {
+		maxWalk = 20 << s.bits
+	}
+	local := uint64(0)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		a, b, err := s.randPair()
+		if err != nil {
+			return err
+		}
+		X := s.canonical(s.c.ECPAffine(s.c.ECPMultiScalarMul([]*ECPoint{s.P, s.Q}, []*big.Int{a, b})), a, b)
+		anchor := X
+		for w := int64(0); w < maxWalk && !X.IsInfinity(); w++ {
+			if distinguished(X, s.bits) {
+				if s.insert(X, a, b) {
+					return nil
+				}
+				break
+			}
+			X = s.next(X, a, b)
+			if w%rhoCycleCheck == 0 {
+				anchor = X
+			} else if X.X.Cmp(anchor.X) == 0 && X.Y.Cmp(anchor.Y) == 0 {
+				X = s.escape(X, a, b)
+			}
+
+			if local++; local == 256 {
+				s.count(local)
+				local = 0
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// Puts distinguished point X = a*P + b*Q into the table.
+// Reports whether the logarithm is found
+func (s *rhoState) insert(X *ECPoint, a, b *big.Int) bool {
+	key := string(s.c.ECPMarshal(X))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.k != nil {
+		return true
+	}
+	prev, ok := s.table[key]
+	if !ok {
+		s.table[key] = [2]*big.Int{a, b}
+		return false
+	}
+	// a + b*k = a' + b'*k, k = (a - a') / (b' - b)
+	db := new(big.Int).Sub(prev[1], b)
+	if db.Mod(db, s.n).Sign() == 0 {
+		return false
+	}
+	k := new(big.Int).Mul(new(big.Int).Sub(a, prev[0]), db.ModInverse(db, s.n))
+	k.Mod(k, s.n)
+	if !s.c.ECPEqual(s.c.ECPScalarMul(s.P, k), s.Q) {
+		return false
+	}
+	s.k = k
+	return true
+}
+
+// Checkpoint file, JSON. Points are SEC 1 encoded
+type rhoCheckpoint struct {
+	P, Q        []byte
+	N           *big.Int
+	Bits        int
+	NegationMap bool
+	Steps       uint64
+	// a_j, b_j of the walk
+	Walk   [][2]*big.Int
+	Points []rhoCheckpointPoint
+}
+
+type rhoCheckpointPoint struct {
+	X    []byte
+	A, B *big.Int
+}
+
+func (s *rhoState) save(name string) error {
+	s.mu.Lock()
+	cp := rhoCheckpoint{
+		P: s.c.ECPMarshal(s.P), Q: s.c.ECPMarshal(s.Q), N: s.n,
+		Bits: s.bits, NegationMap: s.opts.NegationMap,
+		Steps: s.steps.Load(),
+	}
+	for j := range s.a {
+		cp.Walk = append(cp.Walk, [2]*big.Int{s.a[j], s.b[j]})
+	}
+	for key, v := range s.table {
+		cp.Points = append(cp.Points, rhoCheckpointPoint{[]byte(key), v[0], v[1]})
+	}
+	s.mu.Unlock()
+
+	data, err := json.Marshal(&cp)
+	if err != nil {
+		return err
+	}
+	// never leave a half written file behind
+	if err := os.WriteFile(name+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(name+".tmp", name)
+}
+
+// Reads checkpoint, reports false if there is none yet
+func (s *rhoState) load(name string) (bool, error) {
+	data, err := os.ReadFile(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	var cp rhoCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return false, err
+	}
+	if string(cp.P) != string(s.c.ECPMarshal(s.P)) || string(cp.Q) != string(s.c.ECPMarshal(s.Q)) ||
+		cp.N == nil || cp.N.Cmp(s.n) != 0 {
+		return false, errors.New("checkpoint is of another logarithm")
+	}
+	if cp.NegationMap != s.opts.NegationMap || len(cp.Walk) != rhoPartitions {
+		return false, errors.New("checkpoint is of another walk")
+	}
+	s.bits = cp.Bits
+	s.steps.Store(cp.Steps)
+	for _, ab := range cp.Walk {
+		s.a, s.b = append(s.a, ab[0]), append(s.b, ab[1])
+	}
+	for _, p := range cp.Points {
+		s.table[string(p.X)] = [2]*big.Int{p.A, p.B}
+	}
+	return true, nil
+}
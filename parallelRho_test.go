@@ -0,0 +1,145 @@
+package ECwrap
+
+import (
+	"context"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParallelRho(t *testing.T) {
+	c, _ := dlpCurves(t)
+	rnd := NewTestReader([]byte("parallel rho"))
+	for _, neg := range []bool{false, true} {
+		k, _ := randInt(rnd, c.N)
+		opts := &ParallelRhoOptions{Workers: 4, NegationMap: neg}
+		got, err := c.ParallelRho(context.Background(), c.Generator(), c.ECPScalarBaseMul(k), c.N, rnd, opts)
+		if err != nil || got.Cmp(k) != 0 {
+			t.Fatalf(`ParallelRho(negation map = %t) = %v, expected = %v, err = %v`, neg, got, k, err)
+		}
+	}
+}
+
+// Walk with the negation map must not get stuck
+// in fruitless cycles
+func TestParallelRhoCycles(t *testing.T) {
+	// group is too big for the walk to ever come back for real,
+	// only the fruitless cycles can repeat points
+	c := FromElliptic(elliptic.P256())
+	s := &rhoState{c: c, P: c.Generator(), Q: c.ECPScalarBaseMul(big.NewInt(12345)), n: c.N,
+		opts: &ParallelRhoOptions{NegationMap: true}}
+	rnd := NewTestReader([]byte("cycles"))
+	s.rand = rnd
+	for j := 0; j < rhoPartitions; j++ {
+		a, b, _ := s.randPair()
+		s.a, s.b = append(s.a, a), append(s.b, b)
+		s.R = append(s.R, c.ECPAffine(c.ECPMultiScalarMul([]*ECPoint{s.P, s.Q}, []*big.Int{a, b})))
+	}
+	// the worker's loop without distinguished points
+	a, b := big.NewInt(1), big.NewInt(0)
+	X, anchor := c.Generator(), c.Generator()
+	seen := map[string]bool{}
+	escapes := 0
+	for i := 0; i < 10000; i++ {
+		X = s.next(X, a, b)
+		if i%rhoCycleCheck == 0 {
+			anchor = X
+		} else if c.ECPEqual(X, anchor) {
+			X = s.escape(X, a, b)
+			escapes++
+		}
+		seen[string(c.ECPMarshal(X))] = true
+		if new(big.Int).Lsh(X.Y, 1).Cmp(c.P) > 0 {
+			t.Fatalf(`step %d: X is not canonical`, i)
+		}
+	}
+	if !c.ECPEqual(X, c.ECPMultiScalarMul([]*ECPoint{s.P, s.Q}, []*big.Int{a, b})) {
+		t.Fatalf(`X != a*P + b*Q`)
+	}
+	// each cycle repeats at most rhoCycleCheck points
+	// before it is detected
+	if escapes > 20 || len(seen) < 10000-rhoCycleCheck*escapes {
+		t.Fatalf(`walk visited %d points in 10000 steps, %d escapes`, len(seen), escapes)
+	}
+}
+
+// Walks on P-256 never end, with the automatic and a large
+// number of distinguished bits the deadline must stop them
+func TestParallelRhoCancel(t *testing.T) {
+	c := FromElliptic(elliptic.P256())
+	Q := c.ECPScalarBaseMul(big.NewInt(12345678))
+	for _, bits := range []int{0, 60} {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		done := make(chan error, 1)
+		go func() {
+			opts := &ParallelRhoOptions{Workers: 2, DistinguishedBits: bits}
+			_, err := c.ParallelRho(ctx, c.Generator(), Q, c.N, rand.Reader, opts)
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf(`ParallelRho(bits = %d) error = %v, expected = %v`, bits, err, context.DeadlineExceeded)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf(`ParallelRho(bits = %d) ignored the deadline`, bits)
+		}
+		cancel()
+	}
+}
+
+func TestParallelRhoCheckpoint(t *testing.T) {
+	c, _ := dlpCurves(t)
+	name := filepath.Join(t.TempDir(), "rho.json")
+	k := big.NewInt(31415926)
+	Q := c.ECPScalarBaseMul(k)
+	rnd := NewTestReader([]byte("checkpoint"))
+
+	// stop right after the first progress report
+	ctx, cancel := context.WithCancel(context.Background())
+	opts := &ParallelRhoOptions{Workers: 1, NegationMap: true, DistinguishedBits: 4, Checkpoint: name}
+	opts.ProgressInterval = 256
+	opts.Progress = func(uint64) { cancel() }
+	got, err := c.ParallelRho(ctx, c.Generator(), Q, c.N, rnd, opts)
+	if err == nil {
+		// lucky early collision, nothing to resume
+		if got.Cmp(k) != 0 {
+			t.Fatalf(`ParallelRho() = %v, expected = %v`, got, k)
+		}
+		return
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf(`ParallelRho() error = %v, expected = %v`, err, context.Canceled)
+	}
+	if _, err := os.Stat(name); err != nil {
+		t.Fatalf(`checkpoint is not written: %v`, err)
+	}
+
+	// other Q, same file
+	if _, err := c.ParallelRho(context.Background(), c.Generator(), c.Generator(), c.N, rnd,
+		&ParallelRhoOptions{Checkpoint: name, NegationMap: true}); err == nil {
+		t.Fatalf(`ParallelRho() accepted checkpoint of another logarithm`)
+	}
+
+	first := uint64(0)
+	opts = &ParallelRhoOptions{Workers: 2, NegationMap: true, Checkpoint: name}
+	opts.ProgressInterval = 256
+	opts.Progress = func(n uint64) {
+		if first == 0 {
+			first = n
+		}
+	}
+	got, err = c.ParallelRho(context.Background(), c.Generator(), Q, c.N, rnd, opts)
+	if err != nil || got.Cmp(k) != 0 {
+		t.Fatalf(`resumed ParallelRho() = %v, expected = %v, err = %v`, got, k, err)
+	}
+	// counting goes on from the saved steps
+	if first != 0 && first < 512 {
+		t.Fatalf(`resumed run reported %d steps first`, first)
+	}
+}
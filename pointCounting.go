@@ -0,0 +1,500 @@
+package ECwrap
+
+import (
+	"errors"
+	"io"
+	"math/big"
+)
+
+// Counting the points of a curve: #E(F_p) = p + 1 - t for the
+// trace of Frobenius t, which Hasse's theorem keeps within
+// |t| <= 2*sqrt(p). Three ways to find t, from the simplest:
+//
+//   - naive: sum of Legendre symbols of x^(3) + a*x + b over all x,
+//     O(p), for fields up to 24 bits
+//   - Mestre: orders of random points of the curve and of its
+//     quadratic twist found with baby-step giant-step in the Hasse
+//     interval, O(p^(1/4)), for fields up to 64 bits
+//   - Schoof: t mod l for small primes l from the action of the
+//     Frobenius on the l-torsion, computed with polynomials mod
+//     the division polynomial psi_l, polynomial in log(p)
+//
+// `CountPoints` picks one of them by the size of the field
+
+// Fields up to this many bits are counted with Mestre's method
+// by `CountPoints`, bigger ones with Schoof's
+const mestreMaxBits = 64
+
+// Returns #E(F_p) and the trace of Frobenius t = p + 1 - #E(F_p).
+// rand is used by Mestre's method only
+func (c *Curve) CountPoints(rand io.Reader) (order, trace *big.Int, err error) {
+	switch {
+	case c.P.BitLen() <= 16:
+		trace, err = c.TraceNaive()
+	case c.P.BitLen() <= mestreMaxBits:
+		trace, err = c.TraceMestre(rand)
+	default:
+		trace, err = c.TraceSchoof()
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := c.checkHasse(trace); err != nil {
+		return nil, nil, err
+	}
+	order = new(big.Int).Sub(new(big.Int).Add(c.P, big.NewInt(1)), trace)
+	return order, trace, nil
+}
+
+// Returns error if t^(2) > 4*p
+func (c *Curve) checkHasse(t *big.Int) error {
+	if new(big.Int).Mul(t, t).Cmp(new(big.Int).Lsh(c.P, 2)) > 0 {
+		return errors.New("trace of Frobenius is out of the Hasse bound")
+	}
+	return nil
+}
+
+// Returns floor(2*sqrt(p)), the bound on |t|
+func hasseBound(p *big.Int) *big.Int {
+	return new(big.Int).Sqrt(new(big.Int).Lsh(p, 2))
+}
+
+// Returns the trace of Frobenius counted naively: every x adds
+// 1 + (f(x) / p) points for the Legendre symbol of f(x), so
+// t = -sum (f(x) / p). Fields up to 24 bits
+func (c *Curve) TraceNaive() (*big.Int, error) {
+	if c.P.BitLen() > naiveMaxBits {
+		return nil, errors.New("field is too big for naive counting")
+	}
+	p := c.P.Uint64()
+	a, b := new(big.Int).Mod(c.A, c.P).Uint64(), new(big.Int).Mod(c.B, c.P).Uint64()
+	order := naiveOrder(p, a, b, squareCounts(p))
+	return new(big.Int).SetInt64(int64(p) + 1 - int64(order)), nil
+}
+
+// Returns the trace of Frobenius found with Mestre's method. A
+// random point P of E has some multiple M*P = O with M in the
+// Hasse interval, baby-step giant-step finds it in O(p^(1/4)),
+// and the order of P divides #E = p + 1 - t. Points of the twist
+// give t mod their orders too, as the twist has p + 1 + t points.
+// Once only one t in the Hasse bound fits all of them, that is
+// the trace, which takes a few points for p > 229 (Mestre) and
+// is likely to never happen for smaller p, which are counted
+// naively instead
+func (c *Curve) TraceMestre(rand io.Reader) (*big.Int, error) {
+	p := c.P
+	if p.BitLen() > mestreMaxBits {
+		return nil, errors.New("field is too big for Mestre's method")
+	}
+	if p.Cmp(big.NewInt(229)) <= 0 {
+		return c.TraceNaive()
+	}
+
+	// twist y^(2) = x^(3) + a*d^(2)*x + b*d^(3) for non-square d
+	d := big.NewInt(2)
+	for big.Jacobi(d, p) != -1 {
+		d.Add(d, big.NewInt(1))
+	}
+	dd := new(big.Int).Mul(d, d)
+	twist := &Curve{
+		P: p,
+		A: new(big.Int).Mod(new(big.Int).Mul(c.A, dd), p),
+		B: new(big.Int).Mod(new(big.Int).Mul(c.B, new(big.Int).Mul(dd, d)), p),
+	}
+
+	bound := hasseBound(p)
+	pp1 := new(big.Int).Add(p, big.NewInt(1))
+	// t = r mod m
+	r, m := big.NewInt(0), big.NewInt(1)
+	for try := 0; try < 200; try++ {
+		E, sign := c, int64(1)
+		if try%2 == 1 {
+			E, sign = twist, -1
+		}
+		P, err := E.randAffinePoint(rand)
+		if err != nil {
+			return nil, err
+		}
+		M, err := E.multipleInInterval(P, new(big.Int).Sub(pp1, bound), new(big.Int).Lsh(bound, 1))
+		if err != nil {
+			return nil, err
+		}
+		order := E.orderDividing(P, M)
+		ri := new(big.Int).Mod(new(big.Int).Mul(pp1, big.NewInt(sign)), order)
+		var ok bool
+		if r, m, ok = crtCombine(r, m, ri, order); !ok {
+			return nil, errors.New("orders of the curve and the twist do not agree")
+		}
+
+		// smallest t >= -bound with t = r mod m
+		t := new(big.Int).Add(r, bound)
+		t.Mod(t, m).Sub(t, bound)
+		if t.Cmp(bound) > 0 {
+			return nil, errors.New("orders of the curve and the twist do not agree")
+		}
+		if new(big.Int).Add(t, m).Cmp(bound) > 0 {
+			return t, nil
+		}
+	}
+	return nil, errors.New("trace of Frobenius not found")
+}
+
+// Returns random affine point of the curve (not the point
+// at infinity)
+func (c *Curve) randAffinePoint(rand io.Reader) (*ECPoint, error) {
+	for {
+		x, err := randInt(rand, c.P)
+		if err != nil {
+			return nil, err
+		}
+		y := new(big.Int).ModSqrt(c.Polynomial(x), c.P)
+		if y == nil {
+			continue
+		}
+		return &ECPoint{x, y, big.NewInt(1)}, nil
+	}
+}
+
+// Returns M in [lo, lo + w] with M*P = O, baby-step giant-step
+// with sqrt(w) baby steps
+func (c *Curve) multipleInInterval(P *ECPoint, lo, w *big.Int) (*big.Int, error) {
+	m := new(big.Int).Add(new(big.Int).Sqrt(w), big.NewInt(1))
+	if m.BitLen() > 32 {
+		return nil, errors.New("interval is too wide for baby-step giant-step")
+	}
+	size := m.Int64()
+
+	// j*P = -(lo + i*m)*P for M = lo + i*m + j
+	baby := make(map[string]int64, size)
+	R := PointAtInfinity()
+	for j := int64(0); j < size; j++ {
+		key := string(c.ECPMarshal(R))
+		if _, ok := baby[key]; !ok {
+			baby[key] = j
+		}
+		R = c.ECPAdd(R, P)
+	}
+	mP := c.ECPScalarMul(P, m)
+	R = c.ECPNeg(c.ECPScalarMul(P, lo))
+	for i := int64(0); i <= size; i++ {
+		if j, ok := baby[string(c.ECPMarshal(R))]; ok {
+			M := new(big.Int).Mul(big.NewInt(i), m)
+			return M.Add(M, lo).Add(M, big.NewInt(j)), nil
+		}
+		R = c.ECPSub(R, mP)
+	}
+	return nil, errors.New("no multiple of the order in the interval")
+}
+
+// Returns x mod lcm(m1, m2) with x = r1 mod m1 and x = r2 mod m2,
+// false if there is no such x
+func crtCombine(r1, m1, r2, m2 *big.Int) (*big.Int, *big.Int, bool) {
+	g := new(big.Int).GCD(nil, nil, m1, m2)
+	diff := new(big.Int).Sub(r2, r1)
+	if new(big.Int).Mod(diff, g).Sign() != 0 {
+		return nil, nil, false
+	}
+	// x = r1 + m1*k, k = (r2 - r1)/g * (m1/g)^(-1) mod m2/g
+	m2g := new(big.Int).Quo(m2, g)
+	k := new(big.Int).Mul(diff.Quo(diff, g), new(big.Int).ModInverse(new(big.Int).Quo(m1, g), m2g))
+	lcm := new(big.Int).Mul(m1, m2g)
+	x := new(big.Int).Add(r1, new(big.Int).Mul(m1, k.Mod(k, m2g)))
+	return x.Mod(x, lcm), lcm, true
+}
+
+// Returns the trace of Frobenius found with Schoof's algorithm.
+// For small primes l the Frobenius pi(x, y) = (x^(p), y^(p))
+// satisfies pi^(2) - t*pi + p = 0 on the l-torsion, so t mod l is
+// the tau with pi^(2)(P) + (p mod l)*P = tau*pi(P) for the generic
+// l-torsion point P, whose coordinates live in F_p[x, y] modulo
+// psi_l(x) and the curve equation. Primes are taken until their
+// product exceeds 4*sqrt(p), then t is glued with CRT.
+//
+// Far slower than SEA, used by real point counting software:
+// a 64-bit field takes seconds, a 112-bit one minutes and
+// a 256-bit one hours
+func (c *Curve) TraceSchoof() (*big.Int, error) {
+	p := c.P
+	// |t| <= bound, so t mod m > 2*bound + 1 is enough
+	width := new(big.Int).Lsh(new(big.Int).Add(hasseBound(p), big.NewInt(1)), 1)
+
+	// t mod 2: t is even iff there is a point of order 2,
+	// that is x^(3) + a*x + b has a root
+	f := c.curvePoly()
+	x := []*big.Int{big.NewInt(0), big.NewInt(1)}
+	r, m := big.NewInt(1), big.NewInt(2)
+	if len(polyGcd(f, polySub(polyPowMod(x, p, f, p), x, p), p)) > 1 {
+		r.SetInt64(0)
+	}
+
+	var primes []int64
+	prod := big.NewInt(2)
+	for l := int64(3); prod.Cmp(width) <= 0; l += 2 {
+		if !big.NewInt(l).ProbablyPrime(1) || p.Cmp(big.NewInt(l)) == 0 {
+			continue
+		}
+		primes = append(primes, l)
+		prod.Mul(prod, big.NewInt(l))
+	}
+	if len(primes) == 0 {
+		return r, nil
+	}
+	psi := c.divisionPolynomials(int(primes[len(primes)-1]))
+	for _, l := range primes {
+		tau, err := c.schoofTrace(psi[l], l)
+		if err != nil {
+			return nil, err
+		}
+		r, m, _ = crtCombine(r, m, big.NewInt(tau), big.NewInt(l))
+	}
+
+	// t is the residue closest to zero
+	if new(big.Int).Lsh(r, 1).Cmp(m) > 0 {
+		r.Sub(r, m)
+	}
+	if err := c.checkHasse(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Returns x^(3) + a*x + b as a polynomial
+func (c *Curve) curvePoly() []*big.Int {
+	return polyTrim([]*big.Int{new(big.Int).Mod(c.B, c.P), new(big.Int).Mod(c.A, c.P), big.NewInt(0), big.NewInt(1)})
+}
+
+// Returns the n-th division polynomial psi_n, n >= 0, for
+// even n divided by y to make it a polynomial in x alone.
+// Its roots are the x-coordinates of the points P with
+// n*P = O, except for P = O and, for even n, points of order 2.
+// Some of the roots may have no y in F_p, those points are
+// over F_(p^2). Degree is (n^(2) - 1)/2 for odd n and
+// (n^(2) - 4)/2 for even n
+func (c *Curve) DivisionPolynomial(n int) *Poly {
+	if n < 0 {
+		panic("ECwrap: negative index of division polynomial")
+	}
+	g := c.divisionPolynomials(n)[n]
+	if n%2 == 0 {
+		g = polyScale(g, big.NewInt(2), c.P)
+	}
+	return &Poly{p: new(big.Int).Set(c.P), c: g}
+}
+
+// Returns g_0, ..., g_n with the division polynomials
+// psi_k = g_k for odd k and psi_k = 2*y*g_k for even k, so
+// that all g_k are polynomials in x
+func (c *Curve) divisionPolynomials(n int) [][]*big.Int {
+	p := c.P
+	a, b := new(big.Int).Mod(c.A, p), new(big.Int).Mod(c.B, p)
+	num := func(k int64) *big.Int { return new(big.Int).Mod(big.NewInt(k), p) }
+	mod := func(v *big.Int) *big.Int { return v.Mod(v, p) }
+	aa, bb := new(big.Int).Mul(a, a), new(big.Int).Mul(b, b)
+	aaa := new(big.Int).Mul(aa, a)
+
+	g := make([][]*big.Int, max(n+1, 5))
+	g[0] = nil
+	g[1] = []*big.Int{big.NewInt(1)}
+	g[2] = []*big.Int{big.NewInt(1)}
+	// 3*x^(4) + 6*a*x^(2) + 12*b*x - a^(2)
+	g[3] = polyTrim([]*big.Int{
+		mod(new(big.Int).Neg(aa)), mod(new(big.Int).Mul(num(12), b)),
+		mod(new(big.Int).Mul(num(6), a)), big.NewInt(0), num(3),
+	})
+	// 2*(x^(6) + 5*a*x^(4) + 20*b*x^(3) - 5*a^(2)*x^(2) - 4*a*b*x - 8*b^(2) - a^(3))
+	g[4] = polyScale([]*big.Int{
+		mod(new(big.Int).Sub(new(big.Int).Mul(num(-8), bb), aaa)),
+		mod(new(big.Int).Mul(num(-4), new(big.Int).Mul(a, b))),
+		mod(new(big.Int).Mul(num(-5), aa)),
+		mod(new(big.Int).Mul(num(20), b)),
+		mod(new(big.Int).Mul(num(5), a)),
+		big.NewInt(0), big.NewInt(1),
+	}, big.NewInt(2), p)
+
+	f := c.curvePoly()
+	// (2*y)^(4) = 16*f^(2)
+	f16 := polyScale(polyMul(f, f, p), big.NewInt(16), p)
+	cube := func(h []*big.Int) []*big.Int { return polyMul(polyMul(h, h, p), h, p) }
+	sq := func(h []*big.Int) []*big.Int { return polyMul(h, h, p) }
+	for k := 5; k <= n; k++ {
+		m := k / 2
+		if k%2 == 1 {
+			// psi_(2m+1) = psi_(m+2)*psi_m^(3) - psi_(m-1)*psi_(m+1)^(3)
+			s := polyMul(g[m+2], cube(g[m]), p)
+			t := polyMul(g[m-1], cube(g[m+1]), p)
+			if m%2 == 0 {
+				s = polyMul(f16, s, p)
+			} else {
+				t = polyMul(f16, t, p)
+			}
+			g[k] = polySub(s, t, p)
+		} else {
+			// psi_(2m) = psi_m*(psi_(m+2)*psi_(m-1)^(2) - psi_(m-2)*psi_(m+1)^(2))/(2*y)
+			s := polyMul(g[m+2], sq(g[m-1]), p)
+			t := polyMul(g[m-2], sq(g[m+1]), p)
+			g[k] = polyMul(g[m], polySub(s, t, p), p)
+		}
+	}
+	return g[:n+1]
+}
+
+// Point (x(X), y*y(X)) in the ring F_p[X]/(h) for a factor h of
+// psi_l, the generic l-torsion point is (X, y)
+type schoofPoint struct {
+	x, y []*big.Int
+	inf  bool
+}
+
+// Returned when the ring turns out not to be a field: h has
+// a proper factor g, which is just as good a modulus
+type schoofFactor struct {
+	g []*big.Int
+}
+
+func (e *schoofFactor) Error() string {
+	return "factor of the division polynomial found"
+}
+
+type schoofRing struct {
+	p  *big.Int
+	a  *big.Int
+	f  []*big.Int
+	pm *polyModulus
+}
+
+func (r *schoofRing) inv(h []*big.Int) ([]*big.Int, error) {
+	inv, g := polyInvMod(h, r.pm.m, r.p)
+	if inv == nil {
+		return nil, &schoofFactor{g}
+	}
+	return inv, nil
+}
+
+func (r *schoofRing) add(P, Q *schoofPoint) (*schoofPoint, error) {
+	if P.inf {
+		return Q, nil
+	}
+	if Q.inf {
+		return P, nil
+	}
+	dx := polySub(Q.x, P.x, r.p)
+	if len(dx) == 0 {
+		dy, sy := polySub(Q.y, P.y, r.p), polyAdd(Q.y, P.y, r.p)
+		switch {
+		case len(dy) == 0:
+			return r.double(P)
+		case len(sy) == 0:
+			return &schoofPoint{inf: true}, nil
+		}
+		// y1 = y2 at some of the points, y1 = -y2 at others
+		return nil, &schoofFactor{polyGcd(dy, r.pm.m, r.p)}
+	}
+	inv, err := r.inv(dx)
+	if err != nil {
+		return nil, err
+	}
+	// lambda = y*l, x3 = l^(2)*f - x1 - x2, y3 = y*(l*(x1 - x3) - y1)
+	l := r.pm.mul(polySub(Q.y, P.y, r.p), inv)
+	x3 := polySub(polySub(r.pm.mul(r.pm.mul(l, l), r.f), P.x, r.p), Q.x, r.p)
+	y3 := polySub(r.pm.mul(l, polySub(P.x, x3, r.p)), P.y, r.p)
+	return &schoofPoint{x: x3, y: y3}, nil
+}
+
+func (r *schoofRing) double(P *schoofPoint) (*schoofPoint, error) {
+	if P.inf || len(P.y) == 0 {
+		return &schoofPoint{inf: true}, nil
+	}
+	// lambda = (3*x^(2) + a)/(2*y*y1) = y*(3*x^(2) + a)/(2*f*y1)
+	inv, err := r.inv(r.pm.mul(polyScale(P.y, big.NewInt(2), r.p), r.f))
+	if err != nil {
+		return nil, err
+	}
+	num := polyAdd(polyScale(r.pm.mul(P.x, P.x), big.NewInt(3), r.p), polyTrim([]*big.Int{r.a}), r.p)
+	l := r.pm.mul(num, inv)
+	x3 := polySub(r.pm.mul(r.pm.mul(l, l), r.f), polyScale(P.x, big.NewInt(2), r.p), r.p)
+	y3 := polySub(r.pm.mul(l, polySub(P.x, x3, r.p)), P.y, r.p)
+	return &schoofPoint{x: x3, y: y3}, nil
+}
+
+func (r *schoofRing) mul(P *schoofPoint, k int64) (*schoofPoint, error) {
+	R := &schoofPoint{inf: true}
+	for i := 62; i >= 0; i-- {
+		var err error
+		if R, err = r.double(R); err != nil {
+			return nil, err
+		}
+		if k>>i&1 == 1 {
+			if R, err = r.add(R, P); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return R, nil
+}
+
+// Returns t mod l, psi is the l-th division polynomial
+func (c *Curve) schoofTrace(psi []*big.Int, l int64) (int64, error) {
+	p := c.P
+	r := &schoofRing{p: p, a: new(big.Int).Mod(c.A, p), pm: newPolyModulus(psi, p)}
+	r.f = r.pm.reduce(c.curvePoly())
+	x := r.pm.reduce([]*big.Int{big.NewInt(0), big.NewInt(1)})
+	// pi(X, y) = (X^(p), y*f^((p - 1)/2)), pi^(2) is pi applied
+	// to the coordinates of pi
+	xp := r.pm.pow(x, p)
+	yp := r.pm.pow(r.f, new(big.Int).Rsh(p, 1))
+	xpp := r.pm.pow(xp, p)
+	ypp := r.pm.mul(yp, r.pm.pow(yp, p))
+	for {
+		tau, err := r.frobeniusTrace(x, xp, yp, xpp, ypp, l)
+		var factor *schoofFactor
+		if !errors.As(err, &factor) {
+			return tau, err
+		}
+		// go on with the smaller factor of the modulus
+		h := factor.g
+		if other := polyDiv(r.pm.m, h, p); len(other) < len(h) {
+			h = other
+		}
+		r.pm = newPolyModulus(h, p)
+		r.f, x = r.pm.reduce(r.f), r.pm.reduce(x)
+		xp, yp, xpp, ypp = r.pm.reduce(xp), r.pm.reduce(yp), r.pm.reduce(xpp), r.pm.reduce(ypp)
+	}
+}
+
+// Returns tau in [0, l - 1] with pi^(2) + (p mod l) = tau*pi
+func (r *schoofRing) frobeniusTrace(x, xp, yp, xpp, ypp []*big.Int, l int64) (int64, error) {
+	q := new(big.Int).Mod(r.p, big.NewInt(l)).Int64()
+	Q, err := r.mul(&schoofPoint{x: x, y: []*big.Int{big.NewInt(1)}}, q)
+	if err != nil {
+		return 0, err
+	}
+	S, err := r.add(&schoofPoint{x: xpp, y: ypp}, Q)
+	if err != nil {
+		return 0, err
+	}
+	if S.inf {
+		return 0, nil
+	}
+	pi := &schoofPoint{x: xp, y: yp}
+	J := pi
+	for j := int64(1); j <= (l-1)/2; j++ {
+		if j > 1 {
+			if J, err = r.add(J, pi); err != nil {
+				return 0, err
+			}
+		}
+		// x(S) - x(j*pi) is a unit unless tau = +-j, psi_l has
+		// no repeated roots
+		if len(polySub(S.x, J.x, r.p)) != 0 {
+			continue
+		}
+		switch {
+		case len(polySub(S.y, J.y, r.p)) == 0:
+			return j, nil
+		case len(polyAdd(S.y, J.y, r.p)) == 0:
+			return l - j, nil
+		}
+		return 0, errors.New("points with the same x and different y")
+	}
+	return 0, errors.New("trace mod l not found")
+}
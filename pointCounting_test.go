@@ -0,0 +1,67 @@
+package ECwrap
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestTraceNaive(t *testing.T) {
+	// toy97 has 100 points
+	got, err := toyCurve(t).TraceNaive()
+	if err != nil || got.Int64() != -2 {
+		t.Fatalf(`TraceNaive() = %v, expected = -2, err = %v`, got, err)
+	}
+	c, err := GenerateToyCurve(16, 4, NewTestReader([]byte("naive")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	points, _ := c.Points()
+	order, trace, err := c.CountPoints(nil)
+	if err != nil || order.Int64() != int64(len(points)) {
+		t.Fatalf(`CountPoints() = %v, expected = %d, err = %v`, order, len(points), err)
+	}
+	if new(big.Int).Add(order, trace).Cmp(new(big.Int).Add(c.P, big.NewInt(1))) != 0 {
+		t.Fatalf(`order + trace != p + 1`)
+	}
+}
+
+func TestTraceMestreSchoof(t *testing.T) {
+	prime, smooth := dlpCurves(t)
+	rnd := NewTestReader([]byte("mestre"))
+	curves := []*Curve{prime, smooth, toyCurve(t)}
+	for _, bits := range []int{12, 20, 24} {
+		c, err := GenerateToyCurve(bits, 1, rnd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		curves = append(curves, c)
+	}
+	for _, c := range curves {
+		expected := new(big.Int).Sub(new(big.Int).Add(c.P, big.NewInt(1)), new(big.Int).Mul(c.N, c.H))
+		got, err := c.TraceMestre(rnd)
+		if err != nil || got.Cmp(expected) != 0 {
+			t.Fatalf(`%s: TraceMestre() = %v, expected = %v, err = %v`, c.Name, got, expected, err)
+		}
+		got, err = c.TraceSchoof()
+		if err != nil || got.Cmp(expected) != 0 {
+			t.Fatalf(`%s: TraceSchoof() = %v, expected = %v, err = %v`, c.Name, got, expected, err)
+		}
+	}
+}
+
+// Curve over 2^48 - 59, both methods must agree
+func TestTraceSchoof48(t *testing.T) {
+	p := fromHex("ffffffffffc5")
+	c := &Curve{P: p, A: big.NewInt(-3), B: fromHex("5ac635d8aa3a")}
+	mestre, err := c.TraceMestre(NewTestReader([]byte("schoof")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	schoof, err := c.TraceSchoof()
+	if err != nil || schoof.Cmp(mestre) != 0 {
+		t.Fatalf(`TraceSchoof() = %v, expected = %v, err = %v`, schoof, mestre, err)
+	}
+	if err := c.checkHasse(new(big.Int).Add(hasseBound(p), big.NewInt(1))); err == nil {
+		t.Fatalf(`checkHasse() accepted trace above the bound`)
+	}
+}
@@ -0,0 +1,512 @@
+package ECwrap
+
+import (
+	"errors"
+	"math/big"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// Polynomials over F_p. `Poly` is the exported immutable type,
+// inside the package polynomials are plain slices of coefficients
+// from the lowest degree, without leading zeroes, always reduced
+// mod p, and the helpers below work on them. Products of big
+// polynomials (such as the division polynomials of Schoof's
+// algorithm) go through Kronecker substitution: both are packed
+// into one big number, multiplied by math/big and unpacked again
+
+// Polynomials shorter than this are multiplied schoolbook
+const polyKroneckerCutoff = 16
+
+// Polynomial over F_p. Polys are immutable, arithmetic returns
+// new ones. Mixing polynomials over different fields panics
+type Poly struct {
+	p *big.Int
+	c []*big.Int
+}
+
+// Returns polynomial over F_p with given coefficients, from the
+// constant term up. Coefficients are reduced mod p and copied
+func NewPoly(p *big.Int, coeffs ...*big.Int) *Poly {
+	c := make([]*big.Int, len(coeffs))
+	for i := range coeffs {
+		c[i] = new(big.Int).Mod(coeffs[i], p)
+	}
+	return &Poly{p: new(big.Int).Set(p), c: polyTrim(c)}
+}
+
+// Returns x over F_p
+func PolyX(p *big.Int) *Poly {
+	return NewPoly(p, big.NewInt(0), big.NewInt(1))
+}
+
+func (f *Poly) wrap(c []*big.Int) *Poly {
+	return &Poly{p: f.p, c: c}
+}
+
+func (f *Poly) check(g *Poly) {
+	if f.p.Cmp(g.p) != 0 {
+		panic("ECwrap: polynomials over different fields")
+	}
+}
+
+// Returns p, the order of the field
+func (f *Poly) Field() *big.Int {
+	return new(big.Int).Set(f.p)
+}
+
+// Returns degree of f, -1 for zero
+func (f *Poly) Degree() int {
+	return len(f.c) - 1
+}
+
+// Returns copy of the coefficient of x^(i)
+func (f *Poly) Coeff(i int) *big.Int {
+	if i < 0 || i >= len(f.c) {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(f.c[i])
+}
+
+// Returns copies of the coefficients from the constant term
+// up, without leading zeroes (empty for zero)
+func (f *Poly) Coeffs() []*big.Int {
+	out := make([]*big.Int, len(f.c))
+	for i := range f.c {
+		out[i] = new(big.Int).Set(f.c[i])
+	}
+	return out
+}
+
+// Reports whether f is zero
+func (f *Poly) IsZero() bool {
+	return len(f.c) == 0
+}
+
+// Reports whether f == g
+func (f *Poly) Equal(g *Poly) bool {
+	return f.p.Cmp(g.p) == 0 && len(polySub(f.c, g.c, f.p)) == 0
+}
+
+// Returns f + g
+func (f *Poly) Add(g *Poly) *Poly {
+	f.check(g)
+	return f.wrap(polyAdd(f.c, g.c, f.p))
+}
+
+// Returns f - g
+func (f *Poly) Sub(g *Poly) *Poly {
+	f.check(g)
+	return f.wrap(polySub(f.c, g.c, f.p))
+}
+
+// Returns -f
+func (f *Poly) Neg() *Poly {
+	return f.wrap(polyNeg(f.c, f.p))
+}
+
+// Returns f * g
+func (f *Poly) Mul(g *Poly) *Poly {
+	f.check(g)
+	return f.wrap(polyMul(f.c, g.c, f.p))
+}
+
+// Returns k * f
+func (f *Poly) Scale(k *big.Int) *Poly {
+	return f.wrap(polyScale(f.c, k, f.p))
+}
+
+// Returns quotient and remainder of f / g. Panics if g is zero
+func (f *Poly) DivMod(g *Poly) (*Poly, *Poly) {
+	f.check(g)
+	if g.IsZero() {
+		panic("ECwrap: division by zero polynomial")
+	}
+	q, r := polyDivMod(f.c, g.c, f.p)
+	return f.wrap(q), f.wrap(r)
+}
+
+// Returns quotient of f / g
+func (f *Poly) Div(g *Poly) *Poly {
+	q, _ := f.DivMod(g)
+	return q
+}
+
+// Returns f mod g
+func (f *Poly) Mod(g *Poly) *Poly {
+	_, r := f.DivMod(g)
+	return r
+}
+
+// Returns monic f, zero for zero
+func (f *Poly) Monic() *Poly {
+	return f.wrap(polyGcd(f.c, nil, f.p))
+}
+
+// Returns monic greatest common divisor, zero if both are zero
+func (f *Poly) Gcd(g *Poly) *Poly {
+	f.check(g)
+	return f.wrap(polyGcd(f.c, g.c, f.p))
+}
+
+// Returns f^(e) mod m, e >= 0. Panics if m is zero
+func (f *Poly) PowMod(e *big.Int, m *Poly) *Poly {
+	f.check(m)
+	if m.IsZero() {
+		panic("ECwrap: division by zero polynomial")
+	}
+	return f.wrap(polyPowMod(f.c, e, m.c, f.p))
+}
+
+// Returns f^(-1) mod m, or error if f and m are not coprime
+func (f *Poly) InvMod(m *Poly) (*Poly, error) {
+	f.check(m)
+	if m.Degree() < 1 {
+		return nil, errors.New("modulus must not be constant")
+	}
+	inv, _ := polyInvMod(f.c, m.c, f.p)
+	if inv == nil {
+		return nil, errors.New("polynomial is not invertible")
+	}
+	return f.wrap(inv), nil
+}
+
+// Returns f(x)
+func (f *Poly) Eval(x *big.Int) *big.Int {
+	return polyEval(f.c, x, f.p)
+}
+
+// Returns distinct roots of f in F_p in ascending order,
+// see `polyRoots`
+func (f *Poly) Roots() []*big.Int {
+	return polyRoots(f.c, f.p)
+}
+
+// Returns f as text, highest degree first, e.g. x^3 + 2*x + 5
+func (f *Poly) String() string {
+	if len(f.c) == 0 {
+		return "0"
+	}
+	var terms []string
+	for i := len(f.c) - 1; i >= 0; i-- {
+		c := f.c[i]
+		if c.Sign() == 0 {
+			continue
+		}
+		var t string
+		switch {
+		case i == 0:
+			t = c.String()
+		case c.Cmp(big.NewInt(1)) == 0:
+			t = "x"
+		default:
+			t = c.String() + "*x"
+		}
+		if i > 1 {
+			t += "^" + strconv.Itoa(i)
+		}
+		terms = append(terms, t)
+	}
+	return strings.Join(terms, " + ")
+}
+
+// Returns distinct roots of f in F_p in ascending order. Roots in
+// F_p are the roots of gcd(f, x^(p) - x), which is split further
+// with Cantor-Zassenhaus if needed
+func polyRoots(f []*big.Int, p *big.Int) []*big.Int {
+	f = polyTrim(f)
+	if len(f) < 2 {
+		return nil
+	}
+	x := []*big.Int{big.NewInt(0), big.NewInt(1)}
+
+	// x^(p) - x mod f
+	xp := polyPowMod(x, p, f, p)
+	g := polyGcd(f, polySub(xp, x, p), p)
+
+	var roots []*big.Int
+	var split func(g []*big.Int)
+	split = func(g []*big.Int) {
+		switch len(g) - 1 {
+		case 0:
+			return
+		case 1:
+			// monic x + g0
+			roots = append(roots, new(big.Int).Mod(new(big.Int).Neg(g[0]), p))
+			return
+		}
+		// gcd((x + delta)^((p - 1)/2) - 1, g) is a proper divisor
+		// of g for about half of delta
+		e := new(big.Int).Rsh(p, 1)
+		for delta := int64(0); big.NewInt(delta).Cmp(p) < 0; delta++ {
+			h := polyPowMod([]*big.Int{big.NewInt(delta), big.NewInt(1)}, e, g, p)
+			h = polyGcd(g, polySub(h, []*big.Int{big.NewInt(1)}, p), p)
+			if len(h) > 1 && len(h) < len(g) {
+				split(h)
+				split(polyDiv(g, h, p))
+				return
+			}
+		}
+	}
+	split(g)
+
+	for i := range roots {
+		for j := i + 1; j < len(roots); j++ {
+			if roots[j].Cmp(roots[i]) < 0 {
+				roots[i], roots[j] = roots[j], roots[i]
+			}
+		}
+	}
+	return roots
+}
+
+func polyTrim(f []*big.Int) []*big.Int {
+	for len(f) > 0 && f[len(f)-1].Sign() == 0 {
+		f = f[:len(f)-1]
+	}
+	return f
+}
+
+func polyAdd(f, g []*big.Int, p *big.Int) []*big.Int {
+	return polySub(f, polyNeg(g, p), p)
+}
+
+func polyNeg(f []*big.Int, p *big.Int) []*big.Int {
+	return polyScale(f, big.NewInt(-1), p)
+}
+
+// Returns c*f
+func polyScale(f []*big.Int, c, p *big.Int) []*big.Int {
+	out := make([]*big.Int, len(f))
+	for i := range f {
+		out[i] = new(big.Int).Mod(new(big.Int).Mul(f[i], c), p)
+	}
+	return polyTrim(out)
+}
+
+func polySub(f, g []*big.Int, p *big.Int) []*big.Int {
+	n := max(len(f), len(g))
+	out := make([]*big.Int, n)
+	for i := range out {
+		out[i] = new(big.Int)
+		if i < len(f) {
+			out[i].Add(out[i], f[i])
+		}
+		if i < len(g) {
+			out[i].Sub(out[i], g[i])
+		}
+		out[i].Mod(out[i], p)
+	}
+	return polyTrim(out)
+}
+
+// Returns quotient and remainder of f / g, g != 0
+func polyDivMod(f, g []*big.Int, p *big.Int) ([]*big.Int, []*big.Int) {
+	r := make([]*big.Int, len(f))
+	for i := range f {
+		r[i] = new(big.Int).Mod(f[i], p)
+	}
+	r = polyTrim(r)
+	if len(r) < len(g) {
+		return nil, r
+	}
+	q := make([]*big.Int, len(r)-len(g)+1)
+	lcInv := new(big.Int).ModInverse(g[len(g)-1], p)
+	t := new(big.Int)
+	for i := len(q) - 1; i >= 0; i-- {
+		c := new(big.Int).Mul(r[i+len(g)-1], lcInv)
+		q[i] = c.Mod(c, p)
+		for j := range g {
+			r[i+j].Sub(r[i+j], t.Mul(c, g[j])).Mod(r[i+j], p)
+		}
+	}
+	return polyTrim(q), polyTrim(r[:len(g)-1])
+}
+
+func polyDiv(f, g []*big.Int, p *big.Int) []*big.Int {
+	q, _ := polyDivMod(f, g, p)
+	return q
+}
+
+// Returns f(x) mod p, Horner's rule
+func polyEval(f []*big.Int, x, p *big.Int) *big.Int {
+	r := new(big.Int)
+	for i := len(f) - 1; i >= 0; i-- {
+		r.Mul(r, x).Add(r, f[i]).Mod(r, p)
+	}
+	return r
+}
+
+// Returns f*g mod m
+func polyMulMod(f, g, m []*big.Int, p *big.Int) []*big.Int {
+	_, r := polyDivMod(polyMul(f, g, p), m, p)
+	return r
+}
+
+// Returns f^(e) mod m
+func polyPowMod(f []*big.Int, e *big.Int, m []*big.Int, p *big.Int) []*big.Int {
+	return newPolyModulus(m, p).pow(f, e)
+}
+
+// Returns monic gcd of f and g
+func polyGcd(f, g []*big.Int, p *big.Int) []*big.Int {
+	f, g = polyTrim(f), polyTrim(g)
+	for len(g) > 0 {
+		_, r := polyDivMod(f, g, p)
+		f, g = g, r
+	}
+	if len(f) == 0 {
+		return f
+	}
+	lcInv := new(big.Int).ModInverse(f[len(f)-1], p)
+	out := make([]*big.Int, len(f))
+	for i := range f {
+		out[i] = new(big.Int).Mod(new(big.Int).Mul(f[i], lcInv), p)
+	}
+	return out
+}
+
+// Returns f*g
+func polyMul(f, g []*big.Int, p *big.Int) []*big.Int {
+	if len(f) == 0 || len(g) == 0 {
+		return nil
+	}
+	if min(len(f), len(g)) < polyKroneckerCutoff {
+		out := make([]*big.Int, len(f)+len(g)-1)
+		for i := range out {
+			out[i] = new(big.Int)
+		}
+		t := new(big.Int)
+		for i := range f {
+			for j := range g {
+				out[i+j].Add(out[i+j], t.Mul(f[i], g[j]))
+			}
+		}
+		for i := range out {
+			out[i].Mod(out[i], p)
+		}
+		return polyTrim(out)
+	}
+
+	// coefficients of f*g are below min(len(f), len(g))*p^(2),
+	// each gets a slot of whole words
+	slot := (2*p.BitLen() + bits.Len(uint(min(len(f), len(g)))) + bits.UintSize - 1) / bits.UintSize
+	pack := func(f []*big.Int) *big.Int {
+		words := make([]big.Word, len(f)*slot)
+		for i, c := range f {
+			if c.Sign() < 0 || c.Cmp(p) >= 0 {
+				c = new(big.Int).Mod(c, p)
+			}
+			copy(words[i*slot:], c.Bits())
+		}
+		return new(big.Int).SetBits(words)
+	}
+	words := new(big.Int).Mul(pack(f), pack(g)).Bits()
+	out := make([]*big.Int, len(f)+len(g)-1)
+	for i := range out {
+		lo, hi := min(i*slot, len(words)), min((i+1)*slot, len(words))
+		out[i] = new(big.Int).Mod(new(big.Int).SetBits(words[lo:hi:hi]), p)
+	}
+	return polyTrim(out)
+}
+
+// Returns f^(-1) mod m. If f is not invertible, nil and the
+// monic gcd of f and m are returned instead, which is either
+// m (f = 0 mod m) or a proper factor of m
+func polyInvMod(f, m []*big.Int, p *big.Int) ([]*big.Int, []*big.Int) {
+	_, r1 := polyDivMod(f, m, p)
+	r0 := m
+	s0, s1 := []*big.Int(nil), []*big.Int{big.NewInt(1)}
+	// s_i*f = r_i mod m
+	for len(r1) > 0 {
+		q, r := polyDivMod(r0, r1, p)
+		r0, r1 = r1, r
+		s0, s1 = s1, polySub(s0, polyMul(q, s1, p), p)
+	}
+	if len(r0) > 1 {
+		return nil, polyGcd(r0, nil, p)
+	}
+	return polyScale(s0, new(big.Int).ModInverse(r0[0], p), p), nil
+}
+
+// Fixed modulus m of degree d with precomputed inverse of its
+// reversal mod x^(d - 1), so that products of two reduced
+// polynomials are reduced with two multiplications instead of
+// long division (Barrett's reduction for polynomials)
+type polyModulus struct {
+	p   *big.Int
+	m   []*big.Int
+	inv []*big.Int
+}
+
+func newPolyModulus(m []*big.Int, p *big.Int) *polyModulus {
+	m = polyGcd(m, nil, p)
+	d := len(m) - 1
+	rev := polyReverse(m, d)
+	// Newton's iteration g = g*(2 - rev*g) doubles the precision
+	inv := []*big.Int{big.NewInt(1)}
+	for k := 1; k < d-1; {
+		k = min(2*k, d-1)
+		e := polyTrunc(polyMul(polyTrunc(rev, k), inv, p), k)
+		e = polySub([]*big.Int{big.NewInt(2)}, e, p)
+		inv = polyTrunc(polyMul(inv, e, p), k)
+	}
+	return &polyModulus{p: p, m: m, inv: inv}
+}
+
+// Returns f mod m
+func (pm *polyModulus) reduce(f []*big.Int) []*big.Int {
+	d := len(pm.m) - 1
+	if len(f) <= d {
+		return f
+	}
+	if len(f) > 2*d-1 {
+		_, r := polyDivMod(f, pm.m, pm.p)
+		return r
+	}
+	// reversed quotient is the reversed f times the inverse,
+	// mod x^(k) for quotient of k coefficients
+	k := len(f) - d
+	revF := polyReverse(f, len(f)-1)
+	q := polyReverse(polyTrunc(polyMul(polyTrunc(revF, k), pm.inv, pm.p), k), k-1)
+	r := polySub(f, polyMul(q, pm.m, pm.p), pm.p)
+	return polyTrunc(r, d)
+}
+
+func (pm *polyModulus) mul(f, g []*big.Int) []*big.Int {
+	return pm.reduce(polyMul(f, g, pm.p))
+}
+
+// Returns f^(e) mod m
+func (pm *polyModulus) pow(f []*big.Int, e *big.Int) []*big.Int {
+	base := pm.reduce(f)
+	out := pm.reduce([]*big.Int{big.NewInt(1)})
+	for i := e.BitLen() - 1; i >= 0; i-- {
+		out = pm.mul(out, out)
+		if e.Bit(i) == 1 {
+			out = pm.mul(out, base)
+		}
+	}
+	return out
+}
+
+// Returns x^(n)*f(1/x), f of degree at most n
+func polyReverse(f []*big.Int, n int) []*big.Int {
+	out := make([]*big.Int, n+1)
+	for i := range out {
+		out[i] = new(big.Int)
+		if n-i < len(f) {
+			out[i].Set(f[n-i])
+		}
+	}
+	return polyTrim(out)
+}
+
+// Returns f mod x^(k)
+func polyTrunc(f []*big.Int, k int) []*big.Int {
+	if len(f) > k {
+		f = f[:k]
+	}
+	return polyTrim(f)
+}
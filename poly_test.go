@@ -0,0 +1,142 @@
+package ECwrap
+
+import (
+	"math/big"
+	"testing"
+)
+
+func randPoly(t *testing.T, rnd *HMACDRBG, n int, p *big.Int) []*big.Int {
+	f := make([]*big.Int, n)
+	for i := range f {
+		f[i], _ = randInt(rnd, p)
+	}
+	f[n-1] = big.NewInt(1)
+	return f
+}
+
+// Kronecker substitution and Barrett's reduction against
+// schoolbook multiplication and long division
+func TestPolyMul(t *testing.T) {
+	p := fromHex("ffffffff00000001000000000000000000000000ffffffffffffffffffffffff")
+	rnd := NewTestReader([]byte("poly"))
+	for _, n := range []int{3, 17, 100} {
+		f, g, m := randPoly(t, rnd, n, p), randPoly(t, rnd, n+5, p), randPoly(t, rnd, n+3, p)
+		// schoolbook product
+		expected := make([]*big.Int, 2*n+4)
+		for i := range expected {
+			expected[i] = new(big.Int)
+		}
+		for i := range f {
+			for j := range g {
+				expected[i+j].Add(expected[i+j], new(big.Int).Mul(f[i], g[j])).Mod(expected[i+j], p)
+			}
+		}
+		got := polyMul(f, g, p)
+		if len(polySub(got, expected, p)) != 0 {
+			t.Fatalf(`polyMul() of length %d is wrong`, n)
+		}
+		_, r := polyDivMod(got, m, p)
+		_, fr := polyDivMod(f, m, p)
+		_, gr := polyDivMod(g, m, p)
+		if len(polySub(newPolyModulus(m, p).mul(fr, gr), r, p)) != 0 {
+			t.Fatalf(`polyModulus.mul() of length %d is wrong`, n)
+		}
+		inv, _ := polyInvMod(f, m, p)
+		if one := polyMulMod(inv, f, m, p); len(one) != 1 || one[0].Int64() != 1 {
+			t.Fatalf(`polyInvMod() of length %d is wrong`, n)
+		}
+	}
+}
+
+func TestPoly(t *testing.T) {
+	p := big.NewInt(1000003)
+	x := PolyX(p)
+	lin := func(r int64) *Poly { return x.Sub(NewPoly(p, big.NewInt(r))) }
+	f := lin(1).Mul(lin(2)).Mul(lin(-5))
+	g := lin(2).Mul(lin(7))
+	if got := f.Gcd(g); !got.Equal(lin(2)) {
+		t.Fatalf(`Gcd() = %v, expected = %v`, got, lin(2))
+	}
+	if s := f.String(); s != "x^3 + 2*x^2 + 999990*x + 10" {
+		t.Fatalf(`String() = %q`, s)
+	}
+	q, r := f.DivMod(g)
+	if !q.Mul(g).Add(r).Equal(f) || r.Degree() >= g.Degree() {
+		t.Fatalf(`DivMod() = %v, %v`, q, r)
+	}
+	roots := f.Roots()
+	if len(roots) != 3 || roots[0].Int64() != 1 || roots[1].Int64() != 2 || roots[2].Int64() != 999998 {
+		t.Fatalf(`Roots() = %v`, roots)
+	}
+	// x^(p) = x mod f, f splits over F_p
+	if got := x.PowMod(p, f); !got.Equal(x) {
+		t.Fatalf(`PowMod() = %v, expected = x`, got)
+	}
+	inv, err := x.Add(NewPoly(p, big.NewInt(3))).InvMod(g)
+	if err != nil || !inv.Mul(x.Add(NewPoly(p, big.NewInt(3)))).Mod(g).Equal(NewPoly(p, big.NewInt(1))) {
+		t.Fatalf(`InvMod() = %v, err = %v`, inv, err)
+	}
+	if _, err := lin(7).InvMod(g); err == nil {
+		t.Fatalf(`InvMod() inverted a factor of the modulus`)
+	}
+}
+
+// Roots of psi_n are the x-coordinates of the n-torsion points
+func TestDivisionPolynomial(t *testing.T) {
+	c := toyCurve(t)
+	points, _ := c.Points()
+	for n := 1; n <= 12; n++ {
+		psi := c.DivisionPolynomial(n)
+		degree := (n*n - 1) / 2
+		if n%2 == 0 {
+			degree = (n*n - 4) / 2
+		}
+		if psi.Degree() != degree {
+			t.Fatalf(`psi_%d has degree %d, expected = %d`, n, psi.Degree(), degree)
+		}
+		expected := map[int64]bool{}
+		for _, P := range points[1:] {
+			if P.Y.Sign() != 0 && c.ECPScalarMul(P, big.NewInt(int64(n))).IsInfinity() {
+				expected[P.X.Int64()] = true
+			}
+		}
+		got := map[int64]bool{}
+		for _, x := range psi.Roots() {
+			// the rest are over F_(p^2)
+			if big.Jacobi(c.Polynomial(x), c.P) == 1 {
+				got[x.Int64()] = true
+			}
+		}
+		if len(got) != len(expected) {
+			t.Fatalf(`psi_%d: %d roots, expected = %d`, n, len(got), len(expected))
+		}
+		for x := range expected {
+			if !got[x] {
+				t.Fatalf(`psi_%d: %d is not a root`, n, x)
+			}
+		}
+	}
+
+	// cyclic group of order 2^7 * 3^2 * 7 * 53 * 157: gcd(n, N)
+	// points with n*P = O, one is O, one has order 2
+	_, smooth := dlpCurves(t)
+	for _, n := range []int64{3, 4, 6, 7, 9} {
+		count := int64(1)
+		for _, x := range smooth.DivisionPolynomial(int(n)).Roots() {
+			y := new(big.Int).ModSqrt(smooth.Polynomial(x), smooth.P)
+			if y == nil {
+				continue
+			}
+			if !smooth.ECPScalarMul(&ECPoint{x, y, big.NewInt(1)}, big.NewInt(n)).IsInfinity() {
+				t.Fatalf(`psi_%d: root %v is not of an n-torsion point`, n, x)
+			}
+			count += 2
+		}
+		if n%2 == 0 {
+			count++
+		}
+		if expected := new(big.Int).GCD(nil, nil, big.NewInt(n), smooth.N); count != expected.Int64() {
+			t.Fatalf(`psi_%d: %d points, expected = %v`, n, count, expected)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package ECwrap
+
+import (
+	"crypto/hmac"
+	"hash"
+	"math/big"
+)
+
+// Deterministic nonce generator from RFC 6979, section 3.2
+// https://www.rfc-editor.org/rfc/rfc6979#section-3.2
+//
+// Each call to `Next` returns the next candidate k in
+// [1, q - 1]. First one is the one to use, next ones are
+// only needed if the first did not fit (r = 0 or s = 0)
+type RFC6979 struct {
+	q    *big.Int
+	hash func() hash.Hash
+	k    []byte
+	v    []byte
+	// set after the first `Next`
+	started bool
+}
+
+// Returns generator for private key `x`, hashed message `h1`
+// and hash function `h` used for HMAC. `q` is the group order
+// `h` does not have to be the one message was hashed with,
+// though it usually is
+func NewRFC6979(q, x *big.Int, h1 []byte, h func() hash.Hash) *RFC6979 {
+	g := &RFC6979{q: q, hash: h}
+	hlen := h().Size()
+
+	// b. V = 0x01 0x01 0x01 ... 0x01
+	g.v = make([]byte, hlen)
+	for i := range g.v {
+		g.v[i] = 0x01
+	}
+	// c. K = 0x00 0x00 0x00 ... 0x00
+	g.k = make([]byte, hlen)
+
+	xo := int2octets(x, q)
+	ho := bits2octets(h1, q)
+
+	// d. K = HMAC_K(V || 0x00 || int2octets(x) || bits2octets(h1))
+	g.k = g.mac(g.k, g.v, []byte{0x00}, xo, ho)
+	// e. V = HMAC_K(V)
+	g.v = g.mac(g.k, g.v)
+	// f. K = HMAC_K(V || 0x01 || int2octets(x) || bits2octets(h1))
+	g.k = g.mac(g.k, g.v, []byte{0x01}, xo, ho)
+	// g. V = HMAC_K(V)
+	g.v = g.mac(g.k, g.v)
+
+	return g
+}
+
+func (g *RFC6979) mac(key []byte, data ...[]byte) []byte {
+	m := hmac.New(g.hash, key)
+	for _, d := range data {
+		m.Write(d)
+	}
+	return m.Sum(nil)
+}
+
+// Returns next nonce candidate (step h. of the RFC)
+func (g *RFC6979) Next() *big.Int {
+	if g.started {
+		// K = HMAC_K(V || 0x00), V = HMAC_K(V)
+		g.k = g.mac(g.k, g.v, []byte{0x00})
+		g.v = g.mac(g.k, g.v)
+	}
+	g.started = true
+
+	qlen := g.q.BitLen()
+	for {
+		var t []byte
+		for len(t)*8 < qlen {
+			g.v = g.mac(g.k, g.v)
+			t = append(t, g.v...)
+		}
+		k := bits2int(t, g.q)
+		if k.Sign() > 0 && k.Cmp(g.q) < 0 {
+			return k
+		}
+		g.k = g.mac(g.k, g.v, []byte{0x00})
+		g.v = g.mac(g.k, g.v)
+	}
+}
+
+// Takes leftmost qlen bits of `b` as integer (RFC 6979, 2.3.2)
+// Same truncation is used by ECDSA for the message hash
+func bits2int(b []byte, q *big.Int) *big.Int {
+	v := new(big.Int).SetBytes(b)
+	if excess := len(b)*8 - q.BitLen(); excess > 0 {
+		v.Rsh(v, uint(excess))
+	}
+	return v
+}
+
+// Returns `x` as big-endian rlen bytes (RFC 6979, 2.3.3)
+func int2octets(x, q *big.Int) []byte {
+	return new(big.Int).Mod(x, q).FillBytes(make([]byte, (q.BitLen()+7)/8))
+}
+
+// RFC 6979, 2.3.4
+func bits2octets(b []byte, q *big.Int) []byte {
+	return int2octets(bits2int(b, q), q)
+}
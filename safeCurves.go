@@ -0,0 +1,326 @@
+package ECwrap
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// Security checks of a curve in the spirit of SafeCurves
+// (https://safecurves.cr.yp.to): the ECDLP must be hard for
+// generic attacks (rho), must not transfer to an easier problem
+// (MOV / Frey-Ruck, Smart's attack on anomalous curves), and the
+// curve should be easy to implement safely (twist security, the
+// Montgomery ladder, complete addition formulas). Rigidity of the
+// parameters is not something a program can check.
+//
+// Some of the checks need factorizations of numbers as big as p,
+// which are hard in general. The parts Pollard's rho does not
+// split quickly are left unfactored and the report says so
+
+// Minimal cost of Pollard's rho, in bits, for the curve and its twist
+const safeRhoBits = 100
+
+// Minimal size of the CM discriminant, in bits
+const safeDiscBits = 100
+
+// Smaller embedding degrees are unsafe, as in BSI TR-03111. Only
+// these exponents are checked when the embedding degree cannot be
+// found exactly (l - 1 does not factor)
+const embeddingMaxCheck = 10000
+
+// Iterations of Pollard's rho spent on each composite
+const safeFactorSteps = 1 << 18
+
+// Result of one check
+type SafetyCheck struct {
+	Name   string
+	Passed bool
+	Detail string
+}
+
+// Security report of a curve, see `CheckSafety`
+type SafetyReport struct {
+	Curve string
+	P     *big.Int
+	// #E(F_p), trace of Frobenius t = p + 1 - #E(F_p)
+	Order, Trace *big.Int
+	// prime factors of the order, with repeats, ascending
+	OrderFactors []*big.Int
+	// largest prime factor l of the order and #E(F_p)/l
+	Subgroup, Cofactor *big.Int
+	// log2 of the expected number of additions of Pollard's
+	// rho, 0.886*sqrt(l)
+	RhoBits float64
+	// smallest k with l | p^(k) - 1, nil if it is not found,
+	// then it is greater than embeddingMaxCheck (or l = p)
+	EmbeddingDegree *big.Int
+	// discriminant of the endomorphism ring, (t^(2) - 4*p)/s^(2)
+	// for the largest square s^(2) (times 4 unless it is 1 mod 4)
+	CMDiscriminant *big.Int
+	// order of the quadratic twist, p + 1 + t, and its
+	// factors (nil if it is not fully factored)
+	TwistOrder   *big.Int
+	TwistFactors []*big.Int
+	// rho cost on the largest prime factor of the twist order,
+	// 0 if it is not known
+	TwistRhoBits float64
+	// whether the curve has a Montgomery form, and a complete
+	// twisted Edwards one
+	Ladder, Complete bool
+	Checks           []SafetyCheck
+}
+
+// Reports whether all checks passed
+func (r *SafetyReport) Safe() bool {
+	for _, ch := range r.Checks {
+		if !ch.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns human-readable report, one line per check
+func (r *SafetyReport) String() string {
+	var b strings.Builder
+	verdict := "safe"
+	if !r.Safe() {
+		verdict = "not safe"
+	}
+	fmt.Fprintf(&b, "%s: %s\n", r.Curve, verdict)
+	fmt.Fprintf(&b, "  p = 0x%x (%d bits)\n", r.P, r.P.BitLen())
+	fmt.Fprintf(&b, "  #E = 0x%x = %s\n", r.Order, joinFactors(r.OrderFactors))
+	fmt.Fprintf(&b, "  t = %v\n", r.Trace)
+	for _, ch := range r.Checks {
+		status := "pass"
+		if !ch.Passed {
+			status = "FAIL"
+		}
+		fmt.Fprintf(&b, "  %-15s %s  %s\n", ch.Name, status, ch.Detail)
+	}
+	return b.String()
+}
+
+func joinFactors(fs []*big.Int) string {
+	if len(fs) == 0 {
+		return "1"
+	}
+	s := make([]string, len(fs))
+	for i, f := range fs {
+		s[i] = f.String()
+	}
+	return strings.Join(s, " * ")
+}
+
+// Returns security report of the curve. The order of the curve
+// is n*h if n is a prime above 4*sqrt(p) (n*G = O pins #E down
+// then), otherwise it is counted with `CountPoints`, which may
+// take long for big fields. rand is used for counting only.
+// Error is returned if the order cannot be factored, such curves
+// have no known prime order subgroup to check
+func (c *Curve) CheckSafety(rand io.Reader) (*SafetyReport, error) {
+	p := c.P
+	r := &SafetyReport{Curve: c.Name, P: p}
+	check := func(name string, passed bool, format string, args ...any) {
+		r.Checks = append(r.Checks, SafetyCheck{name, passed, fmt.Sprintf(format, args...)})
+	}
+
+	if !p.ProbablyPrime(20) {
+		return nil, errors.New("field size is not a prime")
+	}
+	check("field", true, "p is prime")
+	if c.Discriminant().Sign() == 0 {
+		return nil, errors.New("curve is singular")
+	}
+	check("equation", true, "4*a^3 + 27*b^2 != 0")
+
+	order, err := c.pinnedOrder()
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		if order, _, err = c.CountPoints(rand); err != nil {
+			return nil, err
+		}
+	}
+	r.Order = order
+	r.Trace = new(big.Int).Sub(new(big.Int).Add(p, big.NewInt(1)), order)
+
+	factors, rest := factorizeLimited(order, safeFactorSteps)
+	if rest.Cmp(big.NewInt(1)) != 0 {
+		return nil, errors.New("group order could not be factored")
+	}
+	r.OrderFactors = expandFactors(factors)
+	if len(r.OrderFactors) == 0 {
+		return nil, errors.New("group order has no prime factor")
+	}
+	l := r.OrderFactors[len(r.OrderFactors)-1]
+	r.Subgroup, r.Cofactor = l, new(big.Int).Quo(order, l)
+
+	if c.N == nil || c.H == nil || c.Gx == nil || c.Gy == nil {
+		check("base point", false, "no base point")
+	} else {
+		nh := new(big.Int).Mul(c.N, c.H)
+		baseOK := c.IsOnCurve(c.Gx, c.Gy) && c.N.ProbablyPrime(20) && nh.Cmp(order) == 0 &&
+			c.ECPScalarBaseMul(c.N).IsInfinity()
+		check("base point", baseOK, "n = %v, h = %v, n*h = #E: %t", c.N, c.H, nh.Cmp(order) == 0)
+	}
+
+	r.RhoBits = rhoBits(l)
+	check("rho", r.RhoBits >= safeRhoBits, "l has %d bits, rho takes 2^%.1f additions", l.BitLen(), r.RhoBits)
+
+	k := embeddingDegree(p, l)
+	r.EmbeddingDegree = k
+	if k == nil {
+		check("transfer", true, "embedding degree > %d", embeddingMaxCheck)
+	} else {
+		// SafeCurves wants k >= (l - 1)/100, BSI k > 10000,
+		// the latter matters for small l only
+		lm1 := new(big.Int).Sub(l, big.NewInt(1))
+		passed := k.Cmp(new(big.Int).Quo(lm1, big.NewInt(100))) >= 0 && k.Cmp(big.NewInt(embeddingMaxCheck)) > 0
+		check("transfer", passed, "embedding degree %v, (l - 1)/k = %v", k, new(big.Int).Quo(lm1, k))
+	}
+
+	anomalous := r.Trace.Cmp(big.NewInt(1)) == 0 || l.Cmp(p) == 0
+	check("anomalous", !anomalous, "t = %v", r.Trace)
+
+	D, exact := cmDiscriminant(r.Trace, p)
+	r.CMDiscriminant = D
+	note := ""
+	if !exact {
+		note = ", unfactored part of t^2 - 4*p assumed squarefree"
+	}
+	check("CM discriminant", D.BitLen() > safeDiscBits, "|D| has %d bits%s", D.BitLen(), note)
+
+	r.TwistOrder = new(big.Int).Add(new(big.Int).Add(p, big.NewInt(1)), r.Trace)
+	tf, trest := factorizeLimited(r.TwistOrder, safeFactorSteps)
+	if trest.Cmp(big.NewInt(1)) == 0 {
+		r.TwistFactors = expandFactors(tf)
+		r.TwistRhoBits = rhoBits(r.TwistFactors[len(r.TwistFactors)-1])
+		check("twist", r.TwistRhoBits >= safeRhoBits, "twist order %s, rho takes 2^%.1f additions",
+			joinFactors(r.TwistFactors), r.TwistRhoBits)
+	} else {
+		check("twist", false, "twist order has unfactored part of %d bits", trest.BitLen())
+	}
+
+	if A, B, err := c.montgomeryCoefficients(); err == nil {
+		r.Ladder = true
+		r.Complete = completeEdwards(p, A, B)
+	}
+	check("ladder", r.Ladder, "Montgomery form exists: %t", r.Ladder)
+	check("complete", r.Complete, "complete Edwards form exists: %t", r.Complete)
+	return r, nil
+}
+
+// Returns #E(F_p) when n*G = O for prime n > 4*sqrt(p): the only
+// multiple of n in the Hasse interval. Nil if n does not pin the
+// order down
+func (c *Curve) pinnedOrder() (*big.Int, error) {
+	if c.N == nil || c.Gx == nil || !c.N.ProbablyPrime(20) {
+		return nil, nil
+	}
+	bound := hasseBound(c.P)
+	if c.N.Cmp(new(big.Int).Lsh(bound, 1)) <= 0 || !c.ECPScalarBaseMul(c.N).IsInfinity() {
+		return nil, nil
+	}
+	lo := new(big.Int).Sub(new(big.Int).Add(c.P, big.NewInt(1)), bound)
+	// smallest multiple of n not below lo
+	k := new(big.Int).Add(lo, new(big.Int).Sub(c.N, big.NewInt(1)))
+	k.Quo(k, c.N)
+	order := new(big.Int).Mul(k, c.N)
+	if new(big.Int).Sub(order, lo).Cmp(new(big.Int).Lsh(bound, 1)) > 0 {
+		return nil, errors.New("no multiple of n in the Hasse interval")
+	}
+	return order, nil
+}
+
+// Returns the prime factors with repeats
+func expandFactors(factors []primePower) []*big.Int {
+	var out []*big.Int
+	for _, pp := range factors {
+		for e := 0; e < pp.e; e++ {
+			out = append(out, pp.p)
+		}
+	}
+	return out
+}
+
+// log2(0.886*sqrt(l)), the expected cost of Pollard's rho
+// with the negation map
+func rhoBits(l *big.Int) float64 {
+	f, _ := new(big.Float).SetInt(l).Float64()
+	return math.Log2(0.886) + math.Log2(f)/2
+}
+
+// Returns the order of p mod l if l - 1 could be factored.
+// Otherwise only exponents up to embeddingMaxCheck are tried and
+// nil is returned if none of them works
+func embeddingDegree(p, l *big.Int) *big.Int {
+	if new(big.Int).Mod(p, l).Sign() == 0 {
+		return nil
+	}
+	lm1 := new(big.Int).Sub(l, big.NewInt(1))
+	factors, rest := factorizeLimited(lm1, safeFactorSteps)
+	if rest.Cmp(big.NewInt(1)) == 0 {
+		k := new(big.Int).Set(lm1)
+		for _, pp := range factors {
+			for e := 0; e < pp.e; e++ {
+				q := new(big.Int).Quo(k, pp.p)
+				if new(big.Int).Exp(p, q, l).Cmp(big.NewInt(1)) != 0 {
+					break
+				}
+				k = q
+			}
+		}
+		return k
+	}
+	pk := new(big.Int).Mod(p, l)
+	for k := int64(1); k <= embeddingMaxCheck; k++ {
+		if pk.Cmp(big.NewInt(1)) == 0 {
+			return big.NewInt(k)
+		}
+		pk.Mul(pk, p).Mod(pk, l)
+	}
+	return nil
+}
+
+// Returns the CM discriminant for the trace t, false if a part
+// of t^(2) - 4*p could not be factored and is taken as squarefree
+func cmDiscriminant(t, p *big.Int) (*big.Int, bool) {
+	// 4*p - t^(2) > 0
+	v := new(big.Int).Sub(new(big.Int).Lsh(p, 2), new(big.Int).Mul(t, t))
+	factors, rest := factorizeLimited(v, safeFactorSteps)
+	exact := true
+	if rest.Cmp(big.NewInt(1)) != 0 {
+		if s := new(big.Int).Sqrt(rest); new(big.Int).Mul(s, s).Cmp(rest) == 0 {
+			v.Quo(v, rest)
+		} else {
+			exact = false
+		}
+	}
+	for _, pp := range factors {
+		v.Quo(v, new(big.Int).Exp(pp.p, big.NewInt(int64(pp.e/2*2)), nil))
+	}
+	D := v.Neg(v)
+	if new(big.Int).Mod(D, big.NewInt(4)).Int64() != 1 {
+		D.Lsh(D, 2)
+	}
+	return D, exact
+}
+
+// Reports whether B*v^(2) = u^(3) + A*u^(2) + u is birational to
+// a complete twisted Edwards curve: a = (A + 2)/B is a square
+// (so a = 1 after rescaling) and d/a = (A - 2)/(A + 2) is not
+func completeEdwards(p, A, B *big.Int) bool {
+	ap2 := new(big.Int).Add(A, big.NewInt(2))
+	if ap2.Mod(ap2, p).Sign() == 0 {
+		return false
+	}
+	a := new(big.Int).Mul(ap2, new(big.Int).ModInverse(B, p))
+	da := new(big.Int).Mul(new(big.Int).Sub(A, big.NewInt(2)), new(big.Int).ModInverse(ap2, p))
+	return big.Jacobi(a.Mod(a, p), p) == 1 && big.Jacobi(da.Mod(da, p), p) == -1
+}
@@ -0,0 +1,85 @@
+package ECwrap
+
+import (
+	"crypto/elliptic"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// Reports which checks failed, in order
+func failedChecks(r *SafetyReport) []string {
+	var out []string
+	for _, ch := range r.Checks {
+		if !ch.Passed {
+			out = append(out, ch.Name)
+		}
+	}
+	return out
+}
+
+func TestCheckSafety(t *testing.T) {
+	wei25519, err := Curve25519().Weierstrass()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		c      *Curve
+		failed string
+	}{
+		// SafeCurves: curve25519 is safe, P-256 has no ladder and
+		// no complete formulas (and no rigidity, not checked)
+		{wei25519, ""},
+		{FromElliptic(elliptic.P256()), "ladder complete"},
+		{toyCurve(t), "rho transfer CM discriminant twist complete"},
+	}
+	for _, test := range tests {
+		r, err := test.c.CheckSafety(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(failedChecks(r), " "); got != test.failed {
+			t.Fatalf("%s: failed checks = %q, expected = %q\n%s", test.c.Name, got, test.failed, r)
+		}
+		if r.Safe() != (test.failed == "") {
+			t.Fatalf(`%s: Safe() = %t`, test.c.Name, r.Safe())
+		}
+	}
+}
+
+// Returns the check named `name`, nil if there is none
+func findCheck(r *SafetyReport, name string) *SafetyCheck {
+	for i := range r.Checks {
+		if r.Checks[i].Name == name {
+			return &r.Checks[i]
+		}
+	}
+	return nil
+}
+
+// Toy curves with the attacks the checks are about
+func TestCheckSafetyAttacks(t *testing.T) {
+	p := big.NewInt(1019)
+	// supersingular, p = 3 mod 4: t = 0, embedding degree 2
+	r, err := (&Curve{Name: "supersingular", P: p, A: big.NewInt(1), B: big.NewInt(0)}).CheckSafety(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := findCheck(r, "transfer")
+	if r.Trace.Sign() != 0 || r.EmbeddingDegree == nil || r.EmbeddingDegree.Int64() != 2 || ch == nil || ch.Passed {
+		t.Fatalf("supersingular curve passed the transfer check\n%s", r)
+	}
+	// #E = p
+	r, err = (&Curve{Name: "anomalous", P: p, A: big.NewInt(5), B: big.NewInt(33)}).CheckSafety(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch = findCheck(r, "anomalous")
+	if r.Order.Cmp(p) != 0 || ch == nil || ch.Passed {
+		t.Fatalf("anomalous curve passed the anomalous check\n%s", r)
+	}
+	// y^2 = x^3 + 2x + 2 over F_3 has the point at infinity only
+	if _, err := (&Curve{Name: "trivial", P: big.NewInt(3), A: big.NewInt(2), B: big.NewInt(2)}).CheckSafety(nil); err == nil {
+		t.Fatalf(`CheckSafety() of a curve with #E = 1 error = nil`)
+	}
+}
@@ -0,0 +1,188 @@
+package ECwrap
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"math/big"
+)
+
+// Integer modulo n, the order of the base point of a curve.
+// Private keys and nonces are Scalars, so they are always
+// reduced and always encode to the same number of bytes.
+// Scalars are immutable, arithmetic returns new ones
+//
+// Encoding and decoding do not branch on the value, big.Int
+// arithmetic is not constant time though, so this is about
+// as far as it goes
+type Scalar struct {
+	n *big.Int
+	v *big.Int
+}
+
+// Returns v mod n as a scalar of curve c
+func (c *Curve) NewScalar(v *big.Int) *Scalar {
+	return &Scalar{n: c.N, v: new(big.Int).Mod(v, c.N)}
+}
+
+// Length of scalar encoding in bytes
+func (c *Curve) ScalarLen() int {
+	return (c.N.BitLen() + 7) / 8
+}
+
+// Decodes big-endian scalar of exactly `ScalarLen` bytes.
+// Values not less than n are rejected, not reduced
+func (c *Curve) ScalarFromBytes(b []byte) (*Scalar, error) {
+	if len(b) != c.ScalarLen() {
+		return nil, errors.New("invalid scalar length")
+	}
+	if !ctLess(b, c.N.FillBytes(make([]byte, len(b)))) {
+		return nil, errors.New("scalar is not less than n")
+	}
+	return &Scalar{n: c.N, v: new(big.Int).SetBytes(b)}, nil
+}
+
+// Returns uniformly random scalar in [1, n - 1]. Random
+// values of n's bit length are drawn until one fits,
+// so there is no modular bias at all
+func (c *Curve) RandomScalar(rand io.Reader) (*Scalar, error) {
+	buf := make([]byte, c.ScalarLen())
+	// excess bits of the top byte
+	mask := byte(0xff >> (8*len(buf) - c.N.BitLen()))
+	for {
+		if _, err := io.ReadFull(rand, buf); err != nil {
+			return nil, errors.New("could not generate rand value")
+		}
+		buf[0] &= mask
+		k := new(big.Int).SetBytes(buf)
+		if k.Sign() != 0 && k.Cmp(c.N) < 0 {
+			return &Scalar{n: c.N, v: k}, nil
+		}
+	}
+}
+
+// Hashes `msg` with domain separation tag `dst` to a scalar,
+// hash_to_field of RFC 9380 with modulus n. Security level
+// is half of n's bit length, expand_message_xmd uses SHA-256,
+// SHA-384 or SHA-512 whichever is the first to cover it
+// (the HashToScalar of RFC 9497 for the NIST curves)
+func (c *Curve) HashToScalar(msg, dst []byte) (*Scalar, error) {
+	bits := c.N.BitLen()
+	expand := XMD(sha512.New)
+	switch {
+	case bits <= 256:
+		expand = XMD(sha256.New)
+	case bits <= 384:
+		expand = XMD(sha512.New384)
+	}
+	u, err := HashToField(expand, msg, dst, c.N, 1, 1, fieldLen(c.N, bits/2))
+	if err != nil {
+		return nil, err
+	}
+	return &Scalar{n: c.N, v: u[0]}, nil
+}
+
+// Returns k*P
+func (c *Curve) ECPMul(P *ECPoint, k *Scalar) *ECPoint {
+	c.checkScalar(k)
+	return c.ECPScalarMul(P, k.v)
+}
+
+// Returns k*G
+func (c *Curve) ECPBaseMul(k *Scalar) *ECPoint {
+	c.checkScalar(k)
+	return c.ECPScalarBaseMul(k.v)
+}
+
+func (c *Curve) checkScalar(k *Scalar) {
+	if k.n.Cmp(c.N) != 0 {
+		panic("ECwrap: scalar of another curve")
+	}
+}
+
+// Returns private key `d` if it belongs to curve c and is not zero
+func (c *Curve) privateKey(d *Scalar) (*big.Int, error) {
+	if d == nil || d.n.Cmp(c.N) != 0 {
+		return nil, errors.New("private key is not a scalar of the curve")
+	}
+	if d.IsZero() {
+		return nil, errors.New("private key is zero")
+	}
+	return d.v, nil
+}
+
+func (s *Scalar) check(t *Scalar) {
+	if s.n.Cmp(t.n) != 0 {
+		panic("ECwrap: scalars of different curves")
+	}
+}
+
+// Returns s + t
+func (s *Scalar) Add(t *Scalar) *Scalar {
+	s.check(t)
+	v := new(big.Int).Add(s.v, t.v)
+	return &Scalar{n: s.n, v: v.Mod(v, s.n)}
+}
+
+// Returns s - t
+func (s *Scalar) Sub(t *Scalar) *Scalar {
+	s.check(t)
+	v := new(big.Int).Sub(s.v, t.v)
+	return &Scalar{n: s.n, v: v.Mod(v, s.n)}
+}
+
+// Returns s * t
+func (s *Scalar) Mul(t *Scalar) *Scalar {
+	s.check(t)
+	v := new(big.Int).Mul(s.v, t.v)
+	return &Scalar{n: s.n, v: v.Mod(v, s.n)}
+}
+
+// Returns -s
+func (s *Scalar) Neg() *Scalar {
+	v := new(big.Int).Neg(s.v)
+	return &Scalar{n: s.n, v: v.Mod(v, s.n)}
+}
+
+// Returns s^(-1), computed as s^(n - 2) since n is prime.
+// Zero has no inverse and zero is returned for it
+func (s *Scalar) Inverse() *Scalar {
+	return &Scalar{n: s.n, v: new(big.Int).Exp(s.v, new(big.Int).Sub(s.n, big.NewInt(2)), s.n)}
+}
+
+// Reports whether s is zero
+func (s *Scalar) IsZero() bool {
+	return s.v.Sign() == 0
+}
+
+// Reports whether s == t. Encodings are compared in
+// constant time
+func (s *Scalar) Equal(t *Scalar) bool {
+	s.check(t)
+	return subtle.ConstantTimeCompare(s.Bytes(), t.Bytes()) == 1
+}
+
+// Returns big-endian encoding of `ScalarLen` bytes
+func (s *Scalar) Bytes() []byte {
+	return s.v.FillBytes(make([]byte, (s.n.BitLen()+7)/8))
+}
+
+// Returns copy of the value in [0, n - 1]
+func (s *Scalar) BigInt() *big.Int {
+	return new(big.Int).Set(s.v)
+}
+
+// Reports whether big-endian a < b (of the same length)
+// without branching on the bytes
+func ctLess(a, b []byte) bool {
+	lt, eq := 0, 1
+	for i := range a {
+		// sign bit of a[i] - b[i] is set iff a[i] < b[i]
+		l := ((int(a[i]) - int(b[i])) >> 8) & 1
+		lt |= eq & l
+		eq &= subtle.ConstantTimeByteEq(a[i], b[i])
+	}
+	return lt == 1
+}
@@ -60,3 +60,80 @@ func ScalarMul(P *ECPoint, num, Order *big.Int) *ECPoint {
 	}
 	return P0
 }
+
+// Same double-and-add as `ScalarMul`, but for curves
+// with arbitrary `a` (see `AddJacobianWithA`).
+// Negative scalars are not handled here, negate the
+// point yourself or use `Curve.ECPScalarMul`
+func ScalarMulWithA(P *ECPoint, num, Order, A *big.Int) *ECPoint {
+	P0 := new(ECPoint)
+	P0.SetCoords(big.NewInt(0), big.NewInt(0), big.NewInt(0)) // point at infinity
+	P1 := new(ECPoint)
+	P1.SetCoords(P.X, P.Y, P.Z)
+
+	n := new(big.Int).Set(num)
+	for n.Sign() > 0 {
+		if n.Bit(0) == 1 {
+			P0 = AddJacobianWithA(P0, P1, Order, A)
+		}
+		P1 = DoubleJacobianWithA(P1, Order, A)
+
+		n.Rsh(n, 1)
+	}
+	return P0
+}
+
+// Returns k1*P1 + k2*P2 + ... + kn*Pn, computed at once
+// with Straus (aka Shamir's trick) interleaving. Sums of all
+// the subsets of the points are precomputed, so there is
+// one doubling per bit of the largest scalar, and at most
+// one addition, instead of the doubling chain per point
+//
+// Table grows as 2^(n), so only up to 4 points are
+// combined at a time, the rest is processed in chunks
+// Scalars have to be non-negative, see `Curve.ECPMultiScalarMul`
+func MultiScalarMulWithA(points []*ECPoint, scalars []*big.Int, Order, A *big.Int) *ECPoint {
+	const chunk = 4
+	if len(points) > chunk {
+		return AddJacobianWithA(
+			MultiScalarMulWithA(points[:chunk], scalars[:chunk], Order, A),
+			MultiScalarMulWithA(points[chunk:], scalars[chunk:], Order, A),
+			Order,
+			A,
+		)
+	}
+
+	// table[mask] = sum of points[i] for each bit i set in mask
+	table := make([]*ECPoint, 1<<len(points))
+	table[0] = PointAtInfinity()
+	for mask := 1; mask < len(table); mask++ {
+		low := mask & -mask
+		i := 0
+		for 1<<i != low {
+			i++
+		}
+		table[mask] = AddJacobianWithA(table[mask^low], points[i], Order, A)
+	}
+
+	maxLen := 0
+	for _, k := range scalars {
+		if k.BitLen() > maxLen {
+			maxLen = k.BitLen()
+		}
+	}
+
+	R := PointAtInfinity()
+	for bit := maxLen - 1; bit >= 0; bit-- {
+		R = DoubleJacobianWithA(R, Order, A)
+		mask := 0
+		for i, k := range scalars {
+			if k.Bit(bit) == 1 {
+				mask |= 1 << i
+			}
+		}
+		if mask != 0 {
+			R = AddJacobianWithA(R, table[mask], Order, A)
+		}
+	}
+	return R
+}
@@ -0,0 +1,116 @@
+package ECwrap
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+func TestScalarArithmetic(t *testing.T) {
+	c := FromElliptic(elliptic.P256())
+	one := c.NewScalar(big.NewInt(1))
+	for i := 0; i < 16; i++ {
+		a, _ := c.RandomScalar(rand.Reader)
+		b, _ := c.RandomScalar(rand.Reader)
+		if !a.Add(b).Sub(b).Equal(a) {
+			t.Fatalf(`a + b - b != a for a = %v, b = %v`, a.v, b.v)
+		}
+		if !a.Add(a.Neg()).IsZero() {
+			t.Fatalf(`a + (-a) = %v, expected = 0`, a.Add(a.Neg()).v)
+		}
+		if !a.Mul(a.Inverse()).Equal(one) {
+			t.Fatalf(`a * a^(-1) = %v, expected = 1`, a.Mul(a.Inverse()).v)
+		}
+		// (a + b)*G = a*G + b*G
+		if !c.ECPEqual(c.ECPBaseMul(a.Add(b)), c.ECPAdd(c.ECPBaseMul(a), c.ECPBaseMul(b))) {
+			t.Fatalf(`(a + b)*G != a*G + b*G`)
+		}
+	}
+	if !c.NewScalar(new(big.Int).Add(c.N, big.NewInt(5))).Equal(c.NewScalar(big.NewInt(5))) {
+		t.Fatalf(`NewScalar(n + 5) != NewScalar(5)`)
+	}
+	if !c.NewScalar(big.NewInt(-1)).Equal(one.Neg()) {
+		t.Fatalf(`NewScalar(-1) != -1`)
+	}
+	if !c.NewScalar(big.NewInt(0)).Inverse().IsZero() {
+		t.Fatalf(`Inverse(0) != 0`)
+	}
+}
+
+func TestScalarEncoding(t *testing.T) {
+	for _, c := range []*Curve{FromElliptic(elliptic.P256()), FromElliptic(elliptic.P521()), Secp256k1()} {
+		a, _ := c.RandomScalar(rand.Reader)
+		b := a.Bytes()
+		if len(b) != c.ScalarLen() {
+			t.Fatalf(`%s: len(Bytes()) = %d, expected = %d`, c.Name, len(b), c.ScalarLen())
+		}
+		got, err := c.ScalarFromBytes(b)
+		if err != nil || !got.Equal(a) {
+			t.Fatalf(`%s: ScalarFromBytes(Bytes()) did not round trip, err = %v`, c.Name, err)
+		}
+
+		nMinus1 := new(big.Int).Sub(c.N, big.NewInt(1)).FillBytes(make([]byte, c.ScalarLen()))
+		if _, err := c.ScalarFromBytes(nMinus1); err != nil {
+			t.Fatalf(`%s: ScalarFromBytes(n - 1) error = %v`, c.Name, err)
+		}
+		for _, v := range []*big.Int{c.N, new(big.Int).Add(c.N, big.NewInt(1))} {
+			if _, err := c.ScalarFromBytes(v.FillBytes(make([]byte, c.ScalarLen()))); err == nil {
+				t.Fatalf(`%s: ScalarFromBytes(%v) error = nil`, c.Name, v)
+			}
+		}
+		if _, err := c.ScalarFromBytes(b[1:]); err == nil {
+			t.Fatalf(`%s: ScalarFromBytes(short) error = nil`, c.Name)
+		}
+	}
+}
+
+func TestRandomScalar(t *testing.T) {
+	// n = 5 for the toy curve, every value of [1, 4] must show up
+	c := toyCurve(t)
+	seen := map[int64]bool{}
+	for i := 0; i < 200; i++ {
+		k, err := c.RandomScalar(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if k.IsZero() || k.v.Cmp(c.N) >= 0 {
+			t.Fatalf(`RandomScalar() = %v, expected in [1, n - 1]`, k.v)
+		}
+		seen[k.v.Int64()] = true
+	}
+	if int64(len(seen)) != c.N.Int64()-1 {
+		t.Fatalf(`RandomScalar() hit %d values, expected = %d`, len(seen), c.N.Int64()-1)
+	}
+	if _, err := c.RandomScalar(bytes.NewReader(nil)); err == nil {
+		t.Fatalf(`RandomScalar(empty reader) error = nil`)
+	}
+}
+
+func TestHashToScalar(t *testing.T) {
+	c := FromElliptic(elliptic.P384())
+	a, err := c.HashToScalar([]byte("msg"), []byte("dst"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := c.HashToScalar([]byte("msg"), []byte("dst"))
+	other, _ := c.HashToScalar([]byte("msg"), []byte("dst2"))
+	if !a.Equal(b) || a.Equal(other) {
+		t.Fatalf(`HashToScalar() = %v, %v, %v`, a.v, b.v, other.v)
+	}
+}
+
+func TestScalarCurveMismatch(t *testing.T) {
+	d, _ := Secp256k1().RandomScalar(rand.Reader)
+	c := FromElliptic(elliptic.P256())
+	if _, err := ECDSASign(c, d, make([]byte, 32), nil); err == nil {
+		t.Fatalf(`ECDSASign() accepted secp256k1 key for P-256`)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatalf(`mixing scalars of different curves did not panic`)
+		}
+	}()
+	d.Add(c.NewScalar(big.NewInt(1)))
+}
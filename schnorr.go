@@ -0,0 +1,211 @@
+package ECwrap
+
+import (
+	"crypto/sha256"
+	"errors"
+	"io"
+	"math/big"
+)
+
+// BIP-340 Schnorr signatures over secp256k1
+// https://github.com/bitcoin/bips/blob/master/bip-0340.mediawiki
+//
+// Public keys are x-only: 32 bytes of x coordinate, the
+// point is implicitly the one with even Y. Signatures are
+// 64 bytes R.x || s, where R also has even Y
+
+// Returns SHA256(SHA256(tag) || SHA256(tag) || msg...),
+// the tagged hash of BIP-340. Different tags make hashes of
+// different purposes unrelated, even for the same input
+func TaggedHash(tag string, msgs ...[]byte) []byte {
+	tagHash := sha256.Sum256([]byte(tag))
+	h := sha256.New()
+	h.Write(tagHash[:])
+	h.Write(tagHash[:])
+	for _, m := range msgs {
+		h.Write(m)
+	}
+	return h.Sum(nil)
+}
+
+// Returns 32 byte x-only public key for private key `d`
+func SchnorrPublicKey(d *Scalar) ([]byte, error) {
+	c := Secp256k1()
+	if _, err := c.privateKey(d); err != nil {
+		return nil, err
+	}
+	P := c.ECPAffine(c.ECPBaseMul(d))
+	return P.X.FillBytes(make([]byte, 32)), nil
+}
+
+// Returns the point for x-only public key (lift_x in BIP-340),
+// which is the one with even Y
+func SchnorrParsePublicKey(pub []byte) (*ECPoint, error) {
+	if len(pub) != 32 {
+		return nil, errors.New("invalid x-only public key length")
+	}
+	return Secp256k1().ECPFromX(new(big.Int).SetBytes(pub), false)
+}
+
+// Signs message `msg` (of any length, it is not hashed
+// beforehand) with private key `d`. `auxRand` is 32 bytes
+// of auxiliary randomness which is mixed into the nonce
+// (it protects against side channels, signature is safe
+// even if it is all zeroes or repeats)
+func SchnorrSign(d *Scalar, msg, auxRand []byte) ([]byte, error) {
+	c := Secp256k1()
+	if _, err := c.privateKey(d); err != nil {
+		return nil, err
+	}
+	if len(auxRand) != 32 {
+		return nil, errors.New("auxiliary randomness must be 32 bytes")
+	}
+
+	// P = d'*G, d = d' if P has even Y, n - d' otherwise
+	P := c.ECPAffine(c.ECPBaseMul(d))
+	dd := d
+	if P.Y.Bit(0) == 1 {
+		dd = d.Neg()
+	}
+	pBytes := P.X.FillBytes(make([]byte, 32))
+
+	// t = bytes(d) xor hash_BIP0340/aux(a)
+	t := dd.Bytes()
+	auxHash := TaggedHash("BIP0340/aux", auxRand)
+	for i := range t {
+		t[i] ^= auxHash[i]
+	}
+
+	// k' = int(hash_BIP0340/nonce(t || bytes(P) || m)) mod n
+	k := new(big.Int).SetBytes(TaggedHash("BIP0340/nonce", t, pBytes, msg))
+	k.Mod(k, c.N)
+	if k.Sign() == 0 {
+		return nil, errors.New("nonce is zero")
+	}
+
+	// R = k'*G, k = k' if R has even Y, n - k' otherwise
+	R := c.ECPAffine(c.ECPScalarBaseMul(k))
+	if R.Y.Bit(0) == 1 {
+		k.Sub(c.N, k)
+	}
+	rBytes := R.X.FillBytes(make([]byte, 32))
+
+	e := schnorrChallenge(rBytes, pBytes, msg)
+
+	// s = k + e*d mod n
+	s := new(big.Int).Add(k, new(big.Int).Mul(e, dd.v))
+	s.Mod(s, c.N)
+
+	sig := append(rBytes, s.FillBytes(make([]byte, 32))...)
+	// as BIP-340 recommends, make sure nothing went wrong
+	if !SchnorrVerify(pBytes, msg, sig) {
+		return nil, errors.New("produced signature does not verify")
+	}
+	return sig, nil
+}
+
+// e = int(hash_BIP0340/challenge(bytes(R) || bytes(P) || m)) mod n
+func schnorrChallenge(r, p, msg []byte) *big.Int {
+	e := new(big.Int).SetBytes(TaggedHash("BIP0340/challenge", r, p, msg))
+	return e.Mod(e, Secp256k1().N)
+}
+
+// Splits and range checks signature, returns r and s
+func schnorrParseSignature(sig []byte) (*big.Int, *big.Int, error) {
+	c := Secp256k1()
+	if len(sig) != 64 {
+		return nil, nil, errors.New("invalid signature length")
+	}
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+	if r.Cmp(c.P) >= 0 {
+		return nil, nil, errors.New("signature r is not less than p")
+	}
+	if s.Cmp(c.N) >= 0 {
+		return nil, nil, errors.New("signature s is not less than n")
+	}
+	return r, s, nil
+}
+
+// Verifies signature `sig` of message `msg` against
+// x-only public key `pub`
+func SchnorrVerify(pub, msg, sig []byte) bool {
+	c := Secp256k1()
+	P, err := SchnorrParsePublicKey(pub)
+	if err != nil {
+		return false
+	}
+	r, s, err := schnorrParseSignature(sig)
+	if err != nil {
+		return false
+	}
+	e := schnorrChallenge(sig[:32], pub, msg)
+
+	// R = s*G - e*P
+	R := c.ECPMultiScalarMul(
+		[]*ECPoint{c.Generator(), P},
+		[]*big.Int{s, new(big.Int).Neg(e)},
+	)
+	if R.IsInfinity() {
+		return false
+	}
+	R = c.ECPAffine(R)
+	return R.Y.Bit(0) == 0 && R.X.Cmp(r) == 0
+}
+
+// Verifies a batch of signatures at once, which is faster
+// than verifying them one by one. Result is true only if all
+// of them are valid. Random coefficients a_i are read from
+// `rand`, which must not be nil, and
+//
+//	(s_1 + a_2*s_2 + ...)*G == R_1 + a_2*R_2 + ... + e_1*P_1 + (a_2*e_2)*P_2 + ...
+//
+// is checked. Without them a forger could craft invalid
+// signatures that cancel each other out
+func SchnorrBatchVerify(pubs, msgs, sigs [][]byte, rand io.Reader) (bool, error) {
+	c := Secp256k1()
+	if len(pubs) != len(msgs) || len(pubs) != len(sigs) {
+		return false, errors.New("number of keys, messages and signatures differ")
+	}
+	if rand == nil {
+		return false, errors.New("rand is nil")
+	}
+
+	sum := new(big.Int)
+	points := make([]*ECPoint, 0, 2*len(pubs))
+	scalars := make([]*big.Int, 0, 2*len(pubs))
+	for i := range pubs {
+		P, err := SchnorrParsePublicKey(pubs[i])
+		if err != nil {
+			return false, nil
+		}
+		r, s, err := schnorrParseSignature(sigs[i])
+		if err != nil {
+			return false, nil
+		}
+		R, err := c.ECPFromX(r, false)
+		if err != nil {
+			return false, nil
+		}
+		e := schnorrChallenge(sigs[i][:32], pubs[i], msgs[i])
+
+		a := big.NewInt(1)
+		if i > 0 {
+			r, err := c.RandomScalar(rand)
+			if err != nil {
+				return false, err
+			}
+			a = r.v
+		}
+		sum.Add(sum, new(big.Int).Mul(a, s))
+		points = append(points, R, P)
+		scalars = append(scalars,
+			a,
+			new(big.Int).Mod(new(big.Int).Mul(a, e), c.N),
+		)
+	}
+
+	lhs := c.ECPScalarBaseMul(new(big.Int).Mod(sum, c.N))
+	rhs := c.ECPMultiScalarMul(points, scalars)
+	return c.ECPEqual(lhs, rhs), nil
+}
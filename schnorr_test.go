@@ -0,0 +1,123 @@
+package ECwrap
+
+import (
+	"bytes"
+	"crypto/rand"
+	_ "embed"
+	"encoding/csv"
+	"strings"
+	"testing"
+)
+
+// Official test vectors from the BIP repository
+// https://github.com/bitcoin/bips/blob/master/bip-0340/test-vectors.csv
+//
+//go:embed testdata/bip340-test-vectors.csv
+var bip340Vectors string
+
+type bip340Vector struct {
+	index   string
+	secret  string
+	pub     []byte
+	auxRand []byte
+	msg     []byte
+	sig     []byte
+	valid   bool
+	comment string
+}
+
+func readBIP340Vectors(t *testing.T) []bip340Vector {
+	records, err := csv.NewReader(strings.NewReader(bip340Vectors)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []bip340Vector
+	for _, r := range records[1:] {
+		out = append(out, bip340Vector{
+			index:   r[0],
+			secret:  r[1],
+			pub:     decodeHex(r[2]),
+			auxRand: decodeHex(r[3]),
+			msg:     decodeHex(r[4]),
+			sig:     decodeHex(r[5]),
+			valid:   r[6] == "TRUE",
+			comment: r[7],
+		})
+	}
+	return out
+}
+
+func TestSchnorrVectors(t *testing.T) {
+	for _, v := range readBIP340Vectors(t) {
+		if v.secret != "" {
+			d, _ := Secp256k1().ScalarFromBytes(decodeHex(v.secret))
+			pub, err := SchnorrPublicKey(d)
+			if err != nil || !bytes.Equal(pub, v.pub) {
+				t.Errorf(`#%s SchnorrPublicKey() = %X, expected = %X, err = %v`, v.index, pub, v.pub, err)
+			}
+			sig, err := SchnorrSign(d, v.msg, v.auxRand)
+			if err != nil || !bytes.Equal(sig, v.sig) {
+				t.Errorf(`#%s SchnorrSign() = %X, expected = %X, err = %v`, v.index, sig, v.sig, err)
+			}
+		}
+		if got := SchnorrVerify(v.pub, v.msg, v.sig); got != v.valid {
+			t.Errorf(`#%s SchnorrVerify() = %t, expected = %t (%s)`, v.index, got, v.valid, v.comment)
+		}
+	}
+}
+
+func TestSchnorrBatchVerify(t *testing.T) {
+	var pubs, msgs, sigs [][]byte
+	var bad int
+	for i, v := range readBIP340Vectors(t) {
+		if v.valid {
+			pubs = append(pubs, v.pub)
+			msgs = append(msgs, v.msg)
+			sigs = append(sigs, v.sig)
+		} else if bad == 0 {
+			bad = i
+		}
+	}
+	ok, err := SchnorrBatchVerify(pubs, msgs, sigs, rand.Reader)
+	if err != nil || !ok {
+		t.Fatalf(`SchnorrBatchVerify(valid) = %t, err = %v; expected = true`, ok, err)
+	}
+
+	// swapping two messages must break the batch
+	msgs[0], msgs[1] = msgs[1], msgs[0]
+	ok, err = SchnorrBatchVerify(pubs, msgs, sigs, rand.Reader)
+	if err != nil || ok {
+		t.Fatalf(`SchnorrBatchVerify(swapped) = %t, err = %v; expected = false`, ok, err)
+	}
+
+	v := readBIP340Vectors(t)[bad]
+	ok, _ = SchnorrBatchVerify(append(pubs, v.pub), append(msgs, v.msg), append(sigs, v.sig), rand.Reader)
+	if ok {
+		t.Fatalf(`SchnorrBatchVerify(with invalid #%s) = true, expected = false`, v.index)
+	}
+	if ok, err := SchnorrBatchVerify(pubs, msgs, sigs, nil); ok || err == nil {
+		t.Fatalf(`SchnorrBatchVerify(nil rand) = %t, err = %v; expected error`, ok, err)
+	}
+}
+
+func TestSchnorrSignRandom(t *testing.T) {
+	c := Secp256k1()
+	d, _ := c.RandomScalar(rand.Reader)
+	aux := make([]byte, 32)
+	rand.Read(aux)
+	msg := []byte("ECwrap")
+
+	sig, err := SchnorrSign(d, msg, aux)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, _ := SchnorrPublicKey(d)
+	if !SchnorrVerify(pub, msg, sig) {
+		t.Fatalf(`SchnorrVerify() = false, expected = true`)
+	}
+	// key of n - d has the same x-only encoding
+	pub2, _ := SchnorrPublicKey(d.Neg())
+	if !bytes.Equal(pub, pub2) {
+		t.Fatalf(`x-only keys of d and n - d differ`)
+	}
+}
@@ -0,0 +1,160 @@
+package ECwrap
+
+import (
+	"errors"
+	"io"
+	"math/big"
+	"sort"
+)
+
+// Orders of points and the subgroups of E(F_p). All of them take
+// #E(F_p) = n*h for granted (n and h of the curve), n*h is the
+// group order whose factorization they use. For the standard
+// curves n is prime and h small, so factoring it is instant.
+// Curves with cofactors (curve25519 has h = 8) carry points of
+// small order, which a protocol must either reject
+// (`ECPIsInSubgroup`) or kill (`ECPClearCofactor`)
+
+// Largest cofactor `SmallOrderPoints` works with
+const smallOrderMaxCofactor = 64
+
+// Returns factorization of n*h, n is not factored again
+// when it is prime
+func (c *Curve) groupOrderFactors() []primePower {
+	if !c.N.ProbablyPrime(20) {
+		return factorize(new(big.Int).Mul(c.N, c.H))
+	}
+	factors := factorize(c.H)
+	for i := range factors {
+		if factors[i].p.Cmp(c.N) == 0 {
+			factors[i].e++
+			return factors
+		}
+	}
+	factors = append(factors, primePower{p: c.N, e: 1})
+	sort.Slice(factors, func(i, j int) bool { return factors[i].p.Cmp(factors[j].p) < 0 })
+	return factors
+}
+
+// Returns the order of P: n*h stripped of the prime factors
+// which still leave P at infinity. Error is returned if P is
+// not on the curve
+func (c *Curve) ECPOrder(P *ECPoint) (*big.Int, error) {
+	if !c.ECPIsOnCurve(P) {
+		return nil, errors.New("point is not on the curve")
+	}
+	return c.orderFromFactors(P, new(big.Int).Mul(c.N, c.H), c.groupOrderFactors()), nil
+}
+
+// Returns the order of P given its multiple n > 0
+func (c *Curve) orderDividing(P *ECPoint, n *big.Int) *big.Int {
+	return c.orderFromFactors(P, n, factorize(n))
+}
+
+// Returns the order of P given its multiple n and the
+// factorization of n
+func (c *Curve) orderFromFactors(P *ECPoint, n *big.Int, factors []primePower) *big.Int {
+	order := new(big.Int).Set(n)
+	for _, pp := range factors {
+		for e := 0; e < pp.e; e++ {
+			q := new(big.Int).Quo(order, pp.p)
+			if !c.ECPScalarMul(P, q).IsInfinity() {
+				break
+			}
+			order = q
+		}
+	}
+	return order
+}
+
+// Reports whether P is in the subgroup generated by the base
+// point: it is on the curve and n*P = O. The point at infinity
+// is in it
+func (c *Curve) ECPIsInSubgroup(P *ECPoint) bool {
+	return c.ECPIsOnCurve(P) && c.ECPScalarMul(P, c.N).IsInfinity()
+}
+
+// Returns h*P, which is in the subgroup of order n for any
+// point of the curve
+func (c *Curve) ECPClearCofactor(P *ECPoint) *ECPoint {
+	return c.ECPScalarMul(P, c.H)
+}
+
+// Reports whether the order of P divides h (always false for
+// h = 1, except for the point at infinity). Such points leak
+// the private key mod h in naive Diffie-Hellman
+func (c *Curve) ECPIsSmallOrder(P *ECPoint) bool {
+	return c.ECPScalarMul(P, c.H).IsInfinity()
+}
+
+// Returns all points P with h*P = O, the point at infinity
+// first, the rest affine in order of x (and y). The x of them
+// are the roots of the h-th division polynomial and, for even h,
+// of x^(3) + a*x + b. Works for cofactors up to 64
+func (c *Curve) SmallOrderPoints() ([]*ECPoint, error) {
+	if c.H.Cmp(big.NewInt(smallOrderMaxCofactor)) > 0 {
+		return nil, errors.New("cofactor is too big")
+	}
+	h := int(c.H.Int64())
+	xs := c.DivisionPolynomial(h).Roots()
+	if h%2 == 0 {
+		xs = append(xs, cubicRoots(c.A, c.B, c.P)...)
+	}
+	sort.Slice(xs, func(i, j int) bool { return xs[i].Cmp(xs[j]) < 0 })
+
+	points := []*ECPoint{PointAtInfinity()}
+	for i, x := range xs {
+		if i > 0 && x.Cmp(xs[i-1]) == 0 {
+			continue
+		}
+		y := new(big.Int).ModSqrt(c.Polynomial(x), c.P)
+		if y == nil {
+			// the point is over F_(p^2)
+			continue
+		}
+		y = smallerRoot(y, c.P)
+		points = append(points, &ECPoint{x, y, big.NewInt(1)})
+		if y.Sign() != 0 {
+			points = append(points, &ECPoint{new(big.Int).Set(x), new(big.Int).Sub(c.P, y), big.NewInt(1)})
+		}
+	}
+	return points, nil
+}
+
+// Returns random point of prime order l, which must divide
+// n*h. For l^(e) the largest power of l in n*h, R' = (n*h/l^(e))*R
+// for a random point R has order l^(k), and l^(k - 1)*R' is of
+// order l unless R' is the point at infinity. With l = n this
+// is a random generator of the subgroup of the base point
+func (c *Curve) FindGenerator(l *big.Int, rand io.Reader) (*ECPoint, error) {
+	cofactor := new(big.Int).Mul(c.N, c.H)
+	if l.Sign() <= 0 || !l.ProbablyPrime(20) || new(big.Int).Mod(cofactor, l).Sign() != 0 {
+		return nil, errors.New("l must be a prime factor of the group order")
+	}
+	e := 0
+	for new(big.Int).Mod(cofactor, l).Sign() == 0 {
+		cofactor.Quo(cofactor, l)
+		e++
+	}
+	for tries := 0; tries < 128; tries++ {
+		R, err := c.randAffinePoint(rand)
+		if err != nil {
+			return nil, err
+		}
+		G := c.ECPScalarMul(R, cofactor)
+		if G.IsInfinity() {
+			continue
+		}
+		for i := 0; ; i++ {
+			lG := c.ECPScalarMul(G, l)
+			if lG.IsInfinity() {
+				return c.ECPAffine(G), nil
+			}
+			if i == e {
+				return nil, errors.New("n*h is not the group order")
+			}
+			G = lG
+		}
+	}
+	return nil, errors.New("no point of order l found")
+}
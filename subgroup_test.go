@@ -0,0 +1,98 @@
+package ECwrap
+
+import (
+	"crypto/elliptic"
+	"math/big"
+	"testing"
+)
+
+func TestECPOrder(t *testing.T) {
+	c := toyCurve(t)
+	points, err := c.Points()
+	if err != nil {
+		t.Fatal(err)
+	}
+	inSubgroup, small := 0, 0
+	for _, P := range points {
+		// brute force order
+		expected, Q := int64(1), P
+		for !Q.IsInfinity() {
+			Q = c.ECPAdd(Q, P)
+			expected++
+		}
+		got, err := c.ECPOrder(P)
+		if err != nil || got.Int64() != expected {
+			t.Fatalf(`ECPOrder(%v) = %v, expected = %d, err = %v`, P, got, expected, err)
+		}
+		if c.ECPIsInSubgroup(P) {
+			inSubgroup++
+		}
+		if c.ECPIsSmallOrder(P) {
+			small++
+		}
+		if !c.ECPIsInSubgroup(c.ECPClearCofactor(P)) {
+			t.Fatalf(`ECPClearCofactor(%v) is not in the subgroup`, P)
+		}
+	}
+	if inSubgroup != 5 {
+		t.Fatalf(`%d points in the subgroup, expected = 5`, inSubgroup)
+	}
+
+	smallPoints, err := c.SmallOrderPoints()
+	if err != nil || len(smallPoints) != small {
+		t.Fatalf(`len(SmallOrderPoints()) = %d, expected = %d, err = %v`, len(smallPoints), small, err)
+	}
+	for _, P := range smallPoints {
+		if !c.ECPIsOnCurve(P) || !c.ECPIsSmallOrder(P) {
+			t.Fatalf(`SmallOrderPoints() returned %v`, P)
+		}
+	}
+
+	P256 := FromElliptic(elliptic.P256())
+	if _, err := P256.ECPOrder(&ECPoint{big.NewInt(1), big.NewInt(1), big.NewInt(1)}); err == nil {
+		t.Fatalf(`ECPOrder() of a point off the curve returned no error`)
+	}
+}
+
+func TestFindGenerator(t *testing.T) {
+	c := toyCurve(t)
+	rnd := NewTestReader([]byte("generator"))
+	for _, l := range []int64{2, 5} {
+		G, err := c.FindGenerator(big.NewInt(l), rnd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		order, err := c.ECPOrder(G)
+		if err != nil || order.Int64() != l {
+			t.Fatalf(`ECPOrder(FindGenerator(%d)) = %v, err = %v`, l, order, err)
+		}
+	}
+	if _, err := c.FindGenerator(big.NewInt(3), rnd); err == nil {
+		t.Fatalf(`FindGenerator(3) returned no error`)
+	}
+
+	P256 := FromElliptic(elliptic.P256())
+	G, err := P256.FindGenerator(P256.N, rnd)
+	if err != nil || G.IsInfinity() || !P256.ECPIsInSubgroup(G) {
+		t.Fatalf(`FindGenerator(n) = %v, err = %v`, G, err)
+	}
+}
+
+func TestSmallOrderPoints25519(t *testing.T) {
+	w, err := Curve25519().Weierstrass()
+	if err != nil {
+		t.Fatal(err)
+	}
+	points, err := w.SmallOrderPoints()
+	if err != nil || len(points) != 8 {
+		t.Fatalf(`len(SmallOrderPoints()) = %d, expected = 8, err = %v`, len(points), err)
+	}
+	for _, P := range points {
+		if !w.ECPIsOnCurve(P) || !w.ECPIsSmallOrder(P) {
+			t.Fatalf(`SmallOrderPoints() returned %v`, P)
+		}
+	}
+	if w.ECPIsSmallOrder(w.Generator()) || !w.ECPIsInSubgroup(w.Generator()) {
+		t.Fatalf(`base point is of small order`)
+	}
+}
@@ -0,0 +1,457 @@
+package ECwrap
+
+import (
+	"errors"
+	"io"
+	"math/big"
+)
+
+// Tower of extension fields for pairings on BN and BLS curves:
+//
+//	Fp2  = Fp[u]/(u^(2) - beta)
+//	Fp6  = Fp2[v]/(v^(3) - xi)
+//	Fp12 = Fp6[w]/(w^(2) - v)
+//
+// so w^(6) = xi and an Fp12 element is c0 + c1*w for c0, c1 in Fp6,
+// or sum of g_i*w^(i) for g_i in Fp2. Unlike `ExtField` everything
+// is written out by hand (Karatsuba, the formulas of Beuchat et al.,
+// 2010), which is still slow next to Montgomery form, but fine for
+// pairings that take a fraction of a second
+//
+// Elements are plain structs of *big.Int, always reduced mod p,
+// the methods of `Tower` return new ones and never modify their
+// arguments
+
+// Element a0 + a1*u of Fp2
+type Fp2 struct {
+	A0, A1 *big.Int
+}
+
+// Element b0 + b1*v + b2*v^(2) of Fp6
+type Fp6 struct {
+	B0, B1, B2 *Fp2
+}
+
+// Element c0 + c1*w of Fp12
+type Fp12 struct {
+	C0, C1 *Fp6
+}
+
+// Parameters of the tower
+type Tower struct {
+	P    *big.Int
+	Beta *big.Int
+	Xi   *Fp2
+
+	// gamma[j] = xi^(j*(p - 1)/6), w^(p) = gamma[1]*w
+	gamma [6]*Fp2
+}
+
+// Returns the tower over F_p, p prime and 1 mod 6, with beta a
+// non-square mod p and xi neither a square nor a cube in Fp2,
+// so that all the moduli are irreducible
+func NewTower(p, beta *big.Int, xi *Fp2) (*Tower, error) {
+	if !p.ProbablyPrime(20) {
+		return nil, errors.New("p must be prime")
+	}
+	if new(big.Int).Mod(p, big.NewInt(6)).Int64() != 1 {
+		return nil, errors.New("p must be 1 mod 6")
+	}
+	t := &Tower{P: p, Beta: new(big.Int).Mod(beta, p)}
+	if big.Jacobi(t.Beta, p) != -1 {
+		return nil, errors.New("beta must be a non-square mod p")
+	}
+	t.Xi = t.Fp2(xi.A0, xi.A1)
+	q1 := new(big.Int).Sub(new(big.Int).Mul(p, p), big.NewInt(1))
+	for _, d := range []int64{2, 3} {
+		e := new(big.Int).Quo(q1, big.NewInt(d))
+		if t.Fp2Equal(t.Fp2Exp(t.Xi, e), t.Fp2One()) {
+			return nil, errors.New("xi must be neither a square nor a cube in Fp2")
+		}
+	}
+	g := t.Fp2Exp(t.Xi, new(big.Int).Quo(new(big.Int).Sub(p, big.NewInt(1)), big.NewInt(6)))
+	t.gamma[0] = t.Fp2One()
+	for j := 1; j < 6; j++ {
+		t.gamma[j] = t.Fp2Mul(t.gamma[j-1], g)
+	}
+	return t, nil
+}
+
+func (t *Tower) mod(x *big.Int) *big.Int {
+	return x.Mod(x, t.P)
+}
+
+// ============================== Fp2 ==============================
+
+// Returns a0 + a1*u, reduced mod p
+func (t *Tower) Fp2(a0, a1 *big.Int) *Fp2 {
+	return &Fp2{t.mod(new(big.Int).Set(a0)), t.mod(new(big.Int).Set(a1))}
+}
+
+func (t *Tower) Fp2Zero() *Fp2 {
+	return &Fp2{new(big.Int), new(big.Int)}
+}
+
+func (t *Tower) Fp2One() *Fp2 {
+	return &Fp2{big.NewInt(1), new(big.Int)}
+}
+
+// Returns uniformly random element
+func (t *Tower) Fp2Rand(rand io.Reader) (*Fp2, error) {
+	a0, err := randInt(rand, t.P)
+	if err != nil {
+		return nil, err
+	}
+	a1, err := randInt(rand, t.P)
+	if err != nil {
+		return nil, err
+	}
+	return &Fp2{a0, a1}, nil
+}
+
+func (t *Tower) Fp2IsZero(a *Fp2) bool {
+	return a.A0.Sign() == 0 && a.A1.Sign() == 0
+}
+
+func (t *Tower) Fp2Equal(a, b *Fp2) bool {
+	return a.A0.Cmp(b.A0) == 0 && a.A1.Cmp(b.A1) == 0
+}
+
+func (t *Tower) Fp2Add(a, b *Fp2) *Fp2 {
+	return &Fp2{t.mod(new(big.Int).Add(a.A0, b.A0)), t.mod(new(big.Int).Add(a.A1, b.A1))}
+}
+
+func (t *Tower) Fp2Sub(a, b *Fp2) *Fp2 {
+	return &Fp2{t.mod(new(big.Int).Sub(a.A0, b.A0)), t.mod(new(big.Int).Sub(a.A1, b.A1))}
+}
+
+func (t *Tower) Fp2Neg(a *Fp2) *Fp2 {
+	return t.Fp2Sub(t.Fp2Zero(), a)
+}
+
+// Returns a0 - a1*u, which is a^(p)
+func (t *Tower) Fp2Conj(a *Fp2) *Fp2 {
+	return &Fp2{new(big.Int).Set(a.A0), t.mod(new(big.Int).Neg(a.A1))}
+}
+
+// Returns k*a for k in Fp
+func (t *Tower) Fp2MulScalar(a *Fp2, k *big.Int) *Fp2 {
+	return &Fp2{t.mod(new(big.Int).Mul(a.A0, k)), t.mod(new(big.Int).Mul(a.A1, k))}
+}
+
+// Karatsuba: (a0 + a1*u)(b0 + b1*u) = a0*b0 + beta*a1*b1
+// + ((a0 + a1)(b0 + b1) - a0*b0 - a1*b1)*u
+func (t *Tower) Fp2Mul(a, b *Fp2) *Fp2 {
+	v0 := new(big.Int).Mul(a.A0, b.A0)
+	v1 := new(big.Int).Mul(a.A1, b.A1)
+	c1 := new(big.Int).Mul(new(big.Int).Add(a.A0, a.A1), new(big.Int).Add(b.A0, b.A1))
+	c1.Sub(c1, v0).Sub(c1, v1)
+	c0 := v0.Add(v0, v1.Mul(v1, t.Beta))
+	return &Fp2{t.mod(c0), t.mod(c1)}
+}
+
+// (a0 + a1*u)^(2) = a0^(2) + beta*a1^(2) + 2*a0*a1*u
+func (t *Tower) Fp2Square(a *Fp2) *Fp2 {
+	c0 := new(big.Int).Mul(a.A0, a.A0)
+	c0.Add(c0, new(big.Int).Mul(new(big.Int).Mul(a.A1, a.A1), t.Beta))
+	c1 := new(big.Int).Mul(a.A0, a.A1)
+	return &Fp2{t.mod(c0), t.mod(c1.Lsh(c1, 1))}
+}
+
+// Returns a^(-1) = conj(a)/(a0^(2) - beta*a1^(2)). Panics if a is zero
+func (t *Tower) Fp2Inv(a *Fp2) *Fp2 {
+	if t.Fp2IsZero(a) {
+		panic("ECwrap: inverse of zero")
+	}
+	norm := new(big.Int).Mul(a.A0, a.A0)
+	norm.Sub(norm, new(big.Int).Mul(new(big.Int).Mul(a.A1, a.A1), t.Beta))
+	norm.ModInverse(t.mod(norm), t.P)
+	return t.Fp2MulScalar(t.Fp2Conj(a), norm)
+}
+
+// Returns a^(e), negative e inverts a first
+func (t *Tower) Fp2Exp(a *Fp2, e *big.Int) *Fp2 {
+	if e.Sign() < 0 {
+		return t.Fp2Exp(t.Fp2Inv(a), new(big.Int).Neg(e))
+	}
+	out := t.Fp2One()
+	for i := e.BitLen() - 1; i >= 0; i-- {
+		out = t.Fp2Square(out)
+		if e.Bit(i) == 1 {
+			out = t.Fp2Mul(out, a)
+		}
+	}
+	return out
+}
+
+// Returns square root of a, nil if there is none. With norm
+// n = a0^(2) - beta*a1^(2) = s^(2), the root is x0 + x1*u for
+// x0^(2) = (a0 +- s)/2 and x1 = a1/(2*x0)
+func (t *Tower) Fp2Sqrt(a *Fp2) *Fp2 {
+	if a.A1.Sign() == 0 {
+		if r := new(big.Int).ModSqrt(a.A0, t.P); r != nil {
+			return &Fp2{r, new(big.Int)}
+		}
+		// a0/beta is a square when a0 is not
+		r := new(big.Int).Mul(a.A0, new(big.Int).ModInverse(t.Beta, t.P))
+		return &Fp2{new(big.Int), new(big.Int).ModSqrt(t.mod(r), t.P)}
+	}
+	norm := new(big.Int).Mul(a.A0, a.A0)
+	norm.Sub(norm, new(big.Int).Mul(new(big.Int).Mul(a.A1, a.A1), t.Beta))
+	s := new(big.Int).ModSqrt(t.mod(norm), t.P)
+	if s == nil {
+		return nil
+	}
+	half := new(big.Int).ModInverse(big.NewInt(2), t.P)
+	x0 := new(big.Int).ModSqrt(t.mod(new(big.Int).Mul(new(big.Int).Add(a.A0, s), half)), t.P)
+	if x0 == nil {
+		x0 = new(big.Int).ModSqrt(t.mod(new(big.Int).Mul(new(big.Int).Sub(a.A0, s), half)), t.P)
+	}
+	x1 := new(big.Int).Mul(a.A1, new(big.Int).ModInverse(new(big.Int).Lsh(x0, 1), t.P))
+	return &Fp2{x0, t.mod(x1)}
+}
+
+// ============================== Fp6 ==============================
+
+func (t *Tower) Fp6Zero() *Fp6 {
+	return &Fp6{t.Fp2Zero(), t.Fp2Zero(), t.Fp2Zero()}
+}
+
+func (t *Tower) Fp6One() *Fp6 {
+	return &Fp6{t.Fp2One(), t.Fp2Zero(), t.Fp2Zero()}
+}
+
+func (t *Tower) Fp6IsZero(a *Fp6) bool {
+	return t.Fp2IsZero(a.B0) && t.Fp2IsZero(a.B1) && t.Fp2IsZero(a.B2)
+}
+
+func (t *Tower) Fp6Equal(a, b *Fp6) bool {
+	return t.Fp2Equal(a.B0, b.B0) && t.Fp2Equal(a.B1, b.B1) && t.Fp2Equal(a.B2, b.B2)
+}
+
+func (t *Tower) Fp6Add(a, b *Fp6) *Fp6 {
+	return &Fp6{t.Fp2Add(a.B0, b.B0), t.Fp2Add(a.B1, b.B1), t.Fp2Add(a.B2, b.B2)}
+}
+
+func (t *Tower) Fp6Sub(a, b *Fp6) *Fp6 {
+	return &Fp6{t.Fp2Sub(a.B0, b.B0), t.Fp2Sub(a.B1, b.B1), t.Fp2Sub(a.B2, b.B2)}
+}
+
+func (t *Tower) Fp6Neg(a *Fp6) *Fp6 {
+	return &Fp6{t.Fp2Neg(a.B0), t.Fp2Neg(a.B1), t.Fp2Neg(a.B2)}
+}
+
+// Returns a*v = xi*b2 + b0*v + b1*v^(2)
+func (t *Tower) Fp6MulV(a *Fp6) *Fp6 {
+	return &Fp6{t.Fp2Mul(a.B2, t.Xi), a.B0, a.B1}
+}
+
+// Returns a*k for k in Fp2
+func (t *Tower) Fp6MulFp2(a *Fp6, k *Fp2) *Fp6 {
+	return &Fp6{t.Fp2Mul(a.B0, k), t.Fp2Mul(a.B1, k), t.Fp2Mul(a.B2, k)}
+}
+
+// Karatsuba for three terms, 6 multiplications in Fp2
+func (t *Tower) Fp6Mul(a, b *Fp6) *Fp6 {
+	t0 := t.Fp2Mul(a.B0, b.B0)
+	t1 := t.Fp2Mul(a.B1, b.B1)
+	t2 := t.Fp2Mul(a.B2, b.B2)
+	// c0 = t0 + xi*((a1 + a2)(b1 + b2) - t1 - t2)
+	c0 := t.Fp2Mul(t.Fp2Add(a.B1, a.B2), t.Fp2Add(b.B1, b.B2))
+	c0 = t.Fp2Add(t0, t.Fp2Mul(t.Fp2Sub(t.Fp2Sub(c0, t1), t2), t.Xi))
+	// c1 = (a0 + a1)(b0 + b1) - t0 - t1 + xi*t2
+	c1 := t.Fp2Mul(t.Fp2Add(a.B0, a.B1), t.Fp2Add(b.B0, b.B1))
+	c1 = t.Fp2Add(t.Fp2Sub(t.Fp2Sub(c1, t0), t1), t.Fp2Mul(t2, t.Xi))
+	// c2 = (a0 + a2)(b0 + b2) - t0 - t2 + t1
+	c2 := t.Fp2Mul(t.Fp2Add(a.B0, a.B2), t.Fp2Add(b.B0, b.B2))
+	c2 = t.Fp2Add(t.Fp2Sub(t.Fp2Sub(c2, t0), t2), t1)
+	return &Fp6{c0, c1, c2}
+}
+
+func (t *Tower) Fp6Square(a *Fp6) *Fp6 {
+	return t.Fp6Mul(a, a)
+}
+
+// Returns a^(-1), Algorithm 17 of Beuchat et al. Panics if a is zero
+func (t *Tower) Fp6Inv(a *Fp6) *Fp6 {
+	if t.Fp6IsZero(a) {
+		panic("ECwrap: inverse of zero")
+	}
+	c0 := t.Fp2Sub(t.Fp2Square(a.B0), t.Fp2Mul(t.Fp2Mul(a.B1, a.B2), t.Xi))
+	c1 := t.Fp2Sub(t.Fp2Mul(t.Fp2Square(a.B2), t.Xi), t.Fp2Mul(a.B0, a.B1))
+	c2 := t.Fp2Sub(t.Fp2Square(a.B1), t.Fp2Mul(a.B0, a.B2))
+	// norm = a0*c0 + xi*(a2*c1 + a1*c2)
+	norm := t.Fp2Add(t.Fp2Mul(a.B2, c1), t.Fp2Mul(a.B1, c2))
+	norm = t.Fp2Add(t.Fp2Mul(a.B0, c0), t.Fp2Mul(norm, t.Xi))
+	return t.Fp6MulFp2(&Fp6{c0, c1, c2}, t.Fp2Inv(norm))
+}
+
+// Returns a^(p): v^(p) = gamma_2*v
+func (t *Tower) Fp6Frobenius(a *Fp6) *Fp6 {
+	return &Fp6{
+		t.Fp2Conj(a.B0),
+		t.Fp2Mul(t.Fp2Conj(a.B1), t.gamma[2]),
+		t.Fp2Mul(t.Fp2Conj(a.B2), t.gamma[4]),
+	}
+}
+
+// ============================== Fp12 =============================
+
+func (t *Tower) Fp12Zero() *Fp12 {
+	return &Fp12{t.Fp6Zero(), t.Fp6Zero()}
+}
+
+func (t *Tower) Fp12One() *Fp12 {
+	return &Fp12{t.Fp6One(), t.Fp6Zero()}
+}
+
+// Returns uniformly random element
+func (t *Tower) Fp12Rand(rand io.Reader) (*Fp12, error) {
+	var g [6]*Fp2
+	for i := range g {
+		var err error
+		if g[i], err = t.Fp2Rand(rand); err != nil {
+			return nil, err
+		}
+	}
+	return &Fp12{&Fp6{g[0], g[1], g[2]}, &Fp6{g[3], g[4], g[5]}}, nil
+}
+
+func (t *Tower) Fp12IsZero(a *Fp12) bool {
+	return t.Fp6IsZero(a.C0) && t.Fp6IsZero(a.C1)
+}
+
+func (t *Tower) Fp12IsOne(a *Fp12) bool {
+	return t.Fp12Equal(a, t.Fp12One())
+}
+
+func (t *Tower) Fp12Equal(a, b *Fp12) bool {
+	return t.Fp6Equal(a.C0, b.C0) && t.Fp6Equal(a.C1, b.C1)
+}
+
+func (t *Tower) Fp12Add(a, b *Fp12) *Fp12 {
+	return &Fp12{t.Fp6Add(a.C0, b.C0), t.Fp6Add(a.C1, b.C1)}
+}
+
+func (t *Tower) Fp12Sub(a, b *Fp12) *Fp12 {
+	return &Fp12{t.Fp6Sub(a.C0, b.C0), t.Fp6Sub(a.C1, b.C1)}
+}
+
+func (t *Tower) Fp12Neg(a *Fp12) *Fp12 {
+	return &Fp12{t.Fp6Neg(a.C0), t.Fp6Neg(a.C1)}
+}
+
+// Returns c0 - c1*w, which is a^(p^6), the inverse for
+// elements of the cyclotomic subgroup
+func (t *Tower) Fp12Conj(a *Fp12) *Fp12 {
+	return &Fp12{a.C0, t.Fp6Neg(a.C1)}
+}
+
+// Karatsuba: c0 = a0*b0 + v*a1*b1, c1 = (a0 + a1)(b0 + b1) - a0*b0 - a1*b1
+func (t *Tower) Fp12Mul(a, b *Fp12) *Fp12 {
+	t0 := t.Fp6Mul(a.C0, b.C0)
+	t1 := t.Fp6Mul(a.C1, b.C1)
+	c1 := t.Fp6Mul(t.Fp6Add(a.C0, a.C1), t.Fp6Add(b.C0, b.C1))
+	c1 = t.Fp6Sub(t.Fp6Sub(c1, t0), t1)
+	return &Fp12{t.Fp6Add(t0, t.Fp6MulV(t1)), c1}
+}
+
+// Complex squaring: c0 = (a0 + a1)(a0 + v*a1) - a0*a1 - v*a0*a1,
+// c1 = 2*a0*a1
+func (t *Tower) Fp12Square(a *Fp12) *Fp12 {
+	ab := t.Fp6Mul(a.C0, a.C1)
+	c0 := t.Fp6Mul(t.Fp6Add(a.C0, a.C1), t.Fp6Add(a.C0, t.Fp6MulV(a.C1)))
+	c0 = t.Fp6Sub(t.Fp6Sub(c0, ab), t.Fp6MulV(ab))
+	return &Fp12{c0, t.Fp6Add(ab, ab)}
+}
+
+// Returns a^(-1) = (c0 - c1*w)/(c0^(2) - v*c1^(2)). Panics if a is zero
+func (t *Tower) Fp12Inv(a *Fp12) *Fp12 {
+	if t.Fp12IsZero(a) {
+		panic("ECwrap: inverse of zero")
+	}
+	norm := t.Fp6Sub(t.Fp6Square(a.C0), t.Fp6MulV(t.Fp6Square(a.C1)))
+	inv := t.Fp6Inv(norm)
+	return &Fp12{t.Fp6Mul(a.C0, inv), t.Fp6Neg(t.Fp6Mul(a.C1, inv))}
+}
+
+// Returns a^(p^n). Frobenius maps g*w^(i) to conj(g)*gamma_i*w^(i)
+func (t *Tower) Fp12Frobenius(a *Fp12, n int) *Fp12 {
+	for ; n > 0; n-- {
+		a = &Fp12{
+			t.Fp6Frobenius(a.C0),
+			&Fp6{
+				t.Fp2Mul(t.Fp2Conj(a.C1.B0), t.gamma[1]),
+				t.Fp2Mul(t.Fp2Conj(a.C1.B1), t.gamma[3]),
+				t.Fp2Mul(t.Fp2Conj(a.C1.B2), t.gamma[5]),
+			},
+		}
+	}
+	return a
+}
+
+// Returns a^(2) for a in the cyclotomic subgroup (a^(p^4 - p^2 + 1) = 1,
+// which holds after the easy part of the final exponentiation),
+// Granger and Scott, 2010. Fp12 is viewed as Fp4^(3) with
+// Fp4 = Fp2[s]/(s^(2) - xi), s = w^(3), and squares in Fp4 are cheap
+func (t *Tower) Fp12CyclotomicSquare(a *Fp12) *Fp12 {
+	// the pairs (x0, x4), (x3, x2), (x1, x5) of Fp4 elements
+	x0, x1, x2 := a.C0.B0, a.C0.B1, a.C0.B2
+	x3, x4, x5 := a.C1.B0, a.C1.B1, a.C1.B2
+
+	// returns x^(2) + xi*y^(2) and 2*x*y
+	sq := func(x, y *Fp2) (*Fp2, *Fp2) {
+		xx, yy := t.Fp2Square(x), t.Fp2Square(y)
+		xy := t.Fp2Sub(t.Fp2Sub(t.Fp2Square(t.Fp2Add(x, y)), xx), yy)
+		return t.Fp2Add(xx, t.Fp2Mul(yy, t.Xi)), xy
+	}
+	s04, m04 := sq(x0, x4)
+	s32, m32 := sq(x3, x2)
+	s15, m15 := sq(x1, x5)
+	m15 = t.Fp2Mul(m15, t.Xi)
+
+	// 3*s - 2*x and 3*m + 2*x
+	minus := func(s, x *Fp2) *Fp2 {
+		d := t.Fp2Sub(s, x)
+		return t.Fp2Add(t.Fp2Add(d, d), s)
+	}
+	plus := func(m, x *Fp2) *Fp2 {
+		d := t.Fp2Add(m, x)
+		return t.Fp2Add(t.Fp2Add(d, d), m)
+	}
+	return &Fp12{
+		&Fp6{minus(s04, x0), minus(s32, x1), minus(s15, x2)},
+		&Fp6{plus(m15, x3), plus(m04, x4), plus(m32, x5)},
+	}
+}
+
+// Returns a^(e), negative e inverts a first
+func (t *Tower) Fp12Exp(a *Fp12, e *big.Int) *Fp12 {
+	if e.Sign() < 0 {
+		return t.Fp12Exp(t.Fp12Inv(a), new(big.Int).Neg(e))
+	}
+	out := t.Fp12One()
+	for i := e.BitLen() - 1; i >= 0; i-- {
+		out = t.Fp12Square(out)
+		if e.Bit(i) == 1 {
+			out = t.Fp12Mul(out, a)
+		}
+	}
+	return out
+}
+
+// Returns a^(e) for a in the cyclotomic subgroup, where squaring
+// is `Fp12CyclotomicSquare` and inversion is `Fp12Conj`
+func (t *Tower) Fp12CyclotomicExp(a *Fp12, e *big.Int) *Fp12 {
+	if e.Sign() < 0 {
+		return t.Fp12CyclotomicExp(t.Fp12Conj(a), new(big.Int).Neg(e))
+	}
+	out := t.Fp12One()
+	for i := e.BitLen() - 1; i >= 0; i-- {
+		out = t.Fp12CyclotomicSquare(out)
+		if e.Bit(i) == 1 {
+			out = t.Fp12Mul(out, a)
+		}
+	}
+	return out
+}
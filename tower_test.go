@@ -0,0 +1,166 @@
+package ECwrap
+
+import (
+	"math/big"
+	"testing"
+)
+
+func bn254Tower(t *testing.T) *Tower {
+	p := mustDec("21888242871839275222246405745257275088696311157297823662689037894645226208583")
+	tw, err := NewTower(p, big.NewInt(-1), &Fp2{big.NewInt(9), big.NewInt(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tw
+}
+
+// Returns a as a polynomial in w of F_p[w]/((w^6 - xi0)^2 - beta*xi1^2)
+func fp12ToExt(tw *Tower, F *ExtField, a *Fp12) *Poly {
+	// u = (w^6 - xi0)/xi1
+	w := PolyX(tw.P)
+	w6 := F.Exp(w, big.NewInt(6))
+	u := F.Mul(F.Sub(w6, F.FromInt(tw.Xi.A0)), F.FromInt(new(big.Int).ModInverse(tw.Xi.A1, tw.P)))
+	out := F.Zero()
+	for i, g := range []*Fp2{a.C0.B0, a.C1.B0, a.C0.B1, a.C1.B1, a.C0.B2, a.C1.B2} {
+		gi := F.Add(F.FromInt(g.A0), F.Mul(F.FromInt(g.A1), u))
+		out = F.Add(out, F.Mul(gi, F.Exp(w, big.NewInt(int64(i)))))
+	}
+	return out
+}
+
+func TestTowerToy(t *testing.T) {
+	p := big.NewInt(103)
+	var tw *Tower
+	for c := int64(1); tw == nil; c++ {
+		tw, _ = NewTower(p, big.NewInt(-1), &Fp2{big.NewInt(c), big.NewInt(1)})
+	}
+	// (w^6 - xi0)^2 - beta*xi1^2
+	xi0, xi1 := tw.Xi.A0, tw.Xi.A1
+	m := make([]*big.Int, 13)
+	for i := range m {
+		m[i] = new(big.Int)
+	}
+	m[12].SetInt64(1)
+	m[6].Mul(xi0, big.NewInt(-2))
+	m[0].Mul(xi0, xi0).Sub(m[0], new(big.Int).Mul(tw.Beta, new(big.Int).Mul(xi1, xi1)))
+	F, err := NewExtFieldModulus(NewPoly(p, m...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rnd := NewTestReader([]byte("toy tower"))
+	for i := 0; i < 16; i++ {
+		a, _ := tw.Fp12Rand(rnd)
+		b, _ := tw.Fp12Rand(rnd)
+		fa, fb := fp12ToExt(tw, F, a), fp12ToExt(tw, F, b)
+		for _, tc := range []struct {
+			name          string
+			got, expected *Poly
+		}{
+			{"Fp12Mul", fp12ToExt(tw, F, tw.Fp12Mul(a, b)), F.Mul(fa, fb)},
+			{"Fp12Square", fp12ToExt(tw, F, tw.Fp12Square(a)), F.Mul(fa, fa)},
+			{"Fp12Inv", fp12ToExt(tw, F, tw.Fp12Inv(a)), F.Inv(fa)},
+			{"Fp12Frobenius", fp12ToExt(tw, F, tw.Fp12Frobenius(a, 1)), F.Frobenius(fa)},
+			{"Fp12Conj", fp12ToExt(tw, F, tw.Fp12Conj(a)), F.Exp(fa, new(big.Int).Exp(p, big.NewInt(6), nil))},
+		} {
+			if !F.Equal(tc.got, tc.expected) {
+				t.Fatalf(`%s() = %v, expected = %v`, tc.name, tc.got, tc.expected)
+			}
+		}
+	}
+}
+
+func TestTower(t *testing.T) {
+	tw := bn254Tower(t)
+	rnd := NewTestReader([]byte("tower"))
+	one := tw.Fp12One()
+	for i := 0; i < 4; i++ {
+		a, _ := tw.Fp12Rand(rnd)
+		b, _ := tw.Fp12Rand(rnd)
+		if !tw.Fp12Equal(tw.Fp12Mul(a, tw.Fp12Inv(a)), one) {
+			t.Fatalf(`a*a^(-1) != 1`)
+		}
+		if !tw.Fp12Equal(tw.Fp12Square(a), tw.Fp12Mul(a, a)) {
+			t.Fatalf(`Fp12Square(a) != a*a`)
+		}
+		if !tw.Fp12Equal(tw.Fp12Mul(a, b), tw.Fp12Mul(b, a)) {
+			t.Fatalf(`a*b != b*a`)
+		}
+		if !tw.Fp12Equal(tw.Fp12Frobenius(a, 1), tw.Fp12Exp(a, tw.P)) {
+			t.Fatalf(`Fp12Frobenius(a, 1) != a^p`)
+		}
+		if !tw.Fp12Equal(tw.Fp12Frobenius(a, 12), a) {
+			t.Fatalf(`Fp12Frobenius(a, 12) != a`)
+		}
+		if !tw.Fp6Equal(tw.Fp6Frobenius(a.C0), tw.Fp12Frobenius(&Fp12{a.C0, tw.Fp6Zero()}, 1).C0) {
+			t.Fatalf(`Fp6Frobenius(a) != a^p`)
+		}
+
+		// a^((p^6 - 1)(p^2 + 1)) is in the cyclotomic subgroup
+		c := tw.Fp12Mul(tw.Fp12Conj(a), tw.Fp12Inv(a))
+		c = tw.Fp12Mul(tw.Fp12Frobenius(c, 2), c)
+		if !tw.Fp12Equal(tw.Fp12CyclotomicSquare(c), tw.Fp12Square(c)) {
+			t.Fatalf(`Fp12CyclotomicSquare(c) != c^2`)
+		}
+		if !tw.Fp12Equal(tw.Fp12Conj(c), tw.Fp12Inv(c)) {
+			t.Fatalf(`Fp12Conj(c) != c^(-1)`)
+		}
+		e := new(big.Int).Lsh(big.NewInt(-0x1234567), 100)
+		if !tw.Fp12Equal(tw.Fp12CyclotomicExp(c, e), tw.Fp12Exp(c, e)) {
+			t.Fatalf(`Fp12CyclotomicExp(c, e) != c^e`)
+		}
+
+		x, _ := tw.Fp2Rand(rnd)
+		r := tw.Fp2Sqrt(tw.Fp2Square(x))
+		if r == nil || !tw.Fp2Equal(tw.Fp2Square(r), tw.Fp2Square(x)) {
+			t.Fatalf(`Fp2Sqrt(%v^2) = %v`, x, r)
+		}
+		if tw.Fp2Sqrt(tw.Fp2Mul(tw.Fp2Square(x), tw.Xi)) != nil {
+			t.Fatalf(`Fp2Sqrt() of a non-square is not nil`)
+		}
+	}
+
+	for _, tc := range []struct {
+		p, beta, xi0 int64
+	}{{101, -1, 1}, {103, 1, 1}, {103, -1, 0}} {
+		if _, err := NewTower(big.NewInt(tc.p), big.NewInt(tc.beta), &Fp2{big.NewInt(tc.xi0), big.NewInt(1)}); err == nil {
+			t.Fatalf(`NewTower(%d, %d, %d + u) returned no error`, tc.p, tc.beta, tc.xi0)
+		}
+	}
+}
+
+func TestFp2Curve(t *testing.T) {
+	tw := bn254Tower(t)
+	// BN254 G2: y^2 = x^3 + 3/(9 + u)
+	c, err := NewFp2Curve(tw, tw.Fp2Zero(), tw.Fp2Mul(tw.Fp2(big.NewInt(3), big.NewInt(0)), tw.Fp2Inv(tw.Xi)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	G := &Fp2Point{
+		X: &Fp2{mustDec("10857046999023057135944570762232829481370756359578518086990519993285655852781"),
+			mustDec("11559732032986387107991004021392285783925812861821192530917403151452391805634")},
+		Y: &Fp2{mustDec("8495653923123431417604973247489272438418190587263600148770280649306958101930"),
+			mustDec("4082367875863433681332203403145435568316851327593401208105741076214120093531")},
+	}
+	if !c.IsOnCurve(G) {
+		t.Fatalf(`G2 generator is not on the twist`)
+	}
+	r := mustDec("21888242871839275222246405745257275088548364400416034343698204186575808495617")
+	if !c.ScalarMul(G, r).Inf {
+		t.Fatalf(`r*G != O`)
+	}
+	a, b := big.NewInt(123456789), big.NewInt(-987654321)
+	if !c.Equal(c.ScalarMul(G, new(big.Int).Add(a, b)), c.Add(c.ScalarMul(G, a), c.ScalarMul(G, b))) {
+		t.Fatalf(`(a + b)*G != a*G + b*G`)
+	}
+	if !c.Sub(G, G).Inf {
+		t.Fatalf(`G - G != O`)
+	}
+	P, err := c.RandPoint(NewTestReader([]byte("twist")))
+	if err != nil || !c.IsOnCurve(P) || !c.IsOnCurve(c.Double(P)) {
+		t.Fatalf(`RandPoint() = %v, err = %v`, P, err)
+	}
+	if _, err := NewFp2Curve(tw, tw.Fp2Zero(), tw.Fp2Zero()); err == nil {
+		t.Fatalf(`NewFp2Curve() of a singular curve returned no error`)
+	}
+}
@@ -0,0 +1,228 @@
+package ECwrap
+
+import (
+	"errors"
+	"io"
+	"math/big"
+	"strconv"
+)
+
+// Random curves over small prime fields, for teaching and for
+// tests that would take forever on the real curves. Points are
+// counted with Mestre's method (see `CountPoints`), so the field
+// is at most 64 bits. Up to 40 bits or so the curves fall to
+// `PollardRho` in seconds, bigger ones are for `ParallelRho`
+
+// The largest field counted naively, in bits
+const naiveMaxBits = 24
+
+// The largest field `Points` enumerates
+const toyMaxEnumerate = 1 << 20
+
+// Generates random curve y^(2) = x^(3) + a*x + b over random
+// prime field of `bits` bits (3 to 64) whose order is h*n for
+// prime n and the given cofactor h (1 for prime order curves).
+// Base point of order n is picked at random. A 40-bit field takes
+// well under a second, a 56-bit one seconds, 60 to 64 bits or
+// bigger cofactors up to a minute
+func GenerateToyCurve(bits int, h int64, rand io.Reader) (*Curve, error) {
+	if bits < 3 || bits > mestreMaxBits {
+		return nil, errors.New("field size must be from 3 to 64 bits")
+	}
+	if h < 1 {
+		return nil, errors.New("cofactor must be positive")
+	}
+	// Hasse: #E <= p + 1 + 2*sqrt(p)
+	if bits < 64 && h > 1<<(bits-1) {
+		return nil, errors.New("cofactor is too big for the field")
+	}
+
+	// a curve of the order turns up after some ln(p)*h tries,
+	// a field with none of them is unlikely, but possible
+	for fields := 0; fields < 16; fields++ {
+		p, err := randPrime(rand, bits)
+		if err != nil {
+			return nil, err
+		}
+		for tries := 0; tries < 64*bits*int(min(h, 64)); tries++ {
+			a, err := randInt(rand, new(big.Int).SetUint64(p))
+			if err != nil {
+				return nil, err
+			}
+			b, err := randInt(rand, new(big.Int).SetUint64(p))
+			if err != nil {
+				return nil, err
+			}
+			c := &Curve{P: new(big.Int).SetUint64(p), A: a, B: b, H: big.NewInt(h)}
+			if c.Discriminant().Sign() == 0 {
+				continue
+			}
+			// points (x, 0) are of order 2, the order is even
+			// if and only if there are some, no need to count
+			if (h%2 == 0) != (len(cubicRoots(a, b, c.P)) != 0) {
+				continue
+			}
+			n, err := c.toyOrder(rand)
+			if err != nil {
+				return nil, err
+			}
+			if n == nil {
+				continue
+			}
+			c.N = n
+			G, err := c.toyGenerator(rand)
+			if err != nil {
+				return nil, err
+			}
+			return NewCurve("toy"+strconv.FormatUint(p, 10), c.P, a, b, G.X, G.Y, n, c.H)
+		}
+	}
+	return nil, errors.New("no curve of the order found")
+}
+
+// Returns n if #E = h*n for prime n, nil otherwise. A random
+// point R with h*R != O is enough: if M*R = O for M = h*n in the
+// Hasse interval, n divides the order of R and so #E, and for
+// n > 4*sqrt(p) there is one multiple of n in the interval. That
+// is one baby-step giant-step instead of the few `CountPoints`
+// needs, which is used for small fields and small n
+func (c *Curve) toyOrder(rand io.Reader) (*big.Int, error) {
+	quotient := func(order *big.Int) *big.Int {
+		n, rem := new(big.Int).QuoRem(order, c.H, new(big.Int))
+		if rem.Sign() != 0 || !n.ProbablyPrime(20) {
+			return nil
+		}
+		return n
+	}
+	count := func() (*big.Int, error) {
+		order, _, err := c.CountPoints(rand)
+		if err != nil {
+			return nil, err
+		}
+		return quotient(order), nil
+	}
+	if c.P.BitLen() <= 16 {
+		return count()
+	}
+
+	// odd l divides #E if there is a point of order l, an x
+	// among the roots of psi_l with f(x) a square. It is the case
+	// for about a half of the curves, and it is cheaper to check
+	for _, l := range []int64{3, 5} {
+		if c.H.Int64()%l == 0 {
+			continue
+		}
+		for _, x := range c.DivisionPolynomial(int(l)).Roots() {
+			if isSquare(c.Polynomial(x), c.P) {
+				return nil, nil
+			}
+		}
+	}
+
+	R, err := c.randAffinePoint(rand)
+	if err != nil {
+		return nil, err
+	}
+	if c.ECPScalarMul(R, c.H).IsInfinity() {
+		return count()
+	}
+	bound := hasseBound(c.P)
+	lo := new(big.Int).Sub(new(big.Int).Add(c.P, big.NewInt(1)), bound)
+	w := new(big.Int).Lsh(bound, 1)
+	M, err := c.multipleInInterval(R, lo, w)
+	if err != nil {
+		return nil, err
+	}
+	n := quotient(M)
+	if n != nil && n.Cmp(w) <= 0 {
+		return count()
+	}
+	return n, nil
+}
+
+// Returns random point of order n: h*R for random point R,
+// until it is not the point at infinity
+func (c *Curve) toyGenerator(rand io.Reader) (*ECPoint, error) {
+	for {
+		x, err := randInt(rand, c.P)
+		if err != nil {
+			return nil, err
+		}
+		y := new(big.Int).ModSqrt(c.Polynomial(x), c.P)
+		if y == nil {
+			continue
+		}
+		R := new(ECPoint)
+		R.SetCoords(x, y, big.NewInt(1))
+		G := c.ECPAffine(c.ECPScalarMul(R, c.H))
+		if G.IsInfinity() || !c.ECPScalarMul(G, c.N).IsInfinity() {
+			continue
+		}
+		return G, nil
+	}
+}
+
+// Returns random prime of exactly `bits` bits, greater than 3
+func randPrime(rand io.Reader, bits int) (uint64, error) {
+	lo := new(big.Int).Lsh(big.NewInt(1), uint(bits-1))
+	for {
+		v, err := randInt(rand, lo)
+		if err != nil {
+			return 0, err
+		}
+		v.Add(v, lo)
+		if v.Uint64() > 3 && v.ProbablyPrime(20) {
+			return v.Uint64(), nil
+		}
+	}
+}
+
+// Returns table of the number of square roots (0, 1 or 2)
+// of every element of F_p
+func squareCounts(p uint64) []uint8 {
+	sq := make([]uint8, p)
+	sq[0] = 1
+	for y := uint64(1); y <= p/2; y++ {
+		sq[y*y%p] = 2
+	}
+	return sq
+}
+
+// #E(F_p) of y^(2) = x^(3) + a*x + b, counted as 1 (the point
+// at infinity) plus the number of roots of x^(3) + a*x + b
+// for every x. p is below 2^32
+func naiveOrder(p, a, b uint64, sq []uint8) uint64 {
+	order := uint64(1)
+	for x := uint64(0); x < p; x++ {
+		order += uint64(sq[(x*x%p*x%p+a*x%p+b)%p])
+	}
+	return order
+}
+
+// Returns all points of the curve, the point at infinity first,
+// the rest affine in order of x (and y). Works for fields up to
+// 2^20, there are about p points
+func (c *Curve) Points() ([]*ECPoint, error) {
+	if c.P.Cmp(big.NewInt(toyMaxEnumerate)) > 0 {
+		return nil, errors.New("field is too big to enumerate the points")
+	}
+	p, a, b := c.P.Uint64(), c.A.Uint64(), c.B.Uint64()
+	// root[v] = y + 1 for the smaller square root y of v
+	root := make([]uint32, p)
+	for y := uint64(0); y <= p/2; y++ {
+		root[y*y%p] = uint32(y + 1)
+	}
+	points := []*ECPoint{PointAtInfinity()}
+	for x := uint64(0); x < p; x++ {
+		r := root[(x*x%p*x%p+a*x%p+b)%p]
+		if r == 0 {
+			continue
+		}
+		y := uint64(r - 1)
+		points = append(points, &ECPoint{new(big.Int).SetUint64(x), new(big.Int).SetUint64(y), big.NewInt(1)})
+		if y != 0 {
+			points = append(points, &ECPoint{new(big.Int).SetUint64(x), new(big.Int).SetUint64(p - y), big.NewInt(1)})
+		}
+	}
+	return points, nil
+}
@@ -0,0 +1,70 @@
+package ECwrap
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestGenerateToyCurve(t *testing.T) {
+	rnd := NewTestReader([]byte("toy curves"))
+	for _, test := range []struct {
+		bits int
+		h    int64
+	}{{3, 1}, {8, 1}, {12, 4}, {16, 1}, {18, 8}, {32, 1}, {40, 4}} {
+		c, err := GenerateToyCurve(test.bits, test.h, rnd)
+		if err != nil {
+			t.Fatalf(`GenerateToyCurve(%d, %d) error = %v`, test.bits, test.h, err)
+		}
+		if c.P.BitLen() != test.bits || c.H.Int64() != test.h || !c.N.ProbablyPrime(20) {
+			t.Fatalf(`GenerateToyCurve(%d, %d) = p %v, n %v, h %v`, test.bits, test.h, c.P, c.N, c.H)
+		}
+		G := c.Generator()
+		if !c.ECPIsOnCurve(G) || G.IsInfinity() || !c.ECPScalarMul(G, c.N).IsInfinity() {
+			t.Fatalf(`%s: base point is not of order n`, c.Name)
+		}
+
+		if c.P.Cmp(big.NewInt(toyMaxEnumerate)) > 0 {
+			// too many points, Schoof's method counts them instead
+			trace, err := c.TraceSchoof()
+			if err != nil {
+				t.Fatal(err)
+			}
+			order := new(big.Int).Sub(new(big.Int).Add(c.P, big.NewInt(1)), trace)
+			if order.Cmp(new(big.Int).Mul(c.N, c.H)) != 0 {
+				t.Fatalf(`%s: #E = %v, expected = %v*%v`, c.Name, order, c.N, c.H)
+			}
+			continue
+		}
+		points, err := c.Points()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int64(len(points)) != c.N.Int64()*c.H.Int64() {
+			t.Fatalf(`%s: len(Points()) = %d, expected = %v*%v`, c.Name, len(points), c.N, c.H)
+		}
+		for _, P := range points[1:] {
+			if !c.ECPIsOnCurve(P) {
+				t.Fatalf(`%s: Points() has %v, which is not on curve`, c.Name, P)
+			}
+		}
+	}
+	if _, err := GenerateToyCurve(65, 1, rnd); err == nil {
+		t.Fatalf(`GenerateToyCurve(65) error = nil`)
+	}
+}
+
+func TestPoints(t *testing.T) {
+	// toy97 has 100 points
+	c := toyCurve(t)
+	points, _ := c.Points()
+	if len(points) != 100 || !points[0].IsInfinity() {
+		t.Fatalf(`len(Points()) = %d, expected = 100`, len(points))
+	}
+	seen := map[string]bool{}
+	for _, P := range points {
+		seen[string(c.ECPMarshal(P))] = true
+	}
+	if len(seen) != 100 {
+		t.Fatalf(`Points() has duplicates`)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/rand"
 	"errors"
 	"fmt"
+	"io"
 	"math/big"
 )
 
@@ -70,6 +71,25 @@ func SetPointJacobianStr(x, y, z string, base int) (*ECPoint, error) {
 	return P, nil
 }
 
+// Returns the point at infinity. Package treats every
+// point with Z = 0 as infinity, (0, 0, 0) is used here
+func PointAtInfinity() *ECPoint {
+	P := new(ECPoint)
+	P.SetCoords(big.NewInt(0), big.NewInt(0), big.NewInt(0))
+	return P
+}
+
+func (P *ECPoint) IsInfinity() bool {
+	return P.Z.Sign() == 0
+}
+
+// Returns a deep copy of the point
+func (P *ECPoint) Copy() *ECPoint {
+	C := new(ECPoint)
+	C.SetCoords(P.X, P.Y, P.Z)
+	return C
+}
+
 func (P *ECPoint) SetCoords(x, y, z *big.Int) {
 	P.X = new(big.Int).Set(x)
 	P.Y = new(big.Int).Set(y)
@@ -111,13 +131,18 @@ func OutIsOnCurve(P *ECPoint, curve elliptic.Curve) {
 	fmt.Printf("is on curve: %t\n", P.IsOnCurve(curve))
 }
 
+// Returns k*G for uniformly random k in [1, n - 1],
+// randomness comes from `crypto/rand`
 func RandPoint(curve elliptic.Curve) (*ECPoint, error) {
-	n, err := rand.Int(rand.Reader, curve.Params().P)
+	return RandPointFrom(curve, rand.Reader)
+}
+
+// Same as `RandPoint`, reading randomness from `rand`
+func RandPointFrom(curve elliptic.Curve, rand io.Reader) (*ECPoint, error) {
+	c := FromElliptic(curve)
+	k, err := c.RandomScalar(rand)
 	if err != nil {
-		return nil, errors.New("could not generate rand value")
+		return nil, err
 	}
-	G := new(ECPoint)
-	G.SetCoords(curve.Params().Gx, curve.Params().Gy, big.NewInt(1))
-	P := ScalarMul(G, n, curve.Params().P)
-	return P, err
+	return c.ECPAffine(c.ECPBaseMul(k)), nil
 }
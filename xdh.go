@@ -0,0 +1,121 @@
+package ECwrap
+
+import (
+	"errors"
+	"io"
+	"math/big"
+	"sync"
+)
+
+// X25519 / X448 key agreement functions (RFC 7748, section 5)
+// https://www.rfc-editor.org/rfc/rfc7748
+//
+// Scalars and u coordinates are little-endian byte strings
+// of the fixed length, scalars are clamped before use
+type XDH struct {
+	Name  string
+	Curve *MontgomeryCurve
+
+	// length of scalars and u coordinates
+	size int
+	// bits of u coordinate that are used, the rest are masked
+	bits int
+	// log2 of the cofactor, the lowest bits cleared by clamping
+	c uint
+	// the highest set bit of the clamped scalar
+	n int
+}
+
+var initX25519, initX448 sync.Once
+var x25519, x448 *XDH
+
+// Returns X25519, the ladder over curve25519
+func X25519() *XDH {
+	initX25519.Do(func() {
+		x25519 = &XDH{
+			Name:  "X25519",
+			Curve: Curve25519(),
+			size:  32,
+			bits:  255,
+			c:     3,
+			n:     254,
+		}
+	})
+	return x25519
+}
+
+// Returns X448, the ladder over curve448
+func X448() *XDH {
+	initX448.Do(func() {
+		x448 = &XDH{
+			Name:  "X448",
+			Curve: Curve448(),
+			size:  56,
+			bits:  448,
+			c:     2,
+			n:     447,
+		}
+	})
+	return x448
+}
+
+// Length of scalars, public keys and shared secrets
+func (x *XDH) Size() int {
+	return x.size
+}
+
+// Decodes and clamps scalar `k`: the lowest c bits are cleared
+// (so the result is a multiple of the cofactor), bit n is set
+// and all the bits above it are cleared
+func (x *XDH) clamp(k []byte) *big.Int {
+	s := leInt(k)
+	for i := uint(0); i < x.c; i++ {
+		s.SetBit(s, int(i), 0)
+	}
+	for i := x.n + 1; i < 8*x.size; i++ {
+		s.SetBit(s, i, 0)
+	}
+	return s.SetBit(s, x.n, 1)
+}
+
+// Computes X25519(k, u) / X448(k, u). Unused high bits of `u`
+// are masked and non-canonical values (>= p) are accepted,
+// as RFC 7748 requires
+//
+// If the result is all zeroes (u is of small order), error is
+// returned. RFC 7748 leaves this check optional, but it is what
+// `crypto/ecdh` does, and the zero secret is useless anyway
+func (x *XDH) ScalarMult(k, u []byte) ([]byte, error) {
+	if len(k) != x.size {
+		return nil, errors.New("invalid scalar length")
+	}
+	if len(u) != x.size {
+		return nil, errors.New("invalid u coordinate length")
+	}
+	uu := leInt(u)
+	for i := x.bits; i < 8*x.size; i++ {
+		uu.SetBit(uu, i, 0)
+	}
+	r := x.Curve.Ladder(x.clamp(k), uu)
+	if r.Sign() == 0 {
+		return nil, errors.New("result is all zeroes, u is of small order")
+	}
+	return leBytes(r, x.size), nil
+}
+
+// Generates key pair, private key is `Size` bytes of `rand`
+// (clamping happens on use)
+func (x *XDH) GenerateKey(rand io.Reader) ([]byte, []byte, error) {
+	k := make([]byte, x.size)
+	if _, err := io.ReadFull(rand, k); err != nil {
+		return nil, nil, errors.New("could not generate rand value")
+	}
+	pub, err := x.ScalarBaseMult(k)
+	return k, pub, err
+}
+
+// Returns public key for private key `k`, which is
+// `ScalarMult(k, u(G))`
+func (x *XDH) ScalarBaseMult(k []byte) ([]byte, error) {
+	return x.ScalarMult(k, leBytes(x.Curve.Gu, x.size))
+}